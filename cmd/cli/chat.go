@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/seternate/go-lanty-client/pkg/event"
+	"github.com/seternate/go-lanty/pkg/chat"
+)
+
+type messageView struct {
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	User    string    `json:"user"`
+	IP      string    `json:"ip"`
+	Message string    `json:"message"`
+}
+
+func chatSend(ctx context.Context, cli *cli, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	message := strings.Join(args, " ")
+	controller, err := cli.newController(ctx)
+	if err != nil {
+		return err
+	}
+	controller.WithUserController().
+		WithChatController()
+	defer cli.quit(controller)
+
+	err = controller.Start()
+	if err != nil {
+		return err
+	}
+	err = cli.waitFor(ctx, "login at server", controller.User.IsLoggedIn)
+	if err != nil {
+		return err
+	}
+	var senderr error
+	err = cli.waitFor(ctx, "chat connection", func() bool {
+		senderr = controller.Chat.SendTextMessage(message)
+		return senderr == nil
+	})
+	if err != nil && senderr != nil {
+		return fmt.Errorf("%w: %w", err, senderr)
+	}
+	return err
+}
+
+func chatTail(ctx context.Context, cli *cli, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+	controller, err := cli.newController(ctx)
+	if err != nil {
+		return err
+	}
+	controller.WithUserController().
+		WithChatController()
+	defer cli.quit(controller)
+	err = controller.Start()
+	if err != nil {
+		return err
+	}
+
+	messages := make(chan event.Event[chat.Message], 50)
+	controller.Chat.Subscribe(messages)
+	defer controller.Chat.Unsubscribe(messages)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case message := <-messages:
+			view := newMessageView(message.Data)
+			cli.printEvent(view, func(w io.Writer) {
+				fmt.Fprintf(w, "[%s] %s: %s\n", view.Time.Local().Format("15:04:05"), view.User, view.Message)
+			})
+		}
+	}
+}
+
+func newMessageView(message chat.Message) messageView {
+	view := messageView{
+		Time:    message.GetTime(),
+		Type:    "text",
+		User:    message.GetUser().Name,
+		IP:      message.GetUser().IP,
+		Message: message.GetMessage(),
+	}
+	if message.GetType() == chat.TYPE_FILE {
+		view.Type = "file"
+		view.Message = message.(*chat.FileMessage).Message.URL
+	}
+	return view
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/seternate/go-lanty-client/pkg/controller"
+	"github.com/seternate/go-lanty-client/pkg/event"
+)
+
+type cli struct {
+	options options
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+func newCli(options options, stdout io.Writer, stderr io.Writer) *cli {
+	return &cli{
+		options: options,
+		stdout:  stdout,
+		stderr:  stderr,
+	}
+}
+
+// newController creates a controller with the settings and status controller. The remaining
+// sub-controllers are added by the caller, as every command only needs a part of them.
+func (cli *cli) newController(ctx context.Context) (*controller.Controller, error) {
+	controller, err := controller.NewController(ctx)
+	if err != nil {
+		return nil, err
+	}
+	controller.WithSettingsController().
+		WithStatusController()
+	controller.Go("cli.statusPrinter", func() {
+		cli.statusPrinter(controller)
+	})
+	return controller, nil
+}
+
+func (cli *cli) quit(controller *controller.Controller) {
+	controller.Quit()
+	controller.WaitGroup().Wait()
+	log.Debug().Msg("cli stopped")
+}
+
+func (cli *cli) statusPrinter(parent *controller.Controller) {
+	statusupdate := make(chan event.Event[controller.Status], 50)
+	parent.Status.Subscribe(statusupdate)
+	defer parent.Status.Unsubscribe(statusupdate)
+	for {
+		select {
+		case <-parent.Context().Done():
+			return
+		case event := <-statusupdate:
+			status := event.Data
+			if status.Text == "" {
+				continue
+			}
+			fmt.Fprintf(cli.stderr, "%s: %s\n", status.Level, status.Text)
+		}
+	}
+}
+
+// print writes v as JSON if requested, otherwise the text callback is used.
+func (cli *cli) print(v any, text func(w io.Writer)) error {
+	if cli.options.json {
+		encoder := json.NewEncoder(cli.stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+	text(cli.stdout)
+	return nil
+}
+
+// printEvent writes v as a single JSON line if requested, so streams can be processed line by line.
+func (cli *cli) printEvent(v any, text func(w io.Writer)) error {
+	if cli.options.json {
+		return json.NewEncoder(cli.stdout).Encode(v)
+	}
+	text(cli.stdout)
+	return nil
+}
+
+func (cli *cli) waitFor(ctx context.Context, what string, condition func() bool) error {
+	ctx, cancel := context.WithTimeout(ctx, cli.options.timeout)
+	defer cancel()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for !condition() {
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("timeout waiting for %s", what)
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
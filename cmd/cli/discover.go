@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+type serverView struct {
+	Name    string `json:"name"`
+	URL     string `json:"url"`
+	Latency int64  `json:"latencyms"`
+}
+
+func discover(ctx context.Context, cli *cli, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+	controller, err := cli.newController(ctx)
+	if err != nil {
+		return err
+	}
+	defer cli.quit(controller)
+
+	servers, err := controller.Settings.Discover(ctx)
+	if err != nil {
+		return err
+	}
+	views := make([]serverView, 0, len(servers))
+	for _, server := range servers {
+		views = append(views, serverView{Name: server.Name, URL: server.URL, Latency: server.Latency.Milliseconds()})
+	}
+	return cli.print(views, func(w io.Writer) {
+		table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "NAME\tURL\tLATENCY")
+		for _, view := range views {
+			fmt.Fprintf(table, "%s\t%s\t%d ms\n", view.Name, view.URL, view.Latency)
+		}
+		table.Flush()
+	})
+}
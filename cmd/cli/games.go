@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/seternate/go-lanty-client/pkg/controller"
+	"github.com/seternate/go-lanty-client/pkg/event"
+	"github.com/seternate/go-lanty/pkg/game"
+	"github.com/seternate/go-lanty/pkg/game/argument"
+	"github.com/seternate/go-lanty/pkg/user"
+)
+
+type gameView struct {
+	Slug           string `json:"slug"`
+	Name           string `json:"name"`
+	Installed      bool   `json:"installed"`
+	CanConnect     bool   `json:"canconnect"`
+	CanStartServer bool   `json:"canstartserver"`
+}
+
+type argumentView struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Value     any    `json:"value"`
+	Mandatory bool   `json:"mandatory"`
+	Disabled  bool   `json:"disabled"`
+}
+
+type downloadView struct {
+	Slug           string  `json:"slug"`
+	State          string  `json:"state"`
+	Progress       float64 `json:"progress"`
+	BytesPerSecond float64 `json:"bytespersecond"`
+	Error          string  `json:"error,omitempty"`
+}
+
+type argumentFlags []string
+
+func (flags *argumentFlags) String() string {
+	return strings.Join(*flags, ",")
+}
+
+func (flags *argumentFlags) Set(value string) error {
+	*flags = append(*flags, value)
+	return nil
+}
+
+func gamesList(ctx context.Context, cli *cli, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+	controller, err := cli.newController(ctx)
+	if err != nil {
+		return err
+	}
+	controller.WithGameController()
+	defer cli.quit(controller)
+
+	err = controller.Game.Refresh()
+	if err != nil {
+		return err
+	}
+	views := make([]gameView, 0, len(controller.Game.GetGames().Games()))
+	for _, game := range controller.Game.GetGames().Games() {
+		views = append(views, gameView{
+			Slug:           game.Slug,
+			Name:           game.Name,
+			Installed:      controller.Game.IsInstalled(game),
+			CanConnect:     game.Client.CanConnect(),
+			CanStartServer: game.CanStartServer(),
+		})
+	}
+	return cli.print(views, func(w io.Writer) {
+		table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "SLUG\tNAME\tINSTALLED\tJOIN\tSERVER")
+		for _, view := range views {
+			fmt.Fprintf(table, "%s\t%s\t%t\t%t\t%t\n", view.Slug, view.Name, view.Installed, view.CanConnect, view.CanStartServer)
+		}
+		table.Flush()
+	})
+}
+
+func download(ctx context.Context, cli *cli, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	statusupdated := make(chan event.Event[controller.DownloadEvent], 50)
+	controller, err := cli.newController(ctx)
+	if err != nil {
+		return err
+	}
+	controller.WithGameController().
+		WithDownloadController()
+	defer cli.quit(controller)
+
+	game, err := findGame(controller, args[0])
+	if err != nil {
+		return err
+	}
+	err = controller.Start()
+	if err != nil {
+		return err
+	}
+	controller.Download.Download(game)
+	download, err := controller.Download.GetLatest(game)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	download.Subscribe(statusupdated)
+	defer download.Unsubscribe(statusupdated)
+	state := ""
+	for {
+		view := newDownloadView(download)
+		if view.State != state || view.State == "downloading" || view.State == "extracting" {
+			state = view.State
+			cli.printEvent(view, func(w io.Writer) {
+				fmt.Fprintln(w, view.String())
+			})
+		}
+		if download.IsComplete() || download.IsStopped() {
+			if download.Err() != nil {
+				return fmt.Errorf("download of %s failed: %w", game.Slug, download.Err())
+			}
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			download.Stop()
+			return ctx.Err()
+		case <-statusupdated:
+		case <-ticker.C:
+		}
+	}
+}
+
+func startServer(ctx context.Context, cli *cli, args []string) error {
+	if len(args) < 1 || strings.HasPrefix(args[0], "-") {
+		return errUsage
+	}
+	var arguments argumentFlags
+	flags := flag.NewFlagSet("start-server", flag.ContinueOnError)
+	flags.SetOutput(cli.stderr)
+	flags.Var(&arguments, "arg", "Sets a server argument as name=value, can be repeated")
+	list := flags.Bool("list", false, "List the server arguments instead of starting the server")
+	if flags.Parse(args[1:]) != nil || flags.NArg() != 0 {
+		return errUsage
+	}
+
+	controller, err := cli.newController(ctx)
+	if err != nil {
+		return err
+	}
+	controller.WithGameController()
+	defer cli.quit(controller)
+
+	game, err := findGame(controller, args[0])
+	if err != nil {
+		return err
+	}
+	if !game.CanStartServer() {
+		return fmt.Errorf("game %s has no server", game.Slug)
+	}
+	values := make(map[string]string, len(arguments))
+	for _, arg := range arguments {
+		name, value, found := strings.Cut(arg, "=")
+		if !found {
+			return fmt.Errorf("invalid argument %q: expected name=value", arg)
+		}
+		values[name] = value
+	}
+	game, err = controller.Game.SetServerArguments(game, values)
+	if err != nil {
+		return err
+	}
+	if *list {
+		views := serverArgumentViews(game)
+		return cli.print(views, func(w io.Writer) {
+			table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(table, "NAME\tTYPE\tVALUE\tMANDATORY\tDISABLED")
+			for _, view := range views {
+				fmt.Fprintf(table, "%s\t%s\t%v\t%t\t%t\n", view.Name, view.Type, view.Value, view.Mandatory, view.Disabled)
+			}
+			table.Flush()
+		})
+	}
+	err = controller.Game.StartServer(game)
+	if err != nil {
+		return err
+	}
+	return cli.print(map[string]string{"slug": game.Slug, "status": "started"}, func(w io.Writer) {
+		fmt.Fprintf(w, "started server of %s\n", game.Name)
+	})
+}
+
+func join(ctx context.Context, cli *cli, args []string) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+	controller, err := cli.newController(ctx)
+	if err != nil {
+		return err
+	}
+	controller.WithGameController().
+		WithUserController()
+	defer cli.quit(controller)
+
+	game, err := findGame(controller, args[0])
+	if err != nil {
+		return err
+	}
+	if !game.Client.CanConnect() {
+		return fmt.Errorf("game %s can not connect to a server", game.Slug)
+	}
+	user, err := findUser(controller, args[1])
+	if err != nil {
+		return err
+	}
+	err = controller.Game.JoinServer(game, user)
+	if err != nil {
+		return err
+	}
+	return cli.print(map[string]string{"slug": game.Slug, "user": user.Name, "ip": user.IP, "status": "joined"}, func(w io.Writer) {
+		fmt.Fprintf(w, "joined server of %s on %s (%s)\n", game.Name, user.Name, user.IP)
+	})
+}
+
+func findGame(controller *controller.Controller, slug string) (game game.Game, err error) {
+	err = controller.Game.Refresh()
+	if err != nil {
+		return
+	}
+	game, err = controller.Game.GetGames().Get(slug)
+	if err != nil {
+		err = fmt.Errorf("game %s not found", slug)
+	}
+	return
+}
+
+func findUser(controller *controller.Controller, nameOrIP string) (user.User, error) {
+	err := controller.User.Refresh()
+	if err != nil {
+		return user.User{}, err
+	}
+	for _, user := range controller.User.GetUsers() {
+		if user.IP == nameOrIP || user.Name == nameOrIP {
+			return user, nil
+		}
+	}
+	return user.User{}, fmt.Errorf("user %s not found", nameOrIP)
+}
+
+func serverArgumentViews(game game.Game) (views []argumentView) {
+	views = make([]argumentView, 0)
+	if game.Server.Arguments == nil {
+		return
+	}
+	for _, arg := range game.Server.Arguments.Arguments {
+		view := argumentView{
+			Name:      arg.GetName(),
+			Mandatory: arg.IsMandatory(),
+			Disabled:  arg.IsDisabled(),
+		}
+		switch arg := arg.(type) {
+		case *argument.String:
+			view.Type, view.Value = "string", arg.Value
+		case *argument.Boolean:
+			view.Type, view.Value = "boolean", arg.Value
+		case *argument.Integer:
+			view.Type, view.Value = "integer", arg.Value
+		case *argument.Float:
+			view.Type, view.Value = "float", arg.Value
+		case *argument.Enum:
+			view.Type, view.Value = "enum", arg.Value
+		default:
+			view.Type = "flag"
+		}
+		views = append(views, view)
+	}
+	return
+}
+
+func newDownloadView(download *controller.Download) downloadView {
+	view := downloadView{
+		Slug:           download.Game().Slug,
+		State:          download.State(),
+		Progress:       download.Progress(),
+		BytesPerSecond: download.BytesPerSecond(),
+	}
+	if download.Err() != nil {
+		view.Error = download.Err().Error()
+	}
+	return view
+}
+
+func (view downloadView) String() string {
+	switch view.State {
+	case "downloading", "extracting":
+		return fmt.Sprintf("%s %s: %.0f%% (%.1f MB/s)", view.State, view.Slug, view.Progress*100, view.BytesPerSecond/(1024*1024))
+	}
+	if view.Error != "" {
+		return fmt.Sprintf("%s %s: %s", view.State, view.Slug, view.Error)
+	}
+	return fmt.Sprintf("%s %s", view.State, view.Slug)
+}
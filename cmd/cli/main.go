@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/seternate/go-lanty-client/pkg/setting"
+	"github.com/seternate/go-lanty/pkg/logging"
+)
+
+var errUsage = errors.New("invalid usage")
+
+type options struct {
+	json        bool
+	timeout     time.Duration
+	printconfig bool
+	settings    setting.Layer
+}
+
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, cli *cli, args []string) error
+}
+
+var commands = []command{
+	{name: "games list", usage: "list all games of the server", run: gamesList},
+	{name: "download", usage: "<slug> download and extract a game", run: download},
+	{name: "start-server", usage: "<slug> [-arg name=value]... [-list] start a game server", run: startServer},
+	{name: "join", usage: "<slug> <user> join the game server of a user (name or IP)", run: join},
+	{name: "users", usage: "list all users connected to the server", run: users},
+	{name: "discover", usage: "list the servers on the local network", run: discover},
+	{name: "chat send", usage: "<message>... send a chat message", run: chatSend},
+	{name: "chat tail", usage: "print incoming chat messages until interrupted", run: chatTail},
+}
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	signalCtx, cancelSignalCtx := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancelSignalCtx()
+
+	logconfig := logging.Config{
+		ConsoleLoggingEnabled: false,
+		FileLoggingEnabled:    true,
+		Filename:              "lanty-cli.log",
+		Directory:             setting.LogDirectory(),
+	}
+	options := parseFlags(&logconfig)
+	log.Logger = logging.Configure(logconfig)
+	setting.SetOverrides(setting.EnvironmentLayer(), options.settings)
+	if options.printconfig {
+		return printConfig()
+	}
+
+	command, args, ok := findCommand(flag.Args())
+	if !ok {
+		flag.Usage()
+		return 2
+	}
+
+	cli := newCli(options, os.Stdout, os.Stderr)
+	err := command.run(signalCtx, cli, args)
+	if errors.Is(err, errUsage) {
+		fmt.Fprintf(os.Stderr, "usage: %s %s %s\n", os.Args[0], command.name, command.usage)
+		return 2
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+func findCommand(args []string) (command, []string, bool) {
+	for _, cmd := range commands {
+		name := strings.Fields(cmd.name)
+		if len(args) >= len(name) && slices.Equal(args[:len(name)], name) {
+			return cmd, args[len(name):], true
+		}
+	}
+	return command{}, nil, false
+}
+
+func parseFlags(config *logging.Config) (options options) {
+	flag.BoolVar(&options.json, "json", false, "Print output as JSON")
+	flag.DurationVar(&options.timeout, "timeout", 10*time.Second, "Sets how long to wait for the server")
+	flag.StringVar(&config.LogLevel, "loglevel", "info", "Sets the log level")
+	flag.BoolVar(&config.ConsoleLoggingEnabled, "logconsole", false, "Additionally writes the log to the console")
+	flag.IntVar(&config.MaxBackups, "logbackups", 0, "Sets the number of old logs to remain")
+	flag.IntVar(&config.MaxSize, "logfilesize", 10, "Sets the size of the logs before rotating to new file")
+	flag.IntVar(&config.MaxAge, "logage", 0, "Sets the maximum number of days to retain old logs")
+	flag.BoolVar(&options.printconfig, "print-config", false, "Prints the effective settings and where each value came from")
+	options.settings = setting.RegisterFlags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "%s command-line client %s\n\n", setting.APPLICATION_NAME, setting.VERSION)
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] <command> [arguments]\n\ncommands:\n", os.Args[0])
+		for _, command := range commands {
+			fmt.Fprintf(flag.CommandLine.Output(), "  %s %s\n", command.name, command.usage)
+		}
+		fmt.Fprintf(flag.CommandLine.Output(), "\nflags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	return
+}
+
+func printConfig() int {
+	settings, err := setting.LoadSettings()
+	if settings == nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", err)
+	}
+	err = settings.WriteConfig(os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		return 1
+	}
+	return 0
+}
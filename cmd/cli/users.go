@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+type userView struct {
+	Name string `json:"name"`
+	IP   string `json:"ip"`
+}
+
+func users(ctx context.Context, cli *cli, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+	controller, err := cli.newController(ctx)
+	if err != nil {
+		return err
+	}
+	controller.WithUserController()
+	defer cli.quit(controller)
+
+	err = controller.User.Refresh()
+	if err != nil {
+		return err
+	}
+	views := make([]userView, 0, len(controller.User.GetUsers()))
+	for _, user := range controller.User.GetUsers() {
+		views = append(views, userView{Name: user.Name, IP: user.IP})
+	}
+	return cli.print(views, func(w io.Writer) {
+		table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "NAME\tIP")
+		for _, view := range views {
+			fmt.Fprintf(table, "%s\t%s\n", view.Name, view.IP)
+		}
+		table.Flush()
+	})
+}
@@ -6,12 +6,17 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"path"
 	"syscall"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"github.com/rs/zerolog/log"
+	"github.com/seternate/go-lanty-client/pkg/controlapi"
 	"github.com/seternate/go-lanty-client/pkg/controller"
+	"github.com/seternate/go-lanty-client/pkg/diagnostics"
+	"github.com/seternate/go-lanty-client/pkg/hook"
+	"github.com/seternate/go-lanty-client/pkg/metrics"
 	"github.com/seternate/go-lanty-client/pkg/setting"
 	"github.com/seternate/go-lanty-client/pkg/widget"
 	"github.com/seternate/go-lanty/pkg/logging"
@@ -30,28 +35,54 @@ func main() {
 		ConsoleLoggingEnabled: true,
 		FileLoggingEnabled:    true,
 		Filename:              "lanty.log",
-		Directory:             "log",
+		Directory:             setting.LogDirectory(),
 	}
-	parseFlags(&logconfig)
+	var flags flags
+	parseFlags(&logconfig, &flags)
 	log.Logger = logging.Configure(logconfig)
+	setting.SetOverrides(setting.EnvironmentLayer(), flags.settings)
+	if flags.printconfig {
+		printConfig()
+		return
+	}
 
 	err := clipboard.Init()
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to init clipboard package")
 	}
 
-	controller := controller.NewController(signalCtx).
-		WithConnectionController().
+	controller, err := controller.NewController(signalCtx)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to create controller")
+	}
+	controller.WithConnectionController().
 		WithSettingsController().
 		WithStatusController().
 		WithGameController().
 		WithDownloadController().
 		WithUserController().
 		WithChatController()
+	exporter := diagnostics.NewExporter(controller, logconfig)
+	if flags.diagnostics != "" {
+		exportDiagnostics(controller, exporter, flags.diagnostics)
+		return
+	}
+	controller.Register(hook.COMPONENT_NAME, hook.NewRunner(controller), hook.Dependencies...)
+	if flags.apiaddress != "" {
+		registerControlAPI(controller, flags.apiaddress)
+	}
+	if flags.metricsaddress != "" {
+		controller.Register(metrics.COMPONENT_NAME, metrics.NewServer(controller.Metrics(), flags.metricsaddress))
+	}
+	err = controller.Start()
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to start controller")
+	}
 
 	app := app.New()
 	window := app.NewWindow(getApplicationTitle())
 	lanty := widget.NewLanty(controller, window)
+	lanty.SetDiagnosticsExporter(exporter)
 	window.SetContent(lanty)
 	window.SetPadded(false)
 	window.Resize(fyne.NewSize(1024, 600))
@@ -65,6 +96,21 @@ func main() {
 	log.Debug().Msg("application stopped")
 }
 
+// printConfig writes the effective settings and where every value came from.
+func printConfig() {
+	settings, err := setting.LoadSettings()
+	if settings == nil {
+		log.Fatal().Err(err).Msg("failed to load settings")
+	}
+	if err != nil {
+		log.Warn().Err(err).Msg("recovered settings")
+	}
+	err = settings.WriteConfig(os.Stdout)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to print config")
+	}
+}
+
 func getApplicationTitle() string {
 	ip, err := network.GetOutboundIP()
 	if err != nil {
@@ -73,10 +119,52 @@ func getApplicationTitle() string {
 	return fmt.Sprintf("%s - %s", setting.APPLICATION_NAME, ip.String())
 }
 
-func parseFlags(config *logging.Config) {
+func registerControlAPI(controller *controller.Controller, address string) {
+	directory, err := setting.Directory()
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to get settings directory")
+	}
+	token, err := controlapi.LoadToken(path.Join(directory, setting.TOKEN_PATH))
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to load control API token")
+	}
+	controller.Register(controlapi.COMPONENT_NAME, controlapi.NewServer(controller, address, token), controlapi.Dependencies...)
+}
+
+// exportDiagnostics polls the server once, so the export contains the current games and users.
+func exportDiagnostics(controller *controller.Controller, exporter *diagnostics.Exporter, path string) {
+	defer controller.WaitGroup().Wait()
+	defer controller.Quit()
+	err := controller.Start()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to start controller for diagnostics")
+	}
+	controller.Game.Refresh()
+	controller.User.Refresh()
+	err = exporter.Export(path)
+	if err != nil {
+		log.Fatal().Err(err).Str("path", path).Msg("failed to export diagnostics")
+	}
+	log.Info().Str("path", path).Msg("exported diagnostics")
+}
+
+type flags struct {
+	apiaddress     string
+	metricsaddress string
+	diagnostics    string
+	printconfig    bool
+	settings       setting.Layer
+}
+
+func parseFlags(config *logging.Config, flags *flags) {
 	flag.StringVar(&config.LogLevel, "loglevel", "info", "Sets the log level")
 	flag.IntVar(&config.MaxBackups, "logbackups", 0, "Sets the number of old logs to remain")
 	flag.IntVar(&config.MaxSize, "logfilesize", 10, "Sets the size of the logs before rotating to new file")
 	flag.IntVar(&config.MaxAge, "logage", 0, "Sets the maximum number of days to retain old logs")
+	flag.StringVar(&flags.apiaddress, "apiaddress", "", "Enables the local control API on the given loopback address, e.g. 127.0.0.1:8765")
+	flag.StringVar(&flags.metricsaddress, "metricsaddress", "", "Enables the Prometheus metrics endpoint /metrics on the given address, e.g. :9464")
+	flag.StringVar(&flags.diagnostics, "exportdiagnostics", "", "Writes a diagnostics zip with logs, settings and state to the given path and exits")
+	flag.BoolVar(&flags.printconfig, "print-config", false, "Prints the effective settings and where each value came from and exits")
+	flags.settings = setting.RegisterFlags(flag.CommandLine)
 	flag.Parse()
 }
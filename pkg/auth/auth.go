@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+)
+
+// Credentials adds the event password as bearer token to every request to the Lanty servers.
+// Requests to other hosts are sent without it.
+type Credentials struct {
+	password string
+	hosts    []string
+	mutex    sync.RWMutex
+
+	// OnUnauthorized is called if a server rejects a request, because the password is missing or
+	// wrong.
+	OnUnauthorized func(host string)
+}
+
+func NewCredentials() *Credentials {
+	return &Credentials{hosts: make([]string, 0)}
+}
+
+// Set sets the password and the servers it is sent to.
+func (credentials *Credentials) Set(password string, serverurls []string) {
+	hosts := make([]string, 0, len(serverurls))
+	for _, serverurl := range serverurls {
+		u, err := url.Parse(serverurl)
+		if err == nil && u.Host != "" {
+			hosts = append(hosts, u.Host)
+		}
+	}
+	credentials.mutex.Lock()
+	credentials.password = password
+	credentials.hosts = hosts
+	credentials.mutex.Unlock()
+}
+
+func (credentials *Credentials) Password() string {
+	defer credentials.mutex.RUnlock()
+	credentials.mutex.RLock()
+	return credentials.password
+}
+
+// passwordFor returns the password if the URL is one of the servers.
+func (credentials *Credentials) passwordFor(u *url.URL) string {
+	defer credentials.mutex.RUnlock()
+	credentials.mutex.RLock()
+	if !slices.Contains(credentials.hosts, u.Host) {
+		return ""
+	}
+	return credentials.password
+}
+
+// Transport returns a transport sending the password as bearer token with the requests of next.
+func (credentials *Credentials) Transport(next http.RoundTripper) http.RoundTripper {
+	return &transport{credentials: credentials, next: next}
+}
+
+type transport struct {
+	credentials *Credentials
+	next        http.RoundTripper
+}
+
+func (transport *transport) RoundTrip(request *http.Request) (*http.Response, error) {
+	password := transport.credentials.passwordFor(request.URL)
+	if password != "" {
+		request = request.Clone(request.Context())
+		request.Header.Set("Authorization", "Bearer "+password)
+	}
+	response, err := transport.next.RoundTrip(request)
+	if err == nil && response.StatusCode == http.StatusUnauthorized && transport.credentials.OnUnauthorized != nil {
+		transport.credentials.OnUnauthorized(request.URL.Host)
+	}
+	return response, err
+}
+
+// CloseIdleConnections closes the idle connections of next, e.g. after the TLS settings changed.
+func (transport *transport) CloseIdleConnections() {
+	if closer, ok := transport.next.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,81 @@
+package controlapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+	"github.com/seternate/go-lanty-client/pkg/controller"
+	"github.com/seternate/go-lanty-client/pkg/event"
+	"github.com/seternate/go-lanty/pkg/chat"
+)
+
+type statusView struct {
+	Level string `json:"level"`
+	Text  string `json:"text"`
+}
+
+func (server *Server) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming not supported"))
+		return
+	}
+
+	statusupdated := make(chan event.Event[controller.Status], 50)
+	downloadqueued := make(chan event.Event[*controller.Download], 50)
+	downloadupdated := make(chan event.Event[controller.DownloadEvent], 200)
+	messagereceived := make(chan event.Event[chat.Message], 50)
+	server.controller.Status.Subscribe(statusupdated)
+	defer server.controller.Status.Unsubscribe(statusupdated)
+	server.controller.Download.Subscribe(downloadqueued)
+	defer server.controller.Download.Unsubscribe(downloadqueued)
+	server.controller.Chat.Subscribe(messagereceived)
+	defer server.controller.Chat.Unsubscribe(messagereceived)
+	downloads := make([]*controller.Download, 0)
+	defer func() {
+		for _, download := range downloads {
+			download.Unsubscribe(downloadupdated)
+		}
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-statusupdated:
+			err = writeEvent(w, event.Topic, statusView{Level: event.Data.Level.String(), Text: event.Data.Text})
+		case event := <-downloadqueued:
+			event.Data.Subscribe(downloadupdated)
+			downloads = append(downloads, event.Data)
+			err = writeEvent(w, event.Topic, newDownloadView(event.Data))
+		case event := <-downloadupdated:
+			err = writeEvent(w, event.Topic, newDownloadView(event.Data.Download))
+		case event := <-messagereceived:
+			err = writeEvent(w, event.Topic, event.Data)
+		}
+		if err != nil {
+			log.Debug().Err(err).Msg("closing control API event stream")
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, topic event.Topic, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", topic, data)
+	return err
+}
@@ -0,0 +1,237 @@
+package controlapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+	"github.com/seternate/go-lanty-client/pkg/controller"
+	"github.com/seternate/go-lanty/pkg/game"
+	"github.com/seternate/go-lanty/pkg/user"
+)
+
+type gameView struct {
+	Slug           string `json:"slug"`
+	Name           string `json:"name"`
+	Installed      bool   `json:"installed"`
+	CanConnect     bool   `json:"canconnect"`
+	CanStartServer bool   `json:"canstartserver"`
+}
+
+type downloadView struct {
+	Slug           string  `json:"slug"`
+	State          string  `json:"state"`
+	Progress       float64 `json:"progress"`
+	BytesPerSecond float64 `json:"bytespersecond"`
+	Error          string  `json:"error,omitempty"`
+}
+
+type serverRequest struct {
+	Arguments map[string]string `json:"arguments"`
+}
+
+type joinRequest struct {
+	User string `json:"user"`
+}
+
+type chatRequest struct {
+	Message string `json:"message"`
+}
+
+func (server *Server) getGames(w http.ResponseWriter, r *http.Request) {
+	games := server.controller.Game.GetGames().Games()
+	views := make([]gameView, 0, len(games))
+	for _, game := range games {
+		views = append(views, gameView{
+			Slug:           game.Slug,
+			Name:           game.Name,
+			Installed:      server.controller.Game.IsInstalled(game),
+			CanConnect:     game.Client.CanConnect(),
+			CanStartServer: game.CanStartServer(),
+		})
+	}
+	writeJSON(w, http.StatusOK, views)
+}
+
+func (server *Server) queueDownload(w http.ResponseWriter, r *http.Request) {
+	game, found := server.game(w, r)
+	if !found {
+		return
+	}
+	server.controller.Download.Download(game)
+	download, err := server.controller.Download.GetLatest(game)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, newDownloadView(download))
+}
+
+func (server *Server) cancelDownload(w http.ResponseWriter, r *http.Request) {
+	game, found := server.game(w, r)
+	if !found {
+		return
+	}
+	download, err := server.controller.Download.GetLatest(game)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	download.Stop()
+	writeJSON(w, http.StatusOK, newDownloadView(download))
+}
+
+func (server *Server) getDownloads(w http.ResponseWriter, r *http.Request) {
+	downloads := server.controller.Download.GetDownloads()
+	views := make([]downloadView, 0, len(downloads))
+	for _, download := range downloads {
+		views = append(views, newDownloadView(download))
+	}
+	writeJSON(w, http.StatusOK, views)
+}
+
+func (server *Server) startGame(w http.ResponseWriter, r *http.Request) {
+	game, found := server.game(w, r)
+	if !found {
+		return
+	}
+	err := server.controller.Game.StartGame(game)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (server *Server) startServer(w http.ResponseWriter, r *http.Request) {
+	game, found := server.game(w, r)
+	if !found {
+		return
+	}
+	if !game.CanStartServer() {
+		writeError(w, http.StatusConflict, fmt.Errorf("game %s has no server", game.Slug))
+		return
+	}
+	var request serverRequest
+	if !readJSON(w, r, &request) {
+		return
+	}
+	game, err := server.controller.Game.SetServerArguments(game, request.Arguments)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	err = server.controller.Game.StartServer(game)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (server *Server) joinServer(w http.ResponseWriter, r *http.Request) {
+	game, found := server.game(w, r)
+	if !found {
+		return
+	}
+	if !game.Client.CanConnect() {
+		writeError(w, http.StatusConflict, fmt.Errorf("game %s can not connect to a server", game.Slug))
+		return
+	}
+	var request joinRequest
+	if !readJSON(w, r, &request) {
+		return
+	}
+	user, found := server.user(request.User)
+	if !found {
+		writeError(w, http.StatusNotFound, fmt.Errorf("user %s not found", request.User))
+		return
+	}
+	err := server.controller.Game.JoinServer(game, user)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (server *Server) getUsers(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, server.controller.User.GetUsers())
+}
+
+func (server *Server) sendChat(w http.ResponseWriter, r *http.Request) {
+	var request chatRequest
+	if !readJSON(w, r, &request) {
+		return
+	}
+	if request.Message == "" {
+		writeError(w, http.StatusBadRequest, errors.New("empty message"))
+		return
+	}
+	err := server.controller.Chat.SendTextMessage(request.Message)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (server *Server) game(w http.ResponseWriter, r *http.Request) (game.Game, bool) {
+	slug := mux.Vars(r)["slug"]
+	game, err := server.controller.Game.GetGames().Get(slug)
+	if err != nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("game %s not found", slug))
+		return game, false
+	}
+	return game, true
+}
+
+func (server *Server) user(nameOrIP string) (user.User, bool) {
+	for _, user := range server.controller.User.GetUsers() {
+		if user.IP == nameOrIP || user.Name == nameOrIP {
+			return user, true
+		}
+	}
+	return user.User{}, false
+}
+
+func newDownloadView(download *controller.Download) downloadView {
+	view := downloadView{
+		Slug:           download.Game().Slug,
+		State:          download.State(),
+		Progress:       download.Progress(),
+		BytesPerSecond: download.BytesPerSecond(),
+	}
+	if download.Err() != nil {
+		view.Error = download.Err().Error()
+	}
+	return view
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if r.ContentLength == 0 {
+		return true
+	}
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(v)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		log.Error().Err(err).Msg("error writing control API response")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,153 @@
+package controlapi
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+	"github.com/seternate/go-lanty-client/pkg/controller"
+)
+
+const COMPONENT_NAME = "controlapi"
+
+var Dependencies = []string{
+	controller.ComponentStatus,
+	controller.ComponentGame,
+	controller.ComponentDownload,
+	controller.ComponentUser,
+	controller.ComponentChat,
+}
+
+// Server exposes the controller over a localhost-only REST API with a server-sent events stream.
+// Every request needs the token as bearer token.
+type Server struct {
+	controller *controller.Controller
+	address    string
+	token      string
+	server     *http.Server
+	ctx        context.Context
+	cancelCtx  context.CancelFunc
+	waitgrp    sync.WaitGroup
+}
+
+func NewServer(controller *controller.Controller, address string, token string) *Server {
+	return &Server{
+		controller: controller,
+		address:    address,
+		token:      token,
+	}
+}
+
+// LoadToken reads the token from the file or creates a new random token if the file does not exist.
+func LoadToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil && len(strings.TrimSpace(string(data))) > 0 {
+		return strings.TrimSpace(string(data)), nil
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	random := make([]byte, 32)
+	_, err = rand.Read(random)
+	if err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(random)
+	err = os.WriteFile(path, []byte(token+"\n"), 0600)
+	if err != nil {
+		return "", err
+	}
+	log.Info().Str("path", path).Msg("created new control API token")
+	return token, nil
+}
+
+func (server *Server) Start(ctx context.Context) error {
+	host, _, err := net.SplitHostPort(server.address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("control API address %s is not a loopback address", server.address)
+	}
+	listener, err := net.Listen("tcp", server.address)
+	if err != nil {
+		return err
+	}
+
+	server.ctx, server.cancelCtx = context.WithCancel(ctx)
+	server.server = &http.Server{
+		Handler:           server.router(),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return server.ctx },
+	}
+	server.waitgrp.Add(1)
+	go func() {
+		defer server.waitgrp.Done()
+		err := server.server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Msg("control API stopped unexpectedly")
+		}
+	}()
+	log.Info().Str("address", listener.Addr().String()).Msg("control API listening")
+	return nil
+}
+
+func (server *Server) Stop() {
+	if server.server == nil {
+		return
+	}
+	server.cancelCtx()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := server.server.Shutdown(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("error shutting down control API")
+	}
+	server.waitgrp.Wait()
+	server.server = nil
+}
+
+func (server *Server) router() http.Handler {
+	router := mux.NewRouter()
+	router.Use(server.authenticate)
+	api := router.PathPrefix("/api").Subrouter()
+	api.HandleFunc("/games", server.getGames).Methods(http.MethodGet)
+	api.HandleFunc("/games/{slug}/download", server.queueDownload).Methods(http.MethodPost)
+	api.HandleFunc("/games/{slug}/download", server.cancelDownload).Methods(http.MethodDelete)
+	api.HandleFunc("/games/{slug}/start", server.startGame).Methods(http.MethodPost)
+	api.HandleFunc("/games/{slug}/server", server.startServer).Methods(http.MethodPost)
+	api.HandleFunc("/games/{slug}/join", server.joinServer).Methods(http.MethodPost)
+	api.HandleFunc("/downloads", server.getDownloads).Methods(http.MethodGet)
+	api.HandleFunc("/users", server.getUsers).Methods(http.MethodGet)
+	api.HandleFunc("/chat", server.sendChat).Methods(http.MethodPost)
+	api.HandleFunc("/events", server.events).Methods(http.MethodGet)
+	return router
+}
+
+func (server *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil || !net.ParseIP(host).IsLoopback() {
+			writeError(w, http.StatusForbidden, errors.New("only local requests are allowed"))
+			return
+		}
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(server.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("invalid token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/seternate/go-lanty/pkg/game"
+	"github.com/seternate/go-lanty/pkg/game/argument"
+)
+
+// SetServerArguments returns the game with a copy of the server arguments, in which the values are
+// parsed and the arguments enabled. The arguments of the game are shared with the games of the
+// controller and are not changed.
+func (controller *GameController) SetServerArguments(g game.Game, values map[string]string) (game.Game, error) {
+	if len(values) == 0 {
+		return g, nil
+	}
+	if g.Server.Arguments == nil {
+		return g, fmt.Errorf("game %s has no server arguments", g.Slug)
+	}
+	arguments := *g.Server.Arguments
+	arguments.Arguments = make([]argument.Argument, 0, len(g.Server.Arguments.Arguments))
+	for _, arg := range g.Server.Arguments.Arguments {
+		arguments.Arguments = append(arguments.Arguments, copyArgument(arg))
+	}
+	g.Server.Arguments = &arguments
+	for name, value := range values {
+		index := slices.IndexFunc(arguments.Arguments, func(arg argument.Argument) bool {
+			return strings.EqualFold(arg.GetName(), name)
+		})
+		if index < 0 {
+			return g, fmt.Errorf("game %s has no server argument %q", g.Slug, name)
+		}
+		arg := arguments.Arguments[index]
+		err := setArgumentValue(arg, value)
+		if err != nil {
+			return g, fmt.Errorf("invalid value for argument %q: %w", name, err)
+		}
+		arg.Enable()
+	}
+	return g, nil
+}
+
+// copyArgument returns a copy of the argument the pointer of the argument type points to.
+func copyArgument(arg argument.Argument) argument.Argument {
+	value := reflect.ValueOf(arg)
+	if value.Kind() != reflect.Pointer || value.IsNil() {
+		return arg
+	}
+	copied := reflect.New(value.Elem().Type())
+	copied.Elem().Set(value.Elem())
+	return copied.Interface().(argument.Argument)
+}
+
+func setArgumentValue(arg argument.Argument, value string) error {
+	switch arg := arg.(type) {
+	case *argument.String:
+		arg.Value = value
+	case *argument.Boolean:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		arg.Value = parsed
+	case *argument.Integer:
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		if parsed < arg.MinValue || parsed > arg.MaxValue {
+			return fmt.Errorf("%d not in range [%d, %d]", parsed, arg.MinValue, arg.MaxValue)
+		}
+		arg.Value = parsed
+	case *argument.Float:
+		parsed, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			return err
+		}
+		if float32(parsed) < arg.MinValue || float32(parsed) > arg.MaxValue {
+			return fmt.Errorf("%g not in range [%g, %g]", parsed, arg.MinValue, arg.MaxValue)
+		}
+		arg.Value = float32(parsed)
+	case *argument.Enum:
+		for _, item := range arg.Items {
+			if item.Name == value || item.Value == value {
+				arg.Value = item.Value
+				return nil
+			}
+		}
+		return errors.New("no such option")
+	default:
+		if value != "" {
+			return errors.New("argument takes no value")
+		}
+	}
+	return nil
+}
@@ -1,38 +1,45 @@
 package controller
 
 import (
+	"context"
 	"fmt"
 	"net/url"
-	"slices"
-	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"github.com/seternate/go-lanty-client/pkg/event"
+	"github.com/seternate/go-lanty-client/pkg/retry"
+	"github.com/seternate/go-lanty-client/pkg/supervisor"
 	"github.com/seternate/go-lanty/pkg/chat"
-	"github.com/seternate/go-lanty/pkg/util"
 )
 
 type ChatController struct {
-	parent          *Controller
-	subscriber      []chan chat.Message
-	ticker          *time.Ticker
-	mutex           sync.RWMutex
-	settingschanged chan struct{}
+	parent   *Controller
+	events   *event.EventBus[chat.Message]
+	routines *routines
+	ticker   *time.Ticker
+	backoff  *retry.Backoff
 }
 
 func NewChatController(parent *Controller) (controller *ChatController) {
 	controller = &ChatController{
-		parent:          parent,
-		subscriber:      make([]chan chat.Message, 50),
-		ticker:          time.NewTicker(time.Second),
-		settingschanged: make(chan struct{}, 50),
+		parent:   parent,
+		events:   event.NewEventBus[chat.Message](),
+		routines: newRoutines(parent, ComponentChat),
+		ticker:   time.NewTicker(time.Second),
+		backoff:  retry.NewBackoff(parent.retryPolicy),
 	}
-
-	controller.parent.Settings.Subscribe(controller.settingschanged)
-	controller.run()
 	return
 }
 
+func (controller *ChatController) Start(ctx context.Context) error {
+	return controller.routines.start(ctx, controller.connectionWatcher, controller.messageReader)
+}
+
+func (controller *ChatController) Stop() {
+	controller.routines.stop()
+}
+
 func (controller *ChatController) DownloadFile(message chat.Message) {
 	if message.GetType() != chat.TYPE_FILE {
 		log.Warn().Interface("message", message).Msg("wrong message type provided for file download")
@@ -45,13 +52,14 @@ func (controller *ChatController) DownloadFile(message chat.Message) {
 		controller.parent.Status.Error("Failed to start download", 3*time.Second)
 		return
 	}
-	download, err := controller.parent.client.File.GetFile(controller.parent.ctx, *u, controller.parent.settings.DownloadDirectory)
+	directory := controller.parent.currentSettings().DownloadDirectory
+	download, err := controller.parent.fileService.GetFile(controller.parent.ctx, *u, directory)
 	if err != nil {
 		log.Error().Err(err).Interface("message", message).Msg("error starting filemessage download")
 		controller.parent.Status.Error(fmt.Sprintf("Failed downloading %s", download.Filename()), 3*time.Second)
 		return
 	}
-	go func() {
+	controller.parent.Go("ChatController.DownloadFile", func() {
 		<-download.Done
 		if download.Err != nil {
 			log.Error().Err(err).Str("file", download.Filename()).Msg("error downloading filemessage file")
@@ -59,104 +67,87 @@ func (controller *ChatController) DownloadFile(message chat.Message) {
 			return
 		}
 		log.Debug().Str("file", download.Filename()).Msg("sucessfully downloaded filemessage file")
-		controller.parent.Status.Info(fmt.Sprintf("Downloaded \"%s\" to \"%s\"", download.Filename(), controller.parent.settings.DownloadDirectory), 3*time.Second)
-	}()
+		controller.parent.Status.Info(fmt.Sprintf("Downloaded \"%s\" to \"%s\"", download.Filename(), directory), 3*time.Second)
+	}, supervisor.WithField("file", download.Filename()), supervisor.WithoutRestart())
 }
 
-func (controller *ChatController) SendTextMessage(message string) {
-	err := controller.parent.client.Chat.SendMessage(chat.NewTextMessage(controller.parent.User.GetUser(), message))
+func (controller *ChatController) SendTextMessage(message string) (err error) {
+	err = controller.parent.chatService.SendMessage(chat.NewTextMessage(controller.parent.User.GetUser(), message))
 	if err != nil {
 		log.Error().Err(err).Msg("error sending textmessage to server")
 	}
+	return
 }
 
 func (controller *ChatController) SendFileMessage(path string) {
 	controller.parent.Status.Info(fmt.Sprintf("Uploading file \"%s\" ...", path), 3*time.Second)
-	fileresponse, err := controller.parent.client.File.UploadFile(path)
+	message, err := controller.parent.fileService.UploadFile(path, controller.parent.User.GetUser())
 	if err != nil {
 		controller.parent.Status.Error(fmt.Sprintf("Error uploading file \"%s\"", path), 3*time.Second)
 		log.Error().Err(err).Str("file", path).Msg("error uploading file to server")
 		return
 	}
 
-	message := chat.NewFileMessage(controller.parent.User.GetUser(), fileresponse)
-	err = controller.parent.client.Chat.SendMessage(message)
+	err = controller.parent.chatService.SendMessage(message)
 	if err != nil {
 		log.Error().Err(err).Interface("message", message).Msg("error sending filemessage to server")
 	}
 }
 
-func (controller *ChatController) Subscribe(subscriber chan chat.Message) {
-	defer controller.mutex.Unlock()
-	controller.mutex.Lock()
-	controller.subscriber = append(controller.subscriber, subscriber)
+func (controller *ChatController) Subscribe(subscriber chan event.Event[chat.Message]) {
+	controller.events.Subscribe(subscriber, TopicChatMessage)
 }
 
-func (controller *ChatController) Unsubscribe(subscriber chan chat.Message) {
-	defer controller.mutex.Unlock()
-	controller.mutex.Lock()
-	index := slices.Index(controller.subscriber, subscriber)
-	_ = slices.Delete(controller.subscriber, index, index+1)
+func (controller *ChatController) Unsubscribe(subscriber chan event.Event[chat.Message]) {
+	controller.events.Unsubscribe(subscriber)
 }
 
-func (controller *ChatController) run() {
-	controller.parent.WaitGroup().Add(2)
-	go controller.connectionWatcher()
-	go controller.messageReader()
-}
-
-func (controller *ChatController) connectionWatcher() {
-	defer func() { controller.parent.WaitGroup().Done(); controller.parent.client.Chat.Disconnect() }()
-	_, err := controller.parent.client.Chat.Connect()
+func (controller *ChatController) connectionWatcher(ctx context.Context) {
+	defer controller.parent.chatService.Disconnect()
+	controller.backoff.Success()
+	err := controller.parent.chatService.Connect()
+	controller.parent.reportConnection(SubsystemChat, err)
 	if err != nil {
+		controller.backoff.Failure()
 		log.Error().Err(err).Msg("error connecting to chat")
 	} else {
 		log.Debug().Msg("successfully connected to chat")
 	}
 	for {
 		select {
-		case <-controller.parent.Context().Done():
-			log.Trace().Err(controller.parent.Context().Err()).Msg("exiting ChatController connectionWatcher()")
+		case <-ctx.Done():
+			log.Trace().Err(ctx.Err()).Msg("exiting ChatController connectionWatcher()")
 			return
 		case <-controller.ticker.C:
-			if controller.parent.client.Chat.Error != nil {
-				log.Debug().Err(controller.parent.client.Chat.Error).Msg("trying to reconnect to chat due to error in chatservice")
-				err = controller.parent.client.Chat.Reconnect()
+			if controller.parent.chatService.Err() != nil {
+				controller.parent.reportConnection(SubsystemChat, controller.parent.chatService.Err())
+			}
+			if controller.parent.chatService.Err() != nil && controller.backoff.Ready() {
+				log.Debug().Err(controller.parent.chatService.Err()).Msg("trying to reconnect to chat due to error in chatservice")
+				err = controller.parent.chatService.Reconnect()
+				controller.parent.reportConnection(SubsystemChat, err)
 				if err != nil {
-					log.Error().Err(err).Msg("error reconnecting to chat")
+					delay := controller.backoff.Failure()
+					controller.parent.metrics.chatReconnects.Inc("error")
+					log.Error().Err(err).Dur("retry", delay).Msg("error reconnecting to chat")
 				} else {
+					controller.backoff.Success()
+					controller.parent.metrics.chatReconnects.Inc("success")
 					log.Debug().Msg("successfully reconnected to chat")
 				}
 			}
-		case <-controller.settingschanged:
-			log.Debug().Err(controller.parent.client.Chat.Error).Msg("trying to reconnect to chat due to a settings change")
-			err = controller.parent.client.Chat.Reconnect()
-			if err != nil {
-				log.Error().Err(err).Msg("error reconnecting to chat")
-			} else {
-				log.Debug().Msg("successfully reconnected to chat")
-			}
 		}
 	}
 }
 
-func (controller *ChatController) messageReader() {
-	defer controller.parent.WaitGroup().Done()
+func (controller *ChatController) messageReader(ctx context.Context) {
 	for {
 		select {
-		case <-controller.parent.Context().Done():
-			log.Trace().Err(controller.parent.Context().Err()).Msg("exiting ChatController messageReader()")
+		case <-ctx.Done():
+			log.Trace().Err(ctx.Err()).Msg("exiting ChatController messageReader()")
 			return
-		case message := <-controller.parent.client.Chat.Messages:
-			controller.notifySubcriber(message)
+		case message := <-controller.parent.chatService.Messages():
+			controller.events.Publish(TopicChatMessage, message)
 		}
 	}
 }
-
-func (controller *ChatController) notifySubcriber(message chat.Message) {
-	defer controller.mutex.RUnlock()
-	controller.mutex.RLock()
-	for _, subscriber := range controller.subscriber {
-		util.ChannelWriteNonBlocking(subscriber, message)
-	}
-}
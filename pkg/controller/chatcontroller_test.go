@@ -0,0 +1,38 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/seternate/go-lanty-client/pkg/event"
+	"github.com/seternate/go-lanty-client/pkg/lantytest"
+	"github.com/seternate/go-lanty/pkg/chat"
+)
+
+func TestChatReconnect(t *testing.T) {
+	server := lantytest.NewServer()
+	defer server.Close()
+	controller := newTestController(t, server)
+	messages := make(chan event.Event[chat.Message], 10)
+	controller.Chat.Subscribe(messages)
+	defer controller.Chat.Unsubscribe(messages)
+	eventually(t, func() bool { return server.ChatConnections() == 1 }, "chat connection")
+	eventually(t, controller.User.IsLoggedIn, "login")
+
+	server.DropChats()
+	eventually(t, func() bool { return server.ChatConnections() == 1 }, "chat reconnect")
+
+	// The server accepts the connection before the client finished reconnecting.
+	eventually(t, func() bool { return controller.Chat.SendTextMessage("hello") == nil }, "sent message")
+	select {
+	case message := <-messages:
+		if message.Data.GetMessage() != "hello" || message.Data.GetUser().Name != "player" {
+			t.Errorf("got message %+v, want hello from player", message.Data)
+		}
+	case <-time.After(TEST_TIMEOUT):
+		t.Fatal("timed out waiting for the chat message")
+	}
+	if len(server.Messages()) != 1 {
+		t.Errorf("got %d messages at the server, want 1", len(server.Messages()))
+	}
+}
@@ -1,25 +1,135 @@
 package controller
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
 	"slices"
 	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
-	"github.com/seternate/go-lanty/pkg/util"
+	"github.com/seternate/go-lanty-client/pkg/event"
+	"github.com/seternate/go-lanty-client/pkg/retry"
+	"github.com/seternate/go-lanty-client/pkg/setting"
+	"github.com/seternate/go-lanty-client/pkg/trust"
 )
 
+const (
+	// QUALITY_WINDOW is the number of health checks the latency and packet loss are estimated from.
+	QUALITY_WINDOW = 20
+	HISTORY_SIZE   = 50
+	// The connection is degraded above this latency or packet loss.
+	DEGRADED_RTT  = 500 * time.Millisecond
+	DEGRADED_LOSS = 0.2
+	// DISCONNECT_FAILURES is the number of failed health checks in a row after which a degraded
+	// connection is disconnected.
+	DISCONNECT_FAILURES = 3
+	PROBE_TIMEOUT       = 2 * time.Second
+	// FAILBACK_INTERVAL is how often the preferred servers are checked while a backup server is used.
+	FAILBACK_INTERVAL = 30 * time.Second
+)
+
+// ErrIncompatible marks errors of a server which speaks another API version. Responses which can
+// not be decoded are treated the same.
+var ErrIncompatible = errors.New("server incompatible")
+
+// ErrUnauthorized is the error of a server rejecting the requests, because the event password is
+// missing or wrong.
+var ErrUnauthorized = errors.New("event password missing or wrong")
+
 type ConnectionStatus int
 
 const (
-	Connected ConnectionStatus = iota
+	Connecting ConnectionStatus = iota
+	Connected
+	Degraded
 	Disconnected
+	ServerIncompatible
+	Unauthorized
 )
 
+func (status ConnectionStatus) String() string {
+	switch status {
+	case Connecting:
+		return "connecting"
+	case Connected:
+		return "connected"
+	case Degraded:
+		return "degraded"
+	case ServerIncompatible:
+		return "incompatible"
+	case Unauthorized:
+		return "unauthorized"
+	}
+	return "disconnected"
+}
+
+// IsConnected returns true if the server can be used, which it can while degraded as well.
+func (status ConnectionStatus) IsConnected() bool {
+	return status == Connected || status == Degraded
+}
+
+// Subsystem is a part of the client talking to the server, which reports the result of its requests.
+type Subsystem string
+
+const (
+	SubsystemAPI          Subsystem = "REST API"
+	SubsystemChat         Subsystem = "chat websocket"
+	SubsystemFileTransfer Subsystem = "file transfer"
+)
+
+type SubsystemStatus struct {
+	Subsystem Subsystem
+	OK        bool
+	Err       string
+	Since     time.Time
+}
+
+type StateChange struct {
+	From ConnectionStatus
+	To   ConnectionStatus
+	Time time.Time
+	Err  string
+}
+
+// ConnectionQuality is the state of the connection after the latest health check.
+type ConnectionQuality struct {
+	Status     ConnectionStatus
+	RTT        time.Duration
+	Loss       float64
+	Subsystems []SubsystemStatus
+}
+
+type probe struct {
+	rtt time.Duration
+	ok  bool
+}
+
 type ConnectionController struct {
 	parent          *Controller
-	subscriber      []chan struct{}
+	events          *event.EventBus[ConnectionStatus]
+	quality         *event.EventBus[ConnectionQuality]
+	untrusted       *event.EventBus[trust.UntrustedError]
+	unauthorized    *event.EventBus[string]
+	routines        *routines
 	refreshinterval time.Duration
+	backoff         *retry.Backoff
+	reset           chan struct{}
+	active          string
+	lastfailback    time.Time
+	probes          []probe
+	failures        int
+	subsystems      map[Subsystem]SubsystemStatus
+	// incompatible is the error of the latest health check, apiIncompatible the one of the latest API
+	// request, if the server speaks another API version.
+	incompatible    error
+	apiIncompatible error
+	rejected        error
+	history         []StateChange
+	prompted        map[string]bool
 	mutex           sync.RWMutex
 	Status          ConnectionStatus
 }
@@ -27,62 +137,397 @@ type ConnectionController struct {
 func NewConnectionController(parent *Controller, refreshinterval time.Duration) (controller *ConnectionController) {
 	controller = &ConnectionController{
 		parent:          parent,
-		subscriber:      make([]chan struct{}, 0, 50),
+		events:          event.NewEventBus[ConnectionStatus](),
+		quality:         event.NewEventBus[ConnectionQuality](),
+		untrusted:       event.NewEventBus[trust.UntrustedError](),
+		unauthorized:    event.NewEventBus[string](),
+		routines:        newRoutines(parent, ComponentConnection),
 		refreshinterval: refreshinterval,
-		Status:          Disconnected,
+		backoff:         retry.NewBackoff(parent.retryPolicy),
+		reset:           make(chan struct{}, 1),
+		active:          parent.currentSettings().ServerURL,
+		subsystems:      make(map[Subsystem]SubsystemStatus),
+		history:         make([]StateChange, 0),
+		prompted:        make(map[string]bool),
+		Status:          Connecting,
 	}
-	parent.WaitGroup().Add(1)
-	go controller.run()
 	return
 }
 
-func (controller *ConnectionController) run() {
-	defer controller.parent.WaitGroup().Done()
-	ticker := time.NewTicker(controller.refreshinterval)
-	controller.updateStatus()
+func (controller *ConnectionController) Start(ctx context.Context) error {
+	return controller.routines.start(ctx, controller.run)
+}
+
+func (controller *ConnectionController) Stop() {
+	controller.routines.stop()
+}
+
+// Reset starts connecting to the server URL from scratch, e.g. after it changed. The components using
+// the server are restarted once the API client uses it.
+func (controller *ConnectionController) Reset() {
+	select {
+	case controller.reset <- struct{}{}:
+	default:
+	}
+}
+
+// run checks the health in the refresh interval while connected and backs off while disconnected.
+func (controller *ConnectionController) run(ctx context.Context) {
+	timer := time.NewTimer(controller.updateStatus(ctx))
+	defer timer.Stop()
 	for {
 		select {
-		case <-controller.parent.Context().Done():
-			log.Trace().Err(controller.parent.Context().Err()).Msg("exiting connectioncontroller run()")
+		case <-ctx.Done():
+			log.Trace().Err(ctx.Err()).Msg("exiting connectioncontroller run()")
 			return
-		case <-ticker.C:
-			controller.updateStatus()
+		case <-controller.reset:
+			controller.mutex.Lock()
+			controller.prompted = make(map[string]bool)
+			controller.mutex.Unlock()
+			serverurl := controller.serverURLs()[0]
+			err := controller.switchServer(serverurl)
+			controller.setStatus(Connecting, nil)
+			if err != nil {
+				log.Error().Err(err).Str("serverurl", serverurl).Msg("error switching server")
+			} else {
+				controller.parent.serverSwitched()
+			}
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(controller.updateStatus(ctx))
+		case <-timer.C:
+			timer.Reset(controller.updateStatus(ctx))
 		}
 	}
 }
 
-func (controller *ConnectionController) updateStatus() {
+// updateStatus checks the health of the server and returns the delay until the next check.
+func (controller *ConnectionController) updateStatus(ctx context.Context) time.Duration {
+	delay := controller.refreshinterval
+	start := time.Now()
+	err := controller.parent.healthService.Health()
+	rtt := time.Since(start)
+	if err != nil {
+		delay = max(controller.backoff.Failure(), controller.refreshinterval)
+		log.Trace().Err(err).Dur("retry", delay).Stringer("circuit", controller.backoff.State()).Msg("server health check failed")
+	} else {
+		controller.backoff.Success()
+	}
+
 	controller.mutex.Lock()
-	oldstatus := controller.Status
-	if controller.parent.client.Health.Health() != nil {
-		controller.Status = Disconnected
+	controller.probes = append(controller.probes, probe{rtt: rtt, ok: err == nil})
+	if len(controller.probes) > QUALITY_WINDOW {
+		controller.probes = controller.probes[len(controller.probes)-QUALITY_WINDOW:]
+	}
+	if err != nil {
+		controller.failures++
 	} else {
-		controller.Status = Connected
+		controller.failures = 0
+	}
+	if err == nil || isIncompatible(err) {
+		controller.incompatible = err
 	}
-	newstatus := controller.Status
 	controller.mutex.Unlock()
-	if newstatus != oldstatus {
-		controller.notifySubcriber()
+
+	status, cause := controller.evaluate(err)
+	controller.setStatus(status, cause)
+	if controller.followServers(ctx, status) {
+		return controller.refreshinterval
 	}
+	quality := controller.Quality()
+	controller.parent.metrics.connectionRTT.Set(quality.RTT.Seconds())
+	controller.parent.metrics.connectionLoss.Set(quality.Loss)
+	controller.quality.Publish(TopicConnectionQuality, quality)
+	return delay
 }
 
-func (controller *ConnectionController) Subscribe(subscriber chan struct{}) {
-	defer controller.mutex.Unlock()
+// evaluate derives the status from the health checks and the reports of the subsystems.
+func (controller *ConnectionController) evaluate(err error) (ConnectionStatus, error) {
+	quality := controller.Quality()
+	controller.mutex.RLock()
+	defer controller.mutex.RUnlock()
+	if controller.incompatible != nil {
+		return ServerIncompatible, controller.incompatible
+	}
+	if controller.apiIncompatible != nil {
+		return ServerIncompatible, controller.apiIncompatible
+	}
+	if controller.rejected != nil {
+		return Unauthorized, controller.rejected
+	}
+	if err != nil {
+		if controller.Status.IsConnected() && controller.failures < DISCONNECT_FAILURES {
+			return Degraded, err
+		}
+		return Disconnected, err
+	}
+	if quality.RTT > DEGRADED_RTT || quality.Loss > DEGRADED_LOSS {
+		return Degraded, nil
+	}
+	for _, subsystem := range controller.subsystems {
+		if !subsystem.OK {
+			return Degraded, errors.New(string(subsystem.Subsystem) + ": " + subsystem.Err)
+		}
+	}
+	return Connected, nil
+}
+
+func (controller *ConnectionController) setStatus(status ConnectionStatus, cause error) {
 	controller.mutex.Lock()
-	controller.subscriber = append(controller.subscriber, subscriber)
+	oldstatus := controller.Status
+	controller.Status = status
+	if status != oldstatus {
+		change := StateChange{From: oldstatus, To: status, Time: time.Now()}
+		if cause != nil {
+			change.Err = cause.Error()
+		}
+		controller.history = append(controller.history, change)
+		if len(controller.history) > HISTORY_SIZE {
+			controller.history = controller.history[len(controller.history)-HISTORY_SIZE:]
+		}
+	}
+	controller.mutex.Unlock()
+	if status != oldstatus {
+		log.Debug().Err(cause).Stringer("from", oldstatus).Stringer("to", status).Msg("connection status changed")
+		controller.parent.metrics.connectionStateChanges.Inc(status.String())
+		if status.IsConnected() {
+			controller.parent.metrics.connectionConnected.Set(1)
+		} else {
+			controller.parent.metrics.connectionConnected.Set(0)
+		}
+		controller.events.Publish(TopicConnectionStatus, status)
+	}
 }
 
-func (controller *ConnectionController) Unsubscribe(subscriber chan struct{}) {
-	defer controller.mutex.Unlock()
+// followServers fails over to the next healthy server while disconnected and fails back to a
+// preferred server once it is healthy again. It returns true if the server was switched.
+func (controller *ConnectionController) followServers(ctx context.Context, status ConnectionStatus) bool {
+	serverurls := controller.serverURLs()
+	active := controller.ServerURL()
+	index := slices.Index(serverurls, active)
+	candidates := make([]string, 0)
+	reason := ""
+	switch {
+	case status == Disconnected && len(serverurls) > 1:
+		for _, serverurl := range serverurls {
+			if serverurl != active {
+				candidates = append(candidates, serverurl)
+			}
+		}
+		reason = "failed over"
+	case status.IsConnected() && index != 0:
+		controller.mutex.Lock()
+		due := time.Since(controller.lastfailback) >= FAILBACK_INTERVAL
+		if due {
+			controller.lastfailback = time.Now()
+		}
+		controller.mutex.Unlock()
+		if !due {
+			return false
+		}
+		candidates = serverurls
+		if index > 0 {
+			candidates = serverurls[:index]
+		}
+		reason = "failed back"
+	}
+	for _, serverurl := range candidates {
+		err := controller.parent.serverProbe.Probe(ctx, serverurl)
+		if err != nil {
+			log.Debug().Err(err).Str("serverurl", serverurl).Msg("server not healthy")
+			continue
+		}
+		err = controller.switchServer(serverurl)
+		if err != nil {
+			log.Error().Err(err).Str("serverurl", serverurl).Msg("error switching server")
+			continue
+		}
+		controller.setStatus(Connecting, fmt.Errorf("%s to %s", reason, serverurl))
+		log.Warn().Str("from", active).Str("to", serverurl).Msg(reason + " to server")
+		if controller.parent.Status != nil {
+			controller.parent.Status.Warning(fmt.Sprintf("Switched to server %s", serverurl), 5*time.Second)
+		}
+		controller.parent.serverSwitched()
+		return true
+	}
+	return false
+}
+
+// switchServer makes the API client use the server, it is the server reported by ServerURL afterwards.
+func (controller *ConnectionController) switchServer(serverurl string) error {
+	err := controller.parent.endpointService.SetBaseURL(serverurl)
+	if err != nil {
+		return err
+	}
+	controller.connect(serverurl)
+	return nil
+}
+
+// connect forgets the quality of the previous server.
+func (controller *ConnectionController) connect(serverurl string) {
 	controller.mutex.Lock()
-	index := slices.Index(controller.subscriber, subscriber)
-	_ = slices.Delete(controller.subscriber, index, index+1)
+	controller.active = serverurl
+	controller.lastfailback = time.Now()
+	controller.probes = nil
+	controller.failures = 0
+	controller.subsystems = make(map[Subsystem]SubsystemStatus)
+	controller.incompatible = nil
+	controller.apiIncompatible = nil
+	controller.rejected = nil
+	controller.mutex.Unlock()
+	controller.backoff.Success()
+}
+
+func (controller *ConnectionController) serverURLs() []string {
+	return controller.parent.currentSettings().ServerURLs()
 }
 
-func (controller *ConnectionController) notifySubcriber() {
+// ServerURL returns the server in use, which is a backup server after a failover.
+func (controller *ConnectionController) ServerURL() string {
 	defer controller.mutex.RUnlock()
 	controller.mutex.RLock()
-	for _, subscriber := range controller.subscriber {
-		util.ChannelWriteNonBlocking(subscriber, struct{}{})
+	return controller.active
+}
+
+// report records the result of a request of the subsystem, which is shown in the connection details.
+func (controller *ConnectionController) report(subsystem Subsystem, err error) {
+	controller.mutex.Lock()
+	defer controller.mutex.Unlock()
+	current, found := controller.subsystems[subsystem]
+	ok := err == nil
+	if found && current.OK == ok && (ok || current.Err == err.Error()) {
+		return
+	}
+	status := SubsystemStatus{Subsystem: subsystem, OK: ok, Since: time.Now()}
+	if err != nil {
+		status.Err = err.Error()
+	}
+	controller.subsystems[subsystem] = status
+	if isIncompatible(err) {
+		controller.apiIncompatible = err
+	} else if ok && subsystem == SubsystemAPI {
+		controller.apiIncompatible = nil
+	}
+}
+
+func (controller *ConnectionController) GetStatus() ConnectionStatus {
+	defer controller.mutex.RUnlock()
+	controller.mutex.RLock()
+	return controller.Status
+}
+
+// Quality returns the status, the average latency and the packet loss of the recent health checks
+// and the status of the subsystems.
+func (controller *ConnectionController) Quality() ConnectionQuality {
+	defer controller.mutex.RUnlock()
+	controller.mutex.RLock()
+	quality := ConnectionQuality{
+		Status:     controller.Status,
+		Subsystems: make([]SubsystemStatus, 0, len(controller.subsystems)),
+	}
+	var total time.Duration
+	succeeded := 0
+	for _, probe := range controller.probes {
+		if probe.ok {
+			total += probe.rtt
+			succeeded++
+		}
+	}
+	if succeeded > 0 {
+		quality.RTT = total / time.Duration(succeeded)
+	}
+	if len(controller.probes) > 0 {
+		quality.Loss = float64(len(controller.probes)-succeeded) / float64(len(controller.probes))
+	}
+	for _, subsystem := range []Subsystem{SubsystemAPI, SubsystemChat, SubsystemFileTransfer} {
+		if status, found := controller.subsystems[subsystem]; found {
+			quality.Subsystems = append(quality.Subsystems, status)
+		}
+	}
+	return quality
+}
+
+// History returns the latest state changes, oldest first.
+func (controller *ConnectionController) History() []StateChange {
+	defer controller.mutex.RUnlock()
+	controller.mutex.RLock()
+	return slices.Clone(controller.history)
+}
+
+func (controller *ConnectionController) Subscribe(subscriber chan event.Event[ConnectionStatus]) {
+	controller.events.SubscribeWithReplay(subscriber, TopicConnectionStatus)
+}
+
+func (controller *ConnectionController) Unsubscribe(subscriber chan event.Event[ConnectionStatus]) {
+	controller.events.Unsubscribe(subscriber)
+}
+
+// SubscribeQuality publishes the quality after every health check.
+func (controller *ConnectionController) SubscribeQuality(subscriber chan event.Event[ConnectionQuality]) {
+	controller.quality.SubscribeWithReplay(subscriber, TopicConnectionQuality)
+}
+
+func (controller *ConnectionController) UnsubscribeQuality(subscriber chan event.Event[ConnectionQuality]) {
+	controller.quality.Unsubscribe(subscriber)
+}
+
+// SubscribeUntrusted publishes every certificate which is not trusted once, so the user can decide
+// to trust it. The certificates are published again after the server or its settings changed.
+func (controller *ConnectionController) SubscribeUntrusted(subscriber chan event.Event[trust.UntrustedError]) {
+	controller.untrusted.Subscribe(subscriber, TopicConnectionUntrusted)
+}
+
+func (controller *ConnectionController) UnsubscribeUntrusted(subscriber chan event.Event[trust.UntrustedError]) {
+	controller.untrusted.Unsubscribe(subscriber)
+}
+
+func (controller *ConnectionController) untrustedCertificate(err *trust.UntrustedError) {
+	controller.mutex.Lock()
+	prompted := controller.prompted[err.Fingerprint]
+	controller.prompted[err.Fingerprint] = true
+	controller.mutex.Unlock()
+	if !prompted {
+		controller.untrusted.Publish(TopicConnectionUntrusted, *err)
+	}
+}
+
+// SubscribeUnauthorized publishes the server once it rejects the requests because of the event
+// password, so the user can enter it. It is published again after the server or password changed.
+func (controller *ConnectionController) SubscribeUnauthorized(subscriber chan event.Event[string]) {
+	controller.unauthorized.Subscribe(subscriber, TopicConnectionUnauthorized)
+}
+
+func (controller *ConnectionController) UnsubscribeUnauthorized(subscriber chan event.Event[string]) {
+	controller.unauthorized.Unsubscribe(subscriber)
+}
+
+// unauthorizedRequest marks the connection unauthorized, if the server in use rejected a request.
+func (controller *ConnectionController) unauthorizedRequest(host string) {
+	u, err := url.Parse(setting.BaseURL(controller.ServerURL()))
+	if err != nil || u.Host != host {
+		return
+	}
+	controller.mutex.Lock()
+	rejected := controller.rejected
+	controller.rejected = ErrUnauthorized
+	controller.mutex.Unlock()
+	if rejected == nil {
+		log.Warn().Str("server", controller.ServerURL()).Msg("server rejected the event password")
+		controller.unauthorized.Publish(TopicConnectionUnauthorized, controller.ServerURL())
+	}
+}
+
+func isIncompatible(err error) bool {
+	var syntaxerr *json.SyntaxError
+	var typeerr *json.UnmarshalTypeError
+	return errors.Is(err, ErrIncompatible) || errors.As(err, &syntaxerr) || errors.As(err, &typeerr)
+}
+
+// reportConnection reports the result of a request of the subsystem to the connection controller,
+// if there is one.
+func (controller *Controller) reportConnection(subsystem Subsystem, err error) {
+	if controller.Connection != nil {
+		controller.Connection.report(subsystem, err)
 	}
 }
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/seternate/go-lanty-client/pkg/lantytest"
+	"github.com/seternate/go-lanty/pkg/game"
+)
+
+func TestSwitchServer(t *testing.T) {
+	first := lantytest.NewServer()
+	defer first.Close()
+	second := lantytest.NewServer()
+	defer second.Close()
+	for _, server := range []*lantytest.Server{first, second} {
+		server.SetUserIP("10.0.0.2")
+	}
+	err := first.AddGame(game.Game{Slug: "quake", Name: "Quake"}, nil, nil)
+	if err == nil {
+		err = second.AddGame(game.Game{Slug: "doom", Name: "Doom"}, nil, nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	controller := newTestController(t, first)
+	expectServer(t, controller, first, second, "quake")
+
+	// Switching back happens before the next failback check, so only the reset switches the server.
+	switchServer(t, controller, first, second)
+	expectServer(t, controller, second, first, "doom")
+	switchServer(t, controller, second, first)
+	expectServer(t, controller, first, second, "quake")
+}
+
+// switchServer changes the server URL while the connection controller is still checking the health
+// of the old server, which answers slowly.
+func switchServer(t *testing.T, controller *Controller, previous *lantytest.Server, next *lantytest.Server) {
+	t.Helper()
+	previous.SetLatency(500 * time.Millisecond)
+	defer previous.SetLatency(0)
+	checks := previous.Requests(lantytest.RouteHealth)
+	eventually(t, func() bool { return previous.Requests(lantytest.RouteHealth) > checks }, "slow health check")
+	err := controller.Settings.SetServerURL(next.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// expectServer waits until the user, the chat and the games are the ones of the server.
+func expectServer(t *testing.T, controller *Controller, server *lantytest.Server, other *lantytest.Server, slug string) {
+	t.Helper()
+	eventually(t, func() bool { return controller.Connection.ServerURL() == server.URL }, "server %s in use", server.URL)
+	eventually(t, func() bool { return serverUser(server, "10.0.0.2").Name == "player" }, "login at %s", server.URL)
+	eventually(t, func() bool { return server.ChatConnections() == 1 && other.ChatConnections() == 0 }, "chat at %s", server.URL)
+	eventually(t, func() bool {
+		_, err := controller.Game.GetGames().Get(slug)
+		return err == nil && len(controller.Game.GetGames().Games()) == 1
+	}, "games of %s", server.URL)
+}
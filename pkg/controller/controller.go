@@ -2,11 +2,22 @@ package controller
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
 	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"github.com/seternate/go-lanty-client/pkg/auth"
+	"github.com/seternate/go-lanty-client/pkg/discovery"
+	"github.com/seternate/go-lanty-client/pkg/event"
+	"github.com/seternate/go-lanty-client/pkg/gateway"
+	"github.com/seternate/go-lanty-client/pkg/metrics"
+	"github.com/seternate/go-lanty-client/pkg/retry"
 	"github.com/seternate/go-lanty-client/pkg/setting"
+	"github.com/seternate/go-lanty-client/pkg/supervisor"
+	"github.com/seternate/go-lanty-client/pkg/trust"
 	"github.com/seternate/go-lanty/pkg/api"
 	"github.com/seternate/go-lanty/pkg/handler"
 )
@@ -20,40 +31,141 @@ type Controller struct {
 	Chat       *ChatController
 	Connection *ConnectionController
 
-	settings  *setting.Settings
-	client    *api.Client
-	ctx       context.Context
-	cancelCtx context.CancelFunc
-	waitgrp   *sync.WaitGroup
+	settings         *setting.Settings
+	settingsErr      error
+	gameService      GameService
+	userService      UserService
+	chatService      ChatService
+	fileService      FileService
+	healthService    HealthService
+	endpointService  EndpointService
+	discoveryService DiscoveryService
+	retryPolicy      retry.Policy
+	serverProbe      ServerProbe
+	verifier         *trust.Verifier
+	credentials      *auth.Credentials
+	transport        http.RoundTripper
+	lifecycle        *Lifecycle
+	metrics          *clientMetrics
+	supervisor       *supervisor.Supervisor
+	ctx              context.Context
+	cancelCtx        context.CancelFunc
+	waitgrp          *sync.WaitGroup
 }
 
-func NewController(ctx context.Context) *Controller {
+// NewController creates the controller with the services of the options. Settings which are not
+// given are loaded from the settings file and services which are not given use the API client of the
+// server.
+func NewController(ctx context.Context, options ...Option) (*Controller, error) {
+	context, cancelContext := context.WithCancel(ctx)
+	controller := &Controller{
+		discoveryService: discovery.NewClient(discovery.PORT, discovery.TIMEOUT),
+		retryPolicy:      retry.DefaultPolicy(),
+		verifier:         trust.NewVerifier(),
+		credentials:      auth.NewCredentials(),
+		lifecycle:        NewLifecycle(),
+		metrics:          newClientMetrics(),
+		ctx:              context,
+		cancelCtx:        cancelContext,
+		waitgrp:          &sync.WaitGroup{},
+	}
+	controller.supervisor = supervisor.NewSupervisor(controller.waitgrp, controller.panicked)
+	for _, option := range options {
+		option(controller)
+	}
+	if controller.settings == nil {
+		controller.loadSettings()
+	}
+	log.Debug().Interface("settings", controller.settings).Msg("loaded settings successfully")
+
+	err := controller.verifier.SetConfig(trustConfig(controller.settings.Profile))
+	if err != nil {
+		log.Error().Err(err).Msg("error loading TLS settings")
+	}
+	controller.verifier.OnUntrusted = controller.untrustedCertificate
+	controller.credentials.OnUnauthorized = controller.unauthorizedRequest
+	controller.transport = controller.credentials.Transport(controller.verifier.Transport())
+	if controller.serverProbe == nil {
+		controller.serverProbe = &httpServerProbe{client: &http.Client{Timeout: PROBE_TIMEOUT, Transport: controller.transport}}
+	}
+
+	err = controller.createAPIClient()
+	if err != nil {
+		cancelContext()
+		return nil, err
+	}
+	return controller, nil
+}
+
+// loadSettings loads the settings file and the event password of the active profile. Settings which
+// can not be loaded are replaced by the defaults and the error is shown once the controller started.
+func (controller *Controller) loadSettings() {
 	settings, err := setting.LoadSettings()
+	controller.settingsErr = err
+	var recovery *setting.RecoveryError
+	if errors.As(err, &recovery) {
+		log.Warn().Err(err).Msg("recovered settings")
+	} else if err != nil {
+		log.Error().Err(err).Msg("failed to load settings, using the defaults")
+		settings = setting.Default()
+	}
+	controller.settings = settings
+
+	password, err := setting.LoadPassword(settings.ActiveProfile)
 	if err != nil {
-		log.Fatal().Err(err).Msg("failed to load settings")
+		log.Error().Err(err).Msg("error loading event password")
 	}
-	log.Debug().Interface("settings", settings).Msg("loaded settings successfully")
+	controller.credentials.Set(password, settings.ServerURLs())
+}
 
-	timeout, err := time.ParseDuration("0s")
+// createAPIClient creates the API client of the server for the services which were not given as
+// options. It is not created if all of them were given. The API client talks to the server through
+// a gateway, which connects with the transport of the controller.
+func (controller *Controller) createAPIClient() error {
+	if controller.gameService != nil && controller.userService != nil && controller.chatService != nil &&
+		controller.fileService != nil && controller.healthService != nil && controller.endpointService != nil {
+		return nil
+	}
+	gateway, err := gateway.New(controller.transport)
+	if err != nil {
+		return fmt.Errorf("error starting API gateway: %w", err)
+	}
+	gateway.CloseAfter(controller.ctx)
+	err = gateway.SetServerURL(setting.BaseURL(controller.settings.ServerURL))
 	if err != nil {
-		log.Fatal().Err(err).Send()
+		log.Warn().Err(err).Msg("invalid server URL")
 	}
 
-	client, err := api.NewClient(settings.ServerURL, timeout)
+	timeout, err := time.ParseDuration("0s")
 	if err != nil {
-		log.Error().Err(err).Msg("error creating API client")
+		return err
+	}
+	client, err := api.NewClient(gateway.URL(), timeout)
+	if err != nil {
+		gateway.Close()
+		return fmt.Errorf("error creating API client: %w", err)
 	}
 	log.Debug().Msg("created API client")
 
-	context, cancelContext := context.WithCancel(ctx)
-
-	return &Controller{
-		settings:  settings,
-		client:    client,
-		ctx:       context,
-		cancelCtx: cancelContext,
-		waitgrp:   &sync.WaitGroup{},
+	if controller.gameService == nil {
+		controller.gameService = client.Game
+	}
+	if controller.userService == nil {
+		controller.userService = client.User
+	}
+	if controller.chatService == nil {
+		controller.chatService = &apiChatService{client: client}
+	}
+	if controller.fileService == nil {
+		controller.fileService = &apiFileService{client: client, gateway: gateway}
+	}
+	if controller.healthService == nil {
+		controller.healthService = client.Health
 	}
+	if controller.endpointService == nil {
+		controller.endpointService = &gatewayEndpointService{gateway: gateway}
+	}
+	return nil
 }
 
 func (controller *Controller) Context() context.Context {
@@ -64,41 +176,174 @@ func (controller *Controller) Quit() {
 	controller.cancelCtx()
 }
 
+// Start starts all sub-controllers after their dependencies. Components using the server are
+// restarted when the server URL, its TLS settings or the event password change.
+func (controller *Controller) Start() error {
+	err := controller.lifecycle.Start(controller.ctx)
+	if err != nil {
+		return err
+	}
+	if controller.Settings != nil {
+		serverchanged := make(chan event.Event[setting.Settings], 50)
+		controller.Settings.Subscribe(serverchanged, TopicSettingsServerURL, TopicSettingsTLS, TopicSettingsPassword)
+		controller.Go("Controller.serverWatcher", func() {
+			controller.serverWatcher(serverchanged)
+		})
+	}
+	return nil
+}
+
+// Register adds a component to the lifecycle of the controller, it has to be called before Start.
+func (controller *Controller) Register(name string, component Component, dependencies ...string) {
+	controller.lifecycle.Register(name, component, dependencies...)
+}
+
+func (controller *Controller) Stop() {
+	controller.lifecycle.Stop()
+}
+
+func (controller *Controller) Restart(components ...string) error {
+	return controller.lifecycle.Restart(components...)
+}
+
+func (controller *Controller) Health() []ComponentHealth {
+	return controller.lifecycle.Health()
+}
+
+func (controller *Controller) serverWatcher(serverchanged chan event.Event[setting.Settings]) {
+	for {
+		select {
+		case <-controller.ctx.Done():
+			controller.Settings.Unsubscribe(serverchanged)
+			log.Trace().Err(controller.ctx.Err()).Msg("exiting controller serverWatcher()")
+			return
+		case <-serverchanged:
+			// The connection controller restarts the components once it switched to the server.
+			if controller.Connection != nil {
+				controller.Connection.Reset()
+				continue
+			}
+			err := controller.Restart(ComponentGame, ComponentUser, ComponentChat)
+			if err != nil {
+				log.Error().Err(err).Msg("error restarting components after server change")
+			}
+		}
+	}
+}
+
+// setTrustConfig verifies the server certificates with the TLS settings of the profile.
+func (controller *Controller) setTrustConfig(profile setting.Profile) error {
+	err := controller.verifier.SetConfig(trustConfig(profile))
+	if err != nil {
+		log.Error().Err(err).Msg("error loading TLS settings")
+	}
+	return err
+}
+
+func (controller *Controller) untrustedCertificate(err *trust.UntrustedError) {
+	if controller.Connection != nil {
+		controller.Connection.untrustedCertificate(err)
+	}
+}
+
+func (controller *Controller) unauthorizedRequest(host string) {
+	if controller.Connection != nil {
+		controller.Connection.unauthorizedRequest(host)
+	}
+}
+
+func trustConfig(profile setting.Profile) trust.Config {
+	return trust.Config{
+		CAFile:             profile.CAFile,
+		Fingerprints:       profile.PinnedCertificates,
+		InsecureSkipVerify: profile.InsecureSkipVerify,
+	}
+}
+
+// serverSwitched reconnects the components using the server after the connection switched it and
+// restarts the running downloads on the new server.
+func (controller *Controller) serverSwitched() {
+	err := controller.Restart(ComponentGame, ComponentUser, ComponentChat)
+	if err != nil {
+		log.Error().Err(err).Msg("error restarting components after server switch")
+	}
+	if controller.Download != nil {
+		controller.Download.restartRunning()
+	}
+}
+
+// currentSettings returns a copy of the settings. They are read through the settings controller, which
+// replaces them under its lock, if there is one.
+func (controller *Controller) currentSettings() setting.Settings {
+	if controller.Settings != nil {
+		return controller.Settings.Settings()
+	}
+	return *controller.settings
+}
+
+func (controller *Controller) Metrics() *metrics.Registry {
+	return controller.metrics.registry
+}
+
+// Go runs the function in a goroutine, which is recovered and restarted with backoff if it panics.
+func (controller *Controller) Go(name string, function func(), options ...supervisor.Option) <-chan struct{} {
+	return controller.supervisor.Go(controller.ctx, name, function, options...)
+}
+
+func (controller *Controller) panicked(recovered supervisor.Panic) {
+	controller.metrics.panics.Inc(recovered.Name)
+	if controller.Status == nil {
+		return
+	}
+	if recovered.Stopped {
+		controller.Status.Error(fmt.Sprintf("Internal error in %s, it was stopped", recovered.Name), 8*time.Second)
+	} else {
+		controller.Status.Error(fmt.Sprintf("Internal error in %s, restarting", recovered.Name), 8*time.Second)
+	}
+}
+
 func (controller *Controller) WaitGroup() *sync.WaitGroup {
 	return controller.waitgrp
 }
 
 func (controller *Controller) WithSettingsController() *Controller {
 	controller.Settings = NewSettingsController(controller, controller.settings)
+	controller.lifecycle.Register(ComponentSettings, controller.Settings, ComponentStatus)
 	return controller
 }
 
 func (controller *Controller) WithStatusController() *Controller {
-	controller.Status = NewStatusController()
+	controller.Status = NewStatusController(controller)
+	controller.lifecycle.Register(ComponentStatus, controller.Status)
 	return controller
 }
 
 func (controller *Controller) WithGameController() *Controller {
 	controller.Game = NewGameController(controller, 2*time.Second)
+	controller.lifecycle.Register(ComponentGame, controller.Game, ComponentSettings)
 	return controller
 }
 
 func (controller *Controller) WithDownloadController() *Controller {
 	controller.Download = NewDownloadController(controller)
+	controller.lifecycle.Register(ComponentDownload, controller.Download, ComponentSettings, ComponentStatus)
 	return controller
 }
 
 func (controller *Controller) WithUserController() *Controller {
 	controller.User = NewUserController(controller, 3*handler.UserStaleDuration/4)
+	controller.lifecycle.Register(ComponentUser, controller.User, ComponentSettings)
 	return controller
 }
 
 func (controller *Controller) WithChatController() *Controller {
 	controller.Chat = NewChatController(controller)
+	controller.lifecycle.Register(ComponentChat, controller.Chat, ComponentSettings, ComponentStatus, ComponentUser)
 	return controller
 }
 
 func (controller *Controller) WithConnectionController() *Controller {
 	controller.Connection = NewConnectionController(controller, 1*time.Second)
+	controller.lifecycle.Register(ComponentConnection, controller.Connection)
 	return controller
 }
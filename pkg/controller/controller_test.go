@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/seternate/go-lanty-client/pkg/lantytest"
+	"github.com/seternate/go-lanty-client/pkg/retry"
+	"github.com/seternate/go-lanty-client/pkg/setting"
+)
+
+const (
+	TEST_INTERVAL = 50 * time.Millisecond
+	TEST_TIMEOUT  = 5 * time.Second
+)
+
+func TestMain(m *testing.M) {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	os.Exit(m.Run())
+}
+
+// newTestController starts a controller with all components talking to the server. The config, data
+// and cache folders are moved to a temporary folder and the components poll with TEST_INTERVAL.
+func newTestController(t *testing.T, server *lantytest.Server, options ...Option) *Controller {
+	t.Helper()
+	home := t.TempDir()
+	for _, variable := range []string{"HOME", "XDG_CONFIG_HOME", "XDG_CACHE_HOME", "XDG_DATA_HOME", "XDG_STATE_HOME", "APPDATA", "LocalAppData"} {
+		t.Setenv(variable, filepath.Join(home, variable))
+	}
+
+	directory, err := setting.Directory()
+	if err == nil {
+		err = os.MkdirAll(directory, 0755)
+	}
+	if err != nil {
+		t.Fatalf("error creating config folder: %v", err)
+	}
+
+	settings := setting.Default()
+	settings.ServerURL = server.URL
+	settings.GameDirectory = filepath.Join(home, "games")
+	settings.DownloadDirectory = filepath.Join(home, "downloads")
+	settings.Username = "player"
+	err = settings.Save()
+	if err != nil {
+		t.Fatalf("error saving settings: %v", err)
+	}
+
+	policy := retry.Policy{Initial: 10 * time.Millisecond, Max: 100 * time.Millisecond, Multiplier: 2}
+	options = append([]Option{WithSettings(settings), WithRetryPolicy(policy)}, options...)
+	controller, err := NewController(context.Background(), options...)
+	if err != nil {
+		t.Fatalf("error creating controller: %v", err)
+	}
+	controller.WithStatusController().WithSettingsController().WithDownloadController()
+	controller.Game = NewGameController(controller, TEST_INTERVAL)
+	controller.Register(ComponentGame, controller.Game, ComponentSettings)
+	controller.User = NewUserController(controller, TEST_INTERVAL)
+	controller.Register(ComponentUser, controller.User, ComponentSettings)
+	controller.WithChatController()
+	controller.Chat.ticker.Reset(TEST_INTERVAL)
+	controller.Connection = NewConnectionController(controller, TEST_INTERVAL)
+	controller.Register(ComponentConnection, controller.Connection)
+
+	t.Cleanup(func() {
+		controller.Quit()
+		controller.WaitGroup().Wait()
+	})
+	err = controller.Start()
+	if err != nil {
+		t.Fatalf("error starting controller: %v", err)
+	}
+	return controller
+}
+
+// eventually fails the test if the condition is not true within TEST_TIMEOUT.
+func eventually(t *testing.T, condition func() bool, format string, args ...any) {
+	t.Helper()
+	deadline := time.Now().Add(TEST_TIMEOUT)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting: "+format, args...)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
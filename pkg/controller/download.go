@@ -7,56 +7,79 @@ import (
 	"os"
 	"path"
 	"path/filepath"
-	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"github.com/seternate/go-lanty-client/pkg/event"
+	"github.com/seternate/go-lanty-client/pkg/retry"
+	"github.com/seternate/go-lanty-client/pkg/supervisor"
 	"github.com/seternate/go-lanty/pkg/filesystem"
 	"github.com/seternate/go-lanty/pkg/game"
 	"github.com/seternate/go-lanty/pkg/network"
-	"github.com/seternate/go-lanty/pkg/util"
 )
 
+// DOWNLOAD_START_ATTEMPTS is how often starting a download is tried before it is stopped.
+const DOWNLOAD_START_ATTEMPTS = 10
+
 type Download struct {
-	controller         *Controller
-	game               game.Game
-	download           *network.Download
-	unzip              *filesystem.Unzip
-	subscriber         []chan struct{}
-	subscriberprogress []chan struct{}
-	started            bool
-	stopped            bool
-	running            bool
-	downloading        bool
-	err                error
-	retries            uint64
-	mutex              sync.RWMutex
-	context            context.Context
-	cancelContext      context.CancelFunc
+	controller    *Controller
+	game          game.Game
+	download      *network.Download
+	unzip         *filesystem.Unzip
+	events        *event.EventBus[DownloadEvent]
+	started       bool
+	stopped       bool
+	running       bool
+	downloading   bool
+	err           error
+	retries       uint64
+	backoff       *retry.Backoff
+	restarting    bool
+	received      float64
+	mutex         sync.RWMutex
+	context       context.Context
+	cancelContext context.CancelFunc
 }
 
 func NewDownload(controller *Controller, game game.Game) (download *Download) {
 	download = &Download{
 		controller:  controller,
 		game:        game,
-		subscriber:  make([]chan struct{}, 0, 50),
+		events:      event.NewEventBus[DownloadEvent](),
 		started:     false,
 		stopped:     false,
 		running:     false,
 		downloading: false,
+		backoff:     retry.NewBackoff(controller.retryPolicy.WithMaxAttempts(DOWNLOAD_START_ATTEMPTS)),
 	}
 	return
 }
 
-func (controller *Download) Start(ctx context.Context, waitgrp *sync.WaitGroup) (err error) {
+func (controller *Download) Start(ctx context.Context) (err error) {
 	if controller.IsStarted() {
 		log.Debug().Str("slug", controller.Game().Slug).Msg("download already started")
 		return errors.New("download already started")
 	}
 	controller.context, controller.cancelContext = context.WithCancel(ctx)
-	download, err := controller.controller.client.Game.Download(controller.context, controller.game, controller.controller.settings.GameDirectory)
+	gamedirectory := controller.controller.currentSettings().GameDirectory
+	// The game folder is validated to be creatable, it is created by the first download. A folder
+	// which can not be created does not become creatable by retrying, so the download is stopped.
+	err = os.MkdirAll(gamedirectory, 0755)
+	if err != nil {
+		controller.mutex.Lock()
+		controller.err = err
+		controller.mutex.Unlock()
+		log.Error().Err(err).Str("path", gamedirectory).Msg("error creating game folder")
+		controller.Stop()
+		controller.controller.Status.Error(fmt.Sprintf("Error creating game folder \"%s\"", gamedirectory), 8*time.Second)
+		return
+	}
+	download, err := controller.controller.gameService.Download(controller.context, controller.game, gamedirectory)
+	if !errors.Is(err, context.Canceled) {
+		controller.controller.reportConnection(SubsystemFileTransfer, err)
+	}
 	if err != nil {
 		controller.mutex.Lock()
 		if strings.Contains(err.Error(), "connectex: No connection") {
@@ -66,17 +89,23 @@ func (controller *Download) Start(ctx context.Context, waitgrp *sync.WaitGroup)
 		}
 		controller.retries += 1
 		controller.mutex.Unlock()
+		controller.cancelContext()
+		controller.backoff.Failure()
+		controller.controller.metrics.downloadRetries.Inc(controller.game.Slug)
+		controller.notifySubcriber(TopicDownloadStatus)
 		log.Error().Err(err).Str("slug", controller.Game().Slug).Msg("error starting game download from server")
 		return
 	}
 	controller.mutex.Lock()
 	controller.download = download
+	controller.err = nil
 	controller.started = true
 	controller.running = true
 	controller.downloading = true
 	controller.mutex.Unlock()
-	waitgrp.Add(1)
-	go controller.watch(controller.context, waitgrp)
+	controller.controller.supervisor.Go(controller.context, "Download.watch", func() {
+		controller.watch(controller.context)
+	}, supervisor.WithField("slug", controller.game.Slug), supervisor.WithoutRestart(), supervisor.WithRecovered(controller.recovered))
 	log.Debug().Str("slug", controller.Game().Slug).Msg("game download started")
 	return
 }
@@ -123,6 +152,21 @@ func (controller *Download) IsStopped() bool {
 	return controller.stopped
 }
 
+// State returns queued, downloading, extracting, stopped or complete.
+func (controller *Download) State() string {
+	switch {
+	case !controller.IsStarted() && !controller.IsStopped():
+		return "queued"
+	case controller.IsDownloading():
+		return "downloading"
+	case controller.IsUnzipping():
+		return "extracting"
+	case controller.IsStopped():
+		return "stopped"
+	}
+	return "complete"
+}
+
 func (controller *Download) Filesize() int64 {
 	defer controller.mutex.RUnlock()
 	controller.mutex.RLock()
@@ -214,37 +258,55 @@ func (controller *Download) Stop() {
 		controller.stopped = true
 		controller.mutex.Unlock()
 		//time.Sleep(5 * time.Second)
-		controller.notifySubcriber()
+		controller.notifySubcriber(TopicDownloadStatus)
 	}
 }
 
-func (controller *Download) watch(ctx context.Context, waitgrp *sync.WaitGroup) {
-	defer waitgrp.Done()
-	controller.notifySubcriber()
-	controller.subscribeSubscriber(controller.download)
-	<-controller.download.Done
+func (controller *Download) watch(ctx context.Context) {
+	controller.notifySubcriber(TopicDownloadStatus)
+	progress := make(chan struct{}, 50)
+	controller.download.Subscribe(progress)
+downloadloop:
+	for {
+		select {
+		case <-controller.download.Done:
+			break downloadloop
+		case <-progress:
+			controller.recordProgress()
+			controller.notifySubcriber(TopicDownloadProgress)
+		}
+	}
+	controller.download.Unsubscribe(progress)
+	controller.recordProgress()
+	controller.controller.metrics.downloadBytesPerSecond.Set(0, controller.game.Slug)
+	if controller.download.Err != nil && controller.requeue(controller.download.Err) {
+		log.Trace().Str("slug", controller.game.Slug).Msg("exiting download watch()")
+		return
+	}
 	if controller.download.Err != nil {
 		controller.mutex.Lock()
 		controller.running = false
 		controller.downloading = false
 		if controller.download.Err == context.Canceled {
+			controller.controller.metrics.downloads.Inc("canceled")
 			controller.err = controller.download.Err
 			log.Debug().Err(controller.download.Err).Str("slug", controller.game.Slug).Msg("download canceled")
 		} else {
+			controller.controller.reportConnection(SubsystemFileTransfer, controller.download.Err)
+			controller.controller.metrics.downloads.Inc("failed")
 			controller.err = errors.New("error downloading")
 			log.Error().Err(controller.download.Err).Str("slug", controller.game.Slug).Msg("error downloading game")
 			controller.controller.Status.Error(fmt.Sprintf("Error downloading game: %s", controller.game.Name), 8*time.Second)
 		}
 		controller.mutex.Unlock()
-		controller.notifySubcriber()
+		controller.notifySubcriber(TopicDownloadStatus)
 		controller.removeGameData(controller.gameDataFilepath())
 		log.Trace().Str("slug", controller.game.Slug).Msg("exiting download watch()")
 		return
 	} else {
 		log.Debug().Str("slug", controller.game.Slug).Msg("game downloads download part finished")
 	}
-	controller.notifySubcriber()
-	controller.unsubscribeSubscriber(controller.download)
+	controller.notifySubcriber(TopicDownloadStatus)
 	controller.mutex.Lock()
 	controller.unzip = filesystem.NewUnzip(
 		controller.gameDataFilepath(),
@@ -254,28 +316,112 @@ func (controller *Download) watch(ctx context.Context, waitgrp *sync.WaitGroup)
 	controller.downloading = false
 	controller.mutex.Unlock()
 	log.Debug().Str("slug", controller.game.Slug).Msg("game downloads unzip part started")
-	controller.notifySubcriber()
-	controller.subscribeSubscriber(controller.unzip)
-	<-controller.unzip.Done
+	controller.notifySubcriber(TopicDownloadStatus)
+	controller.unzip.Subscribe(progress)
+unziploop:
+	for {
+		select {
+		case <-controller.unzip.Done:
+			break unziploop
+		case <-progress:
+			controller.notifySubcriber(TopicDownloadProgress)
+		}
+	}
+	controller.unzip.Unsubscribe(progress)
 	controller.mutex.Lock()
 	if controller.unzip.Err != nil {
 		controller.err = controller.unzip.Err
 		if controller.unzip.Err == context.Canceled {
+			controller.controller.metrics.downloads.Inc("canceled")
 			log.Debug().Err(controller.download.Err).Str("slug", controller.game.Slug).Msg("unzip canceled")
 		} else {
+			controller.controller.metrics.downloads.Inc("failed")
 			log.Error().Err(controller.unzip.Err).Str("slug", controller.game.Slug).Msg("error unzipping game")
 		}
 	} else {
+		controller.controller.metrics.downloads.Inc("complete")
 		log.Debug().Str("slug", controller.game.Slug).Msg("game downloads unzip part finished")
 	}
 	controller.running = false
 	controller.mutex.Unlock()
-	controller.notifySubcriber()
-	controller.unsubscribeSubscriber(controller.unzip)
+	controller.notifySubcriber(TopicDownloadStatus)
 	controller.removeGameData(controller.gameDataFilepath())
 	log.Trace().Str("slug", controller.game.Slug).Msg("exiting download watch()")
 }
 
+// restart cancels the transfer, which is queued again and started on the current server.
+func (controller *Download) restart() {
+	controller.mutex.Lock()
+	if !controller.downloading || controller.stopped {
+		controller.mutex.Unlock()
+		return
+	}
+	controller.restarting = true
+	controller.mutex.Unlock()
+	controller.cancelContext()
+}
+
+// requeue queues the download again after its transfer was restarted or failed, e.g. because the
+// server went down and the connection failed over to a backup server. It returns false if the
+// download is stopped by the user or given up.
+func (controller *Download) requeue(err error) bool {
+	controller.mutex.Lock()
+	restarting := controller.restarting
+	stopped := controller.stopped
+	controller.restarting = false
+	controller.mutex.Unlock()
+	if stopped || (errors.Is(err, context.Canceled) && !restarting) {
+		return false
+	}
+	if !restarting {
+		controller.controller.reportConnection(SubsystemFileTransfer, err)
+		controller.backoff.Failure()
+		if controller.backoff.Exhausted() {
+			return false
+		}
+		controller.mutex.Lock()
+		controller.retries += 1
+		controller.mutex.Unlock()
+		controller.controller.metrics.downloadRetries.Inc(controller.game.Slug)
+	}
+	controller.removeGameData(controller.gameDataFilepath())
+	controller.mutex.Lock()
+	controller.download = nil
+	controller.received = 0
+	controller.started = false
+	controller.running = false
+	controller.downloading = false
+	controller.err = nil
+	controller.mutex.Unlock()
+	log.Info().Err(err).Str("slug", controller.game.Slug).Bool("restart", restarting).Msg("queued download again")
+	controller.notifySubcriber(TopicDownloadStatus)
+	return true
+}
+
+// recovered stops the download after a panic in watch, as the download can not be continued.
+func (controller *Download) recovered(value any) {
+	controller.cancelContext()
+	controller.mutex.Lock()
+	controller.err = fmt.Errorf("internal error: %v", value)
+	controller.running = false
+	controller.downloading = false
+	controller.stopped = true
+	controller.mutex.Unlock()
+	controller.controller.metrics.downloads.Inc("failed")
+	controller.notifySubcriber(TopicDownloadStatus)
+}
+
+func (controller *Download) recordProgress() {
+	controller.mutex.Lock()
+	received := controller.download.Progress() * float64(controller.download.Filesize())
+	delta := received - controller.received
+	controller.received = received
+	bytesPerSecond := controller.download.BytesPerSecond()
+	controller.mutex.Unlock()
+	controller.controller.metrics.downloadBytes.Add(delta, controller.game.Slug)
+	controller.controller.metrics.downloadBytesPerSecond.Set(bytesPerSecond, controller.game.Slug)
+}
+
 func (controller *Download) removeGameData(filepath string) {
 	err := os.Remove(filepath)
 	if err != nil {
@@ -284,12 +430,12 @@ func (controller *Download) removeGameData(filepath string) {
 }
 
 func (controller *Download) gameDataFilepath() string {
-	return path.Join(controller.controller.settings.GameDirectory, controller.download.Filename())
+	return path.Join(controller.controller.currentSettings().GameDirectory, controller.download.Filename())
 }
 
 func (controller *Download) gameDataDestination() string {
-	gamedir := path.Join(controller.controller.settings.GameDirectory, controller.game.Slug)
-	paths, err := filesystem.SearchFilesBreadthFirst(controller.controller.Settings.Settings().GameDirectory, controller.game.Client.Executable, 3, 1)
+	gamedir := path.Join(controller.controller.currentSettings().GameDirectory, controller.game.Slug)
+	paths, err := filesystem.SearchFilesBreadthFirst(controller.controller.currentSettings().GameDirectory, controller.game.Client.Executable, 3, 1)
 	if len(paths) > 0 && err == nil {
 		gamedir = filepath.Dir(paths[0])
 	}
@@ -297,51 +443,18 @@ func (controller *Download) gameDataDestination() string {
 	return gamedir
 }
 
-func (controller *Download) Subscribe(subscriber chan struct{}) {
-	defer controller.mutex.Unlock()
-	controller.mutex.Lock()
-	controller.subscriber = append(controller.subscriber, subscriber)
+func (controller *Download) Subscribe(subscriber chan event.Event[DownloadEvent]) {
+	controller.events.SubscribeWithReplay(subscriber)
 }
 
-func (controller *Download) Unsubscribe(subscriber chan struct{}) {
-	defer controller.mutex.Unlock()
-	controller.mutex.Lock()
-	index := slices.Index(controller.subscriber, subscriber)
-	_ = slices.Delete(controller.subscriber, index, index+1)
+func (controller *Download) Unsubscribe(subscriber chan event.Event[DownloadEvent]) {
+	controller.events.Unsubscribe(subscriber)
 }
 
-func (controller *Download) notifySubcriber() {
-	defer controller.mutex.RUnlock()
-	controller.mutex.RLock()
-	for _, subscriber := range controller.subscriber {
-		util.ChannelWriteNonBlocking(subscriber, struct{}{})
-	}
-}
-
-func (controller *Download) SubscribeProgress(subscriber chan struct{}) {
-	defer controller.mutex.Unlock()
-	controller.mutex.Lock()
-	controller.subscriberprogress = append(controller.subscriberprogress, subscriber)
-}
-
-func (controller *Download) UnsubscribeProgress(subscriber chan struct{}) {
-	defer controller.mutex.Unlock()
-	controller.mutex.Lock()
-	controller.subscriberprogress = append(controller.subscriberprogress, subscriber)
-}
-
-func (controller *Download) subscribeSubscriber(publisher util.Publisher[struct{}]) {
-	defer controller.mutex.RUnlock()
-	controller.mutex.RLock()
-	for _, subscriber := range controller.subscriberprogress {
-		publisher.Subscribe(subscriber)
-	}
-}
-
-func (controller *Download) unsubscribeSubscriber(publisher util.Publisher[struct{}]) {
-	defer controller.mutex.RUnlock()
-	controller.mutex.RLock()
-	for _, subscriber := range controller.subscriberprogress {
-		publisher.Unsubscribe(subscriber)
-	}
+func (controller *Download) notifySubcriber(topic event.Topic) {
+	controller.events.Publish(topic, DownloadEvent{
+		Download:       controller,
+		Progress:       controller.Progress(),
+		BytesPerSecond: controller.BytesPerSecond(),
+	})
 }
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"slices"
@@ -8,37 +9,46 @@ import (
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"github.com/seternate/go-lanty-client/pkg/event"
 	"github.com/seternate/go-lanty/pkg/game"
-	"github.com/seternate/go-lanty/pkg/util"
 )
 
 type DownloadController struct {
-	parent     *Controller
-	downloads  []*Download
-	subscriber []chan struct{}
-	mutex      sync.RWMutex
+	parent    *Controller
+	downloads []*Download
+	events    *event.EventBus[*Download]
+	routines  *routines
+	mutex     sync.RWMutex
 }
 
 func NewDownloadController(parent *Controller) (controller *DownloadController) {
 	controller = &DownloadController{
-		parent:     parent,
-		downloads:  make([]*Download, 0),
-		subscriber: make([]chan struct{}, 0, 50),
+		parent:    parent,
+		downloads: make([]*Download, 0),
+		events:    event.NewEventBus[*Download](),
+		routines:  newRoutines(parent, ComponentDownload),
 	}
-	parent.WaitGroup().Add(1)
-	go controller.run()
 	return
 }
 
+func (controller *DownloadController) Start(ctx context.Context) error {
+	return controller.routines.start(ctx, controller.run)
+}
+
+func (controller *DownloadController) Stop() {
+	controller.routines.stop()
+}
+
 func (controller *DownloadController) Download(game game.Game) {
 	if controller.isDownloading(game) {
 		log.Debug().Str("slug", game.Slug).Msg("game already downloading")
 		return
 	}
+	download := NewDownload(controller.parent, game)
 	controller.mutex.Lock()
-	controller.downloads = append(controller.downloads, NewDownload(controller.parent, game))
+	controller.downloads = append(controller.downloads, download)
 	controller.mutex.Unlock()
-	controller.notifySubcriber()
+	controller.events.Publish(TopicDownloadQueued, download)
 	log.Debug().Str("slug", game.Slug).Msg("added game to download queue")
 }
 
@@ -60,40 +70,27 @@ func (controller *DownloadController) GetLatest(game game.Game) (*Download, erro
 	return nil, errors.New("game is not being downloaded")
 }
 
-func (controller *DownloadController) GetLastQueued() *Download {
+func (controller *DownloadController) GetDownloads() []*Download {
 	defer controller.mutex.RUnlock()
 	controller.mutex.RLock()
-	return controller.downloads[len(controller.downloads)-1]
+	return slices.Clone(controller.downloads)
 }
 
-func (controller *DownloadController) Subscribe(subscriber chan struct{}) {
-	defer controller.mutex.Unlock()
-	controller.mutex.Lock()
-	controller.subscriber = append(controller.subscriber, subscriber)
-}
-
-func (controller *DownloadController) Unsubscribe(subscriber chan struct{}) {
-	defer controller.mutex.Unlock()
-	controller.mutex.Lock()
-	index := slices.Index(controller.subscriber, subscriber)
-	_ = slices.Delete(controller.subscriber, index, index+1)
+func (controller *DownloadController) Subscribe(subscriber chan event.Event[*Download]) {
+	controller.events.Subscribe(subscriber, TopicDownloadQueued)
 }
 
-func (controller *DownloadController) notifySubcriber() {
-	defer controller.mutex.RUnlock()
-	controller.mutex.RLock()
-	for _, subscriber := range controller.subscriber {
-		util.ChannelWriteNonBlocking(subscriber, struct{}{})
-	}
+func (controller *DownloadController) Unsubscribe(subscriber chan event.Event[*Download]) {
+	controller.events.Unsubscribe(subscriber)
 }
 
-func (controller *DownloadController) run() {
-	defer controller.parent.WaitGroup().Done()
+func (controller *DownloadController) run(ctx context.Context) {
 	ticker := time.NewTicker(150 * time.Millisecond)
+	defer ticker.Stop()
 	for {
 		select {
-		case <-controller.parent.Context().Done():
-			log.Trace().Err(controller.parent.Context().Err()).Msg("exiting downloadcontroller run()")
+		case <-ctx.Done():
+			log.Trace().Err(ctx.Err()).Msg("exiting downloadcontroller run()")
 			return
 		case <-ticker.C:
 			controller.startQueuedDownloads()
@@ -101,18 +98,28 @@ func (controller *DownloadController) run() {
 	}
 }
 
+// restartRunning restarts the running transfers, e.g. after the server was switched.
+func (controller *DownloadController) restartRunning() {
+	for _, download := range controller.GetDownloads() {
+		download.restart()
+	}
+}
+
 func (controller *DownloadController) startQueuedDownloads() {
 	controller.mutex.Lock()
 	downloads := controller.downloads
 	controller.mutex.Unlock()
 	for _, download := range downloads {
 		if !download.IsStarted() && !download.IsStopped() {
-			if download.Retries() > 10 {
+			if download.backoff.Exhausted() {
 				download.Stop()
 				controller.parent.Status.Error(fmt.Sprintf("Error starting download of game: %s", download.Game().Name), 8*time.Second)
 				continue
 			}
-			err := download.Start(controller.parent.Context(), controller.parent.WaitGroup())
+			if !download.backoff.Ready() {
+				continue
+			}
+			err := download.Start(controller.parent.Context())
 			if err != nil {
 				log.Error().Err(err).Str("slug", download.Game().Slug).Uint64("retries", download.Retries()).Msg("failed to start download of game")
 				continue
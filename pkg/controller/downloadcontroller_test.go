@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/seternate/go-lanty-client/pkg/lantytest"
+	"github.com/seternate/go-lanty/pkg/game"
+)
+
+func TestDownload(t *testing.T) {
+	server := lantytest.NewServer()
+	defer server.Close()
+	quake := game.Game{Slug: "quake", Name: "Quake"}
+	files := map[string][]byte{
+		"quake.exe":           []byte("executable"),
+		"baseq3/pak0.pk3":     []byte("maps"),
+		"baseq3/q3config.cfg": []byte("config"),
+	}
+	err := server.AddGame(quake, nil, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Fail(lantytest.RouteDownload, http.StatusServiceUnavailable, 1)
+	controller := newTestController(t, server)
+
+	controller.Download.Download(quake)
+	download, err := controller.Download.GetLatest(quake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, download.IsComplete, "download of %s", quake.Slug)
+	if download.Err() != nil {
+		t.Fatalf("got error %v, want none", download.Err())
+	}
+	if download.Retries() != 1 {
+		t.Errorf("got %d retries, want 1", download.Retries())
+	}
+
+	gamedirectory := controller.Settings.Settings().GameDirectory
+	for name, data := range files {
+		extracted, err := os.ReadFile(filepath.Join(gamedirectory, quake.Slug, name))
+		if err != nil {
+			t.Errorf("error reading extracted %s: %v", name, err)
+		} else if string(extracted) != string(data) {
+			t.Errorf("got %s with %q, want %q", name, extracted, data)
+		}
+	}
+	_, err = os.Stat(filepath.Join(gamedirectory, quake.Slug+".zip"))
+	if !os.IsNotExist(err) {
+		t.Errorf("got %v for the archive, want it removed", err)
+	}
+}
+
+func TestDownloadUncreatableFolder(t *testing.T) {
+	server := lantytest.NewServer()
+	defer server.Close()
+	quake := game.Game{Slug: "quake", Name: "Quake"}
+	err := server.AddGame(quake, nil, map[string][]byte{"quake.exe": []byte("executable")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	controller := newTestController(t, server)
+	// A file replaced the game folder after it was set.
+	err = os.WriteFile(controller.Settings.Settings().GameDirectory, nil, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	controller.Download.Download(quake)
+	download, err := controller.Download.GetLatest(quake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, download.IsStopped, "stopped download")
+	if download.Err() == nil {
+		t.Error("got no error for the uncreatable folder")
+	}
+	time.Sleep(3 * TEST_INTERVAL)
+	if download.IsStarted() || server.Requests(lantytest.RouteDownload) != 0 {
+		t.Error("download was started")
+	}
+}
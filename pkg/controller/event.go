@@ -0,0 +1,47 @@
+package controller
+
+import "github.com/seternate/go-lanty-client/pkg/event"
+
+const (
+	TopicConnectionStatus       event.Topic = "connection.status"
+	TopicConnectionQuality      event.Topic = "connection.quality"
+	TopicConnectionUntrusted    event.Topic = "connection.untrusted"
+	TopicConnectionUnauthorized event.Topic = "connection.unauthorized"
+
+	TopicSettingsServerURL         event.Topic = "settings.serverurl"
+	TopicSettingsBackupServerURLs  event.Topic = "settings.backupserverurls"
+	TopicSettingsGameDirectory     event.Topic = "settings.gamedirectory"
+	TopicSettingsUsername          event.Topic = "settings.username"
+	TopicSettingsDownloadDirectory event.Topic = "settings.downloaddirectory"
+	TopicSettingsProfile           event.Topic = "settings.profile"
+	TopicSettingsTLS               event.Topic = "settings.tls"
+	TopicSettingsPassword          event.Topic = "settings.password"
+
+	TopicStatus event.Topic = "status"
+
+	TopicGameAdded   event.Topic = "game.added"
+	TopicGameRemoved event.Topic = "game.removed"
+	TopicGameUpdated event.Topic = "game.updated"
+	TopicGameIcon    event.Topic = "game.icon"
+	TopicGameOffline event.Topic = "game.offline"
+
+	TopicGameStarted   event.Topic = "game.started"
+	TopicGameExited    event.Topic = "game.exited"
+	TopicServerStarted event.Topic = "server.started"
+
+	TopicUserJoined  event.Topic = "user.joined"
+	TopicUserLeft    event.Topic = "user.left"
+	TopicUserUpdated event.Topic = "user.updated"
+
+	TopicDownloadQueued   event.Topic = "download.queued"
+	TopicDownloadStatus   event.Topic = "download.status"
+	TopicDownloadProgress event.Topic = "download.progress"
+
+	TopicChatMessage event.Topic = "chat.message"
+)
+
+type DownloadEvent struct {
+	Download       *Download
+	Progress       float64
+	BytesPerSecond float64
+}
@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"image"
+	"image/png"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/seternate/go-lanty-client/pkg/setting"
+	"github.com/seternate/go-lanty/pkg/game"
+)
+
+const (
+	GAME_CACHE_DIRECTORY = "gamecache"
+	GAME_CACHE_FILE      = "games.json"
+	ICON_CACHE_DIRECTORY = "icons"
+)
+
+// gameCache stores the games and icons of the server of a profile in the cache folder, so installed
+// games can be played while the server is unreachable.
+type gameCache struct {
+	directory string
+}
+
+type cachedGames struct {
+	ServerURL string      `json:"serverurl"`
+	Updated   time.Time   `json:"updated"`
+	Games     []game.Game `json:"games"`
+}
+
+func newGameCache(profile string) (*gameCache, error) {
+	directories, err := setting.Dirs()
+	if err != nil {
+		return nil, err
+	}
+	return &gameCache{directory: filepath.Join(directories.Cache, GAME_CACHE_DIRECTORY, url.PathEscape(profile))}, nil
+}
+
+// load returns the cached games and their icons, an icon which can not be read is skipped. The
+// error is os.ErrNotExist if there is no cache yet.
+func (cache *gameCache) load() (games game.Games, icons map[string]image.Image, err error) {
+	data, err := os.ReadFile(filepath.Join(cache.directory, GAME_CACHE_FILE))
+	if err != nil {
+		return
+	}
+	var cached cachedGames
+	err = json.Unmarshal(data, &cached)
+	if err != nil {
+		return
+	}
+	icons = make(map[string]image.Image, len(cached.Games))
+	for _, g := range cached.Games {
+		err = games.Add(g)
+		if err != nil {
+			return
+		}
+		icon, err := cache.loadIcon(g.Slug)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				log.Warn().Err(err).Str("slug", g.Slug).Msg("error loading cached game icon")
+			}
+			continue
+		}
+		icons[g.Slug] = icon
+	}
+	return
+}
+
+// save replaces the cached games and writes the missing icons. Icons of games which were removed are
+// deleted.
+func (cache *gameCache) save(serverurl string, games game.Games, icons map[string]image.Image) error {
+	err := os.MkdirAll(filepath.Join(cache.directory, ICON_CACHE_DIRECTORY), 0755)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cachedGames{ServerURL: serverurl, Updated: time.Now(), Games: games.Games()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = writeCacheFile(filepath.Join(cache.directory, GAME_CACHE_FILE), data)
+	if err != nil {
+		return err
+	}
+
+	keep := make(map[string]bool, len(icons))
+	for slug, icon := range icons {
+		if icon == nil {
+			continue
+		}
+		keep[cache.iconPath(slug)] = true
+		if _, err := os.Stat(cache.iconPath(slug)); err == nil {
+			continue
+		}
+		err = cache.saveIcon(slug, icon)
+		if err != nil {
+			log.Warn().Err(err).Str("slug", slug).Msg("error caching game icon")
+		}
+	}
+	paths, err := filepath.Glob(filepath.Join(cache.directory, ICON_CACHE_DIRECTORY, "*.png"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if !keep[path] {
+			os.Remove(path)
+		}
+	}
+	return nil
+}
+
+func (cache *gameCache) loadIcon(slug string) (image.Image, error) {
+	file, err := os.Open(cache.iconPath(slug))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return png.Decode(file)
+}
+
+func (cache *gameCache) saveIcon(slug string, icon image.Image) error {
+	file, err := os.CreateTemp(filepath.Dir(cache.iconPath(slug)), slug+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	err = png.Encode(file, icon)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), cache.iconPath(slug))
+}
+
+func (cache *gameCache) iconPath(slug string) string {
+	return filepath.Join(cache.directory, ICON_CACHE_DIRECTORY, url.PathEscape(slug)+".png")
+}
+
+// writeCacheFile replaces the file atomically, so a crash does not leave a partial cache.
+func writeCacheFile(path string, data []byte) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
@@ -1,42 +1,61 @@
 package controller
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"image"
+	"os"
 	"os/exec"
 	"path/filepath"
-	"slices"
 	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"github.com/seternate/go-lanty-client/pkg/event"
+	"github.com/seternate/go-lanty-client/pkg/setting"
+	"github.com/seternate/go-lanty-client/pkg/supervisor"
 	"github.com/seternate/go-lanty/pkg/filesystem"
 	"github.com/seternate/go-lanty/pkg/game"
 	"github.com/seternate/go-lanty/pkg/user"
-	"github.com/seternate/go-lanty/pkg/util"
 )
 
 type GameController struct {
-	parent     *Controller
-	games      game.Games
-	gameIcons  map[string]image.Image
-	subscriber []chan struct{}
-	ticker     *time.Ticker
-	mutex      sync.RWMutex
-	err        error
+	parent    *Controller
+	games     game.Games
+	gameIcons map[string]image.Image
+	events    *event.EventBus[game.Game]
+	routines  *routines
+	ticker    *time.Ticker
+	mutex     sync.RWMutex
+	err       error
+	// offline is set while the games are the cached ones, because the server is unreachable.
+	offline bool
+	// cacheProfile is the profile the games were loaded from the cache for, the cache of another
+	// profile replaces them.
+	cacheProfile string
 }
 
 func NewGameController(parent *Controller, refreshinterval time.Duration) (controller *GameController) {
 	controller = &GameController{
-		parent:     parent,
-		ticker:     time.NewTicker(refreshinterval),
-		gameIcons:  make(map[string]image.Image, 50),
-		subscriber: make([]chan struct{}, 0, 50),
+		parent:    parent,
+		ticker:    time.NewTicker(refreshinterval),
+		gameIcons: make(map[string]image.Image, 50),
+		events:    event.NewEventBus[game.Game](),
+		routines:  newRoutines(parent, ComponentGame),
 	}
-	parent.WaitGroup().Add(1)
-	go controller.run()
 	return
 }
 
+func (controller *GameController) Start(ctx context.Context) error {
+	controller.loadCache()
+	return controller.routines.start(ctx, controller.run)
+}
+
+func (controller *GameController) Stop() {
+	controller.routines.stop()
+}
+
 func (controller *GameController) GetGames() (games game.Games) {
 	defer controller.mutex.RUnlock()
 	controller.mutex.RLock()
@@ -55,12 +74,29 @@ func (controller *GameController) Err() error {
 	return controller.err
 }
 
+// IsOffline returns true if the games are loaded from the cache, because the server is unreachable.
+func (controller *GameController) IsOffline() bool {
+	defer controller.mutex.RUnlock()
+	controller.mutex.RLock()
+	return controller.offline
+}
+
+func (controller *GameController) Refresh() error {
+	controller.update()
+	return controller.Err()
+}
+
 func (controller *GameController) runExecutable(executable string, args []string) (cmd *exec.Cmd, err error) {
-	paths, err := filesystem.SearchFilesBreadthFirst(controller.parent.settings.GameDirectory, executable, 3, -1)
+	paths, err := filesystem.SearchFilesBreadthFirst(controller.parent.currentSettings().GameDirectory, executable, 3, -1)
 	if err != nil {
 		log.Error().Err(err).Msg("error finding game executable path")
 		return
 	}
+	if len(paths) == 0 {
+		err = fmt.Errorf("executable %s not found in gamedirectory", executable)
+		log.Error().Err(err).Msg("error finding game executable path")
+		return
+	}
 	if len(paths) > 1 {
 		log.Warn().Strs("paths", paths).Str("used-path", paths[0]).Msg("found multiple game executables to run")
 	}
@@ -72,11 +108,11 @@ func (controller *GameController) runExecutable(executable string, args []string
 	}
 	cmd = exec.Command(paths[0], args...)
 	cmd.Dir = workingDir
-	cmd.Start()
+	err = cmd.Start()
 	return
 }
 
-func (controller *GameController) StartGame(game game.Game) {
+func (controller *GameController) StartGame(game game.Game) (err error) {
 	args, err := game.Client.Args()
 	if err != nil {
 		log.Error().Err(err).Msg("error parsing game arguments")
@@ -88,10 +124,17 @@ func (controller *GameController) StartGame(game game.Game) {
 		return
 	}
 	log.Debug().Str("slug", game.Slug).Str("cmd", cmd.String()).Msg("started game")
+	controller.watchGame(game, cmd)
+	return
+}
+
+func (controller *GameController) IsInstalled(game game.Game) bool {
+	paths, err := filesystem.SearchFilesBreadthFirst(controller.parent.currentSettings().GameDirectory, game.Client.Executable, 3, 1)
+	return err == nil && len(paths) > 0
 }
 
 func (controller *GameController) OpenGameInExplorer(game game.Game) {
-	paths, err := filesystem.SearchFilesBreadthFirst(controller.parent.settings.GameDirectory, game.Client.Executable, 3, -1)
+	paths, err := filesystem.SearchFilesBreadthFirst(controller.parent.currentSettings().GameDirectory, game.Client.Executable, 3, -1)
 	if err != nil {
 		log.Error().Err(err).Msg("error finding game executable path")
 		return
@@ -103,7 +146,7 @@ func (controller *GameController) OpenGameInExplorer(game game.Game) {
 	}
 }
 
-func (controller *GameController) JoinServer(game game.Game, user user.User) {
+func (controller *GameController) JoinServer(game game.Game, user user.User) (err error) {
 	connectArg, err := game.Client.ParseConnectArg(user.IP)
 	if err != nil {
 		log.Error().Err(err).Msg("error parsing games connect argument")
@@ -121,9 +164,11 @@ func (controller *GameController) JoinServer(game game.Game, user user.User) {
 		return
 	}
 	log.Debug().Str("slug", game.Slug).Str("cmd", cmd.String()).Msg("joining game")
+	controller.watchGame(game, cmd)
+	return
 }
 
-func (controller *GameController) StartServer(game game.Game) {
+func (controller *GameController) StartServer(game game.Game) (err error) {
 	args, err := game.Server.Args()
 	if err != nil {
 		log.Error().Err(err).Msg("error parsing game arguments")
@@ -135,37 +180,44 @@ func (controller *GameController) StartServer(game game.Game) {
 		return
 	}
 	log.Debug().Str("slug", game.Slug).Str("cmd", cmd.String()).Msg("started game server")
+	controller.events.Publish(TopicServerStarted, game)
+	return
 }
 
-func (controller *GameController) Subscribe(subscriber chan struct{}) {
-	defer controller.mutex.Unlock()
-	controller.mutex.Lock()
-	controller.subscriber = append(controller.subscriber, subscriber)
+// watchGame publishes the start of the game and its exit once the process ended.
+func (controller *GameController) watchGame(game game.Game, cmd *exec.Cmd) {
+	controller.events.Publish(TopicGameStarted, game)
+	controller.parent.Go("GameController.watchGame", func() {
+		err := cmd.Wait()
+		if err != nil {
+			log.Debug().Err(err).Str("slug", game.Slug).Msg("game exited with error")
+		}
+		controller.events.Publish(TopicGameExited, game)
+	}, supervisor.WithField("slug", game.Slug), supervisor.WithoutRestart())
 }
 
-func (controller *GameController) Unsubscribe(subscriber chan struct{}) {
-	defer controller.mutex.Unlock()
-	controller.mutex.Lock()
-	index := slices.Index(controller.subscriber, subscriber)
-	_ = slices.Delete(controller.subscriber, index, index+1)
+func (controller *GameController) Subscribe(subscriber chan event.Event[game.Game], topics ...event.Topic) {
+	controller.events.Subscribe(subscriber, topics...)
 }
 
-func (controller *GameController) notifySubcriber() {
-	defer controller.mutex.RUnlock()
-	controller.mutex.RLock()
-	for _, subscriber := range controller.subscriber {
-		util.ChannelWriteNonBlocking(subscriber, struct{}{})
-	}
+func (controller *GameController) Unsubscribe(subscriber chan event.Event[game.Game]) {
+	controller.events.Unsubscribe(subscriber)
 }
 
-func (controller *GameController) run() {
-	defer controller.parent.WaitGroup().Done()
+func (controller *GameController) run(ctx context.Context) {
+	profilechanged := make(chan event.Event[setting.Settings], 10)
+	if controller.parent.Settings != nil {
+		controller.parent.Settings.Subscribe(profilechanged, TopicSettingsProfile)
+		defer controller.parent.Settings.Unsubscribe(profilechanged)
+	}
 	controller.update()
 	for {
 		select {
-		case <-controller.parent.Context().Done():
-			log.Trace().Err(controller.parent.Context().Err()).Msg("exiting gamecontroller run()")
+		case <-ctx.Done():
+			log.Trace().Err(ctx.Err()).Msg("exiting gamecontroller run()")
 			return
+		case <-profilechanged:
+			controller.loadCache()
 		case <-controller.ticker.C:
 			controller.update()
 		}
@@ -173,24 +225,126 @@ func (controller *GameController) run() {
 }
 
 func (controller *GameController) update() {
-	refreshed, err := controller.updateGames()
-	if err != nil || !refreshed {
+	start := time.Now()
+	defer func() {
+		controller.parent.metrics.pollDuration.Observe(time.Since(start).Seconds(), pollerGame)
+	}()
+	changes, err := controller.updateGames()
+	controller.parent.reportConnection(SubsystemAPI, err)
+	if err != nil {
+		controller.parent.metrics.pollErrors.Inc(pollerGame)
 		controller.mutex.Lock()
 		controller.err = err
 		controller.mutex.Unlock()
+		controller.setOffline(true)
 		return
 	}
-	_, err = controller.updateIcons()
+	icons, err := controller.updateIcons()
+	controller.mutex.Lock()
+	controller.err = err
+	controller.mutex.Unlock()
+	controller.setOffline(false)
+	for _, change := range changes {
+		controller.events.Publish(change.Topic, change.Data)
+	}
+	for _, game := range icons {
+		controller.events.Publish(TopicGameIcon, game)
+	}
+	if len(changes) > 0 || len(icons) > 0 {
+		controller.saveCache()
+	}
+}
+
+func (controller *GameController) setOffline(offline bool) {
+	controller.mutex.Lock()
+	changed := controller.offline != offline
+	controller.offline = offline
+	controller.mutex.Unlock()
+	if changed {
+		log.Info().Bool("offline", offline).Msg("game list offline state changed")
+		controller.events.Publish(TopicGameOffline, game.Game{})
+	}
+}
+
+// loadCache shows the cached games of the active profile until the server answers, so installed
+// games can be started without the server. The games of another profile are replaced, the server
+// of the profile may be unreachable.
+func (controller *GameController) loadCache() {
+	profile := controller.profile()
+	controller.mutex.RLock()
+	loaded := controller.cacheProfile == profile
+	controller.mutex.RUnlock()
+	if loaded {
+		return
+	}
+	cache, err := newGameCache(profile)
 	if err != nil {
-		controller.mutex.Lock()
-		controller.err = err
-		controller.mutex.Unlock()
+		log.Warn().Err(err).Msg("error opening game cache")
+		return
+	}
+	games, icons, err := cache.load()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Warn().Err(err).Str("profile", profile).Msg("error loading game cache")
+	}
+	if err != nil {
+		games, icons = game.Games{}, make(map[string]image.Image)
+	}
+	controller.mutex.Lock()
+	changes := diffGames(controller.games, games)
+	controller.games = games
+	controller.gameIcons = icons
+	controller.cacheProfile = profile
+	controller.offline = true
+	controller.mutex.Unlock()
+	log.Info().Str("profile", profile).Int("games", len(games.Games())).Msg("loaded games from cache")
+	for _, change := range changes {
+		controller.events.Publish(change.Topic, change.Data)
+	}
+	for _, g := range games.Games() {
+		if _, found := icons[g.Slug]; found {
+			controller.events.Publish(TopicGameIcon, g)
+		}
 	}
-	controller.notifySubcriber()
+	controller.events.Publish(TopicGameOffline, game.Game{})
 }
 
-func (controller *GameController) updateGames() (refreshed bool, err error) {
-	refreshed = false
+// saveCache saves the games to the cache of the active profile. They are not saved while the
+// cache of a switched profile is not loaded yet, as they may be the games of the previous profile.
+func (controller *GameController) saveCache() {
+	profile := controller.profile()
+	controller.mutex.RLock()
+	games := controller.games
+	icons := make(map[string]image.Image, len(controller.gameIcons))
+	for slug, icon := range controller.gameIcons {
+		icons[slug] = icon
+	}
+	loaded := controller.cacheProfile == profile
+	controller.mutex.RUnlock()
+	if !loaded {
+		return
+	}
+	cache, err := newGameCache(profile)
+	if err != nil {
+		log.Warn().Err(err).Msg("error opening game cache")
+		return
+	}
+	serverurl := ""
+	if controller.parent.Connection != nil {
+		serverurl = controller.parent.Connection.ServerURL()
+	}
+	err = cache.save(serverurl, games, icons)
+	if err != nil {
+		log.Warn().Err(err).Msg("error saving game cache")
+	}
+}
+
+// profile returns the name of the active profile, every profile has its own cache as the profiles
+// usually are different servers.
+func (controller *GameController) profile() string {
+	return controller.parent.currentSettings().ActiveProfile
+}
+
+func (controller *GameController) updateGames() (changes []event.Event[game.Game], err error) {
 	games, err := controller.getServerGames()
 	if err != nil {
 		return
@@ -201,7 +355,7 @@ func (controller *GameController) updateGames() (refreshed bool, err error) {
 	if localGames.Equal(games) {
 		return
 	}
-	refreshed = true
+	changes = diffGames(localGames, games)
 	controller.mutex.Lock()
 	controller.games = games
 	controller.mutex.Unlock()
@@ -209,28 +363,58 @@ func (controller *GameController) updateGames() (refreshed bool, err error) {
 	return
 }
 
-func (controller *GameController) updateIcons() (refreshed bool, err error) {
-	defer controller.mutex.Unlock()
-	controller.mutex.Lock()
-	refreshed = false
+// diffGames returns the events changing the local games to the games.
+func diffGames(localGames game.Games, games game.Games) (changes []event.Event[game.Game]) {
+	for _, g := range games.Games() {
+		localGame, err := localGames.Get(g.Slug)
+		if err != nil {
+			changes = append(changes, event.Event[game.Game]{Topic: TopicGameAdded, Data: g})
+		} else if !localGame.Equal(g) {
+			changes = append(changes, event.Event[game.Game]{Topic: TopicGameUpdated, Data: g})
+		}
+	}
+	for _, g := range localGames.Games() {
+		_, err := games.Get(g.Slug)
+		if err != nil {
+			changes = append(changes, event.Event[game.Game]{Topic: TopicGameRemoved, Data: g})
+		}
+	}
+	return
+}
+
+// updateIcons requests the missing icons without holding the lock, so the games can be read while
+// the server is slow.
+func (controller *GameController) updateIcons() (updated []game.Game, err error) {
+	controller.mutex.RLock()
+	missing := make([]game.Game, 0)
 	for _, game := range controller.games.Games() {
-		_, hasIcon := controller.gameIcons[game.Slug]
-		if hasIcon {
-			continue
+		if _, hasIcon := controller.gameIcons[game.Slug]; !hasIcon {
+			missing = append(missing, game)
 		}
-		image, err := controller.parent.client.Game.GetIcon(game)
+	}
+	controller.mutex.RUnlock()
+
+	icons := make(map[string]image.Image, len(missing))
+	for _, game := range missing {
+		var icon image.Image
+		icon, err = controller.parent.gameService.GetIcon(game)
 		if err != nil {
 			log.Error().Err(err).Str("slug", game.Slug).Msg("error retrieving game icon from server")
-			return refreshed, err
+			break
 		}
-		refreshed = true
-		controller.gameIcons[game.Slug] = image
+		updated = append(updated, game)
+		icons[game.Slug] = icon
 		log.Debug().Str("slug", game.Slug).Msg("game icon updated")
 	}
+
+	defer controller.mutex.Unlock()
+	controller.mutex.Lock()
+	for slug, icon := range icons {
+		controller.gameIcons[slug] = icon
+	}
 	for slug := range controller.gameIcons {
-		_, err = controller.games.Get(slug)
-		if err != nil {
-			err = nil
+		_, getErr := controller.games.Get(slug)
+		if getErr != nil {
 			delete(controller.gameIcons, slug)
 		}
 	}
@@ -238,13 +422,13 @@ func (controller *GameController) updateIcons() (refreshed bool, err error) {
 }
 
 func (controller *GameController) getServerGames() (games game.Games, err error) {
-	slugs, err := controller.parent.client.Game.GetGames()
+	slugs, err := controller.parent.gameService.GetGames()
 	if err != nil {
 		log.Error().Err(err).Msg("error retrieving gameslist from server")
 		return
 	}
 	for _, slug := range slugs {
-		game, err := controller.parent.client.Game.GetGame(slug)
+		game, err := controller.parent.gameService.GetGame(slug)
 		if err != nil {
 			log.Error().Err(err).Str("slug", slug).Msg("error retrieving game from server")
 			return games, err
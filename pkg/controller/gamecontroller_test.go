@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"image"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/seternate/go-lanty-client/pkg/event"
+	"github.com/seternate/go-lanty-client/pkg/lantytest"
+	"github.com/seternate/go-lanty/pkg/game"
+)
+
+func TestGamePolling(t *testing.T) {
+	server := lantytest.NewServer()
+	defer server.Close()
+	controller := newTestController(t, server)
+	events := make(chan event.Event[game.Game], 50)
+	controller.Game.Subscribe(events, TopicGameAdded, TopicGameUpdated, TopicGameRemoved)
+	defer controller.Game.Unsubscribe(events)
+
+	quake := game.Game{Slug: "quake", Name: "Quake"}
+	err := server.AddGame(quake, image.NewRGBA(image.Rect(0, 0, 8, 8)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectGameEvent(t, events, TopicGameAdded, quake.Slug)
+	eventually(t, func() bool { return controller.Game.GetIcon(quake) != nil }, "icon of %s", quake.Slug)
+
+	quake.Name = "Quake III"
+	err = server.AddGame(quake, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectGameEvent(t, events, TopicGameUpdated, quake.Slug)
+	polled, err := controller.Game.GetGames().Get(quake.Slug)
+	if err != nil || polled.Name != quake.Name {
+		t.Fatalf("got game %+v, %v, want name %s", polled, err, quake.Name)
+	}
+
+	server.RemoveGame(quake.Slug)
+	expectGameEvent(t, events, TopicGameRemoved, quake.Slug)
+	if len(controller.Game.GetGames().Games()) != 0 {
+		t.Fatalf("got games %+v, want none", controller.Game.GetGames().Games())
+	}
+}
+
+func TestGamePollingOffline(t *testing.T) {
+	server := lantytest.NewServer()
+	defer server.Close()
+	quake := game.Game{Slug: "quake", Name: "Quake"}
+	err := server.AddGame(quake, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	controller := newTestController(t, server)
+	eventually(t, func() bool { return len(controller.Game.GetGames().Games()) == 1 && !controller.Game.IsOffline() }, "games of the server")
+
+	server.Fail(lantytest.RouteGames, http.StatusInternalServerError, -1)
+	eventually(t, controller.Game.IsOffline, "offline game list")
+	if controller.Game.Err() == nil {
+		t.Error("got no error while the server fails")
+	}
+	if len(controller.Game.GetGames().Games()) != 1 {
+		t.Errorf("got games %+v, want the cached game", controller.Game.GetGames().Games())
+	}
+
+	server.ClearFaults()
+	eventually(t, func() bool { return !controller.Game.IsOffline() }, "online game list")
+}
+
+// expectGameEvent waits for the event of the game, other events are skipped.
+func expectGameEvent(t *testing.T, events chan event.Event[game.Game], topic event.Topic, slug string) {
+	t.Helper()
+	timeout := time.After(TEST_TIMEOUT)
+	for {
+		select {
+		case event := <-events:
+			if event.Topic == topic && event.Data.Slug == slug {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %s of %s", topic, slug)
+		}
+	}
+}
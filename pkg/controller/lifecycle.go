@@ -0,0 +1,353 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/seternate/go-lanty-client/pkg/retry"
+	"github.com/seternate/go-lanty-client/pkg/supervisor"
+)
+
+const (
+	ComponentSettings   = "settings"
+	ComponentStatus     = "status"
+	ComponentConnection = "connection"
+	ComponentGame       = "game"
+	ComponentDownload   = "download"
+	ComponentUser       = "user"
+	ComponentChat       = "chat"
+)
+
+type ComponentState int
+
+const (
+	ComponentStopped ComponentState = iota
+	ComponentRunning
+	ComponentFailed
+)
+
+func (state ComponentState) String() string {
+	switch state {
+	case ComponentRunning:
+		return "running"
+	case ComponentFailed:
+		return "failed"
+	}
+	return "stopped"
+}
+
+type Component interface {
+	Start(ctx context.Context) error
+	Stop()
+}
+
+type ComponentHealth struct {
+	Name     string
+	State    ComponentState
+	Restarts int
+	Err      error
+	Since    time.Time
+}
+
+type component struct {
+	name         string
+	component    Component
+	dependencies []string
+	state        ComponentState
+	restarts     int
+	err          error
+	since        time.Time
+}
+
+// Lifecycle starts the registered components after their dependencies, stops them in reverse order
+// and restarts components with their dependents. The goroutines of the components are supervised by
+// the supervisor of the controller, which restarts a component through the lifecycle after a panic.
+type Lifecycle struct {
+	components map[string]*component
+	order      []string
+	ctx        context.Context
+	mutex      sync.Mutex
+}
+
+func NewLifecycle() *Lifecycle {
+	return &Lifecycle{
+		components: make(map[string]*component),
+		order:      make([]string, 0),
+	}
+}
+
+func (lifecycle *Lifecycle) Register(name string, c Component, dependencies ...string) {
+	defer lifecycle.mutex.Unlock()
+	lifecycle.mutex.Lock()
+	lifecycle.components[name] = &component{
+		name:         name,
+		component:    c,
+		dependencies: dependencies,
+		since:        time.Now(),
+	}
+}
+
+func (lifecycle *Lifecycle) Start(ctx context.Context) error {
+	lifecycle.mutex.Lock()
+	order, err := lifecycle.sort()
+	if err != nil {
+		lifecycle.mutex.Unlock()
+		return err
+	}
+	lifecycle.ctx = ctx
+	lifecycle.order = order
+	lifecycle.mutex.Unlock()
+	return lifecycle.start(order)
+}
+
+func (lifecycle *Lifecycle) Stop() {
+	lifecycle.mutex.Lock()
+	order := slices.Clone(lifecycle.order)
+	lifecycle.mutex.Unlock()
+	slices.Reverse(order)
+	lifecycle.stop(order)
+}
+
+// Restart restarts the components and all components depending on them.
+func (lifecycle *Lifecycle) Restart(names ...string) error {
+	lifecycle.mutex.Lock()
+	if lifecycle.ctx == nil {
+		lifecycle.mutex.Unlock()
+		return errors.New("lifecycle not started")
+	}
+	affected := make([]string, 0)
+	for _, name := range lifecycle.order {
+		if lifecycle.dependsOn(name, names) {
+			affected = append(affected, name)
+		}
+	}
+	lifecycle.mutex.Unlock()
+
+	stopOrder := slices.Clone(affected)
+	slices.Reverse(stopOrder)
+	lifecycle.stop(stopOrder)
+	log.Debug().Strs("components", affected).Msg("restarting components")
+	return lifecycle.start(affected)
+}
+
+func (lifecycle *Lifecycle) Health() []ComponentHealth {
+	defer lifecycle.mutex.Unlock()
+	lifecycle.mutex.Lock()
+	health := make([]ComponentHealth, 0, len(lifecycle.components))
+	names := lifecycle.order
+	if len(names) == 0 {
+		for name := range lifecycle.components {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+	}
+	for _, name := range names {
+		c := lifecycle.components[name]
+		health = append(health, ComponentHealth{
+			Name:     c.name,
+			State:    c.state,
+			Restarts: c.restarts,
+			Err:      c.err,
+			Since:    c.since,
+		})
+	}
+	return health
+}
+
+// Failed marks the component as failed, until it is started again.
+func (lifecycle *Lifecycle) Failed(name string, err error) {
+	lifecycle.mutex.Lock()
+	c, found := lifecycle.components[name]
+	if !found {
+		lifecycle.mutex.Unlock()
+		return
+	}
+	lifecycle.setState(c, ComponentFailed, err)
+	c.restarts++
+	restarts := c.restarts
+	lifecycle.mutex.Unlock()
+	log.Error().Err(err).Str("component", name).Int("restarts", restarts).Msg("component failed")
+}
+
+func (lifecycle *Lifecycle) start(names []string) error {
+	for _, name := range names {
+		lifecycle.mutex.Lock()
+		c := lifecycle.components[name]
+		ctx := lifecycle.ctx
+		lifecycle.mutex.Unlock()
+		err := c.component.Start(ctx)
+		lifecycle.mutex.Lock()
+		if err != nil {
+			lifecycle.setState(c, ComponentFailed, err)
+		} else {
+			lifecycle.setState(c, ComponentRunning, nil)
+		}
+		lifecycle.mutex.Unlock()
+		if err != nil {
+			return fmt.Errorf("error starting %s: %w", name, err)
+		}
+		log.Debug().Str("component", name).Msg("started component")
+	}
+	return nil
+}
+
+func (lifecycle *Lifecycle) stop(names []string) {
+	for _, name := range names {
+		lifecycle.mutex.Lock()
+		c := lifecycle.components[name]
+		lifecycle.mutex.Unlock()
+		c.component.Stop()
+		lifecycle.mutex.Lock()
+		if c.state != ComponentFailed {
+			lifecycle.setState(c, ComponentStopped, nil)
+		}
+		lifecycle.mutex.Unlock()
+		log.Debug().Str("component", name).Msg("stopped component")
+	}
+}
+
+func (lifecycle *Lifecycle) setState(c *component, state ComponentState, err error) {
+	if c.state != state {
+		c.since = time.Now()
+	}
+	c.state = state
+	c.err = err
+}
+
+func (lifecycle *Lifecycle) dependsOn(name string, names []string) bool {
+	if slices.Contains(names, name) {
+		return true
+	}
+	for _, dependency := range lifecycle.components[name].dependencies {
+		if lifecycle.dependsOn(dependency, names) {
+			return true
+		}
+	}
+	return false
+}
+
+func (lifecycle *Lifecycle) sort() ([]string, error) {
+	names := make([]string, 0, len(lifecycle.components))
+	for name := range lifecycle.components {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	order := make([]string, 0, len(names))
+	visiting := make(map[string]bool)
+	var visit func(name string) error
+	visit = func(name string) error {
+		if slices.Contains(order, name) {
+			return nil
+		}
+		if visiting[name] {
+			return fmt.Errorf("dependency cycle at component %s", name)
+		}
+		visiting[name] = true
+		for _, dependency := range lifecycle.components[name].dependencies {
+			if _, found := lifecycle.components[dependency]; !found {
+				return fmt.Errorf("component %s depends on missing component %s", name, dependency)
+			}
+			err := visit(dependency)
+			if err != nil {
+				return err
+			}
+		}
+		visiting[name] = false
+		order = append(order, name)
+		return nil
+	}
+	for _, name := range names {
+		err := visit(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// routines runs the goroutines of a component with their own context, so the component can be
+// stopped and started again. A panic stops the component and the supervisor restarts it through the
+// lifecycle, the restarts of all its goroutines share one backoff.
+type routines struct {
+	parent  *Controller
+	name    string
+	running bool
+	// panicked is set by the first panic of a run, so the component is restarted only once.
+	panicked bool
+	cancel   context.CancelFunc
+	backoff  *retry.Backoff
+	waitgrp  sync.WaitGroup
+	mutex    sync.Mutex
+}
+
+func newRoutines(parent *Controller, name string) *routines {
+	return &routines{
+		parent:  parent,
+		name:    name,
+		backoff: retry.NewBackoff(supervisor.DefaultPolicy()),
+	}
+}
+
+func (routines *routines) start(ctx context.Context, functions ...func(ctx context.Context)) error {
+	defer routines.mutex.Unlock()
+	routines.mutex.Lock()
+	if routines.running {
+		return fmt.Errorf("%s already running", routines.name)
+	}
+	routinesCtx, cancel := context.WithCancel(ctx)
+	routines.cancel = cancel
+	routines.running = true
+	routines.panicked = false
+	for _, function := range functions {
+		function := function
+		routines.waitgrp.Add(1)
+		routines.parent.supervisor.Go(ctx, routines.name, func() {
+			defer routines.waitgrp.Done()
+			function(routinesCtx)
+		}, supervisor.WithField("component", routines.name), supervisor.WithBackoff(routines.backoff),
+			supervisor.WithRecovered(routines.failed), supervisor.WithRestart(routines.restart))
+	}
+	return nil
+}
+
+// failed stops the other goroutines of the component after a panic.
+func (routines *routines) failed(value any) {
+	routines.mutex.Lock()
+	routines.cancel()
+	routines.panicked = true
+	routines.mutex.Unlock()
+	routines.parent.lifecycle.Failed(routines.name, fmt.Errorf("panic: %v", value))
+}
+
+// restart restarts the component after the first panic of the run, unless it was stopped meanwhile.
+func (routines *routines) restart() {
+	routines.mutex.Lock()
+	restart := routines.running && routines.panicked
+	routines.panicked = false
+	routines.mutex.Unlock()
+	if !restart {
+		return
+	}
+	err := routines.parent.lifecycle.Restart(routines.name)
+	if err != nil {
+		log.Error().Err(err).Str("component", routines.name).Msg("error restarting component")
+	}
+}
+
+func (routines *routines) stop() {
+	routines.mutex.Lock()
+	if !routines.running {
+		routines.mutex.Unlock()
+		return
+	}
+	routines.cancel()
+	routines.running = false
+	routines.mutex.Unlock()
+	routines.waitgrp.Wait()
+}
@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"github.com/seternate/go-lanty-client/pkg/metrics"
+)
+
+const (
+	pollerGame = "game"
+	pollerUser = "user"
+)
+
+type clientMetrics struct {
+	registry               *metrics.Registry
+	downloadBytes          *metrics.Counter
+	downloadBytesPerSecond *metrics.Gauge
+	downloadRetries        *metrics.Counter
+	downloads              *metrics.Counter
+	connectionConnected    *metrics.Gauge
+	connectionStateChanges *metrics.Counter
+	connectionRTT          *metrics.Gauge
+	connectionLoss         *metrics.Gauge
+	pollDuration           *metrics.Histogram
+	pollErrors             *metrics.Counter
+	chatReconnects         *metrics.Counter
+	statusMessages         *metrics.Counter
+	panics                 *metrics.Counter
+}
+
+func newClientMetrics() *clientMetrics {
+	registry := metrics.NewRegistry()
+	return &clientMetrics{
+		registry:               registry,
+		downloadBytes:          registry.NewCounter("lanty_download_bytes_total", "Bytes downloaded from the server.", "game"),
+		downloadBytesPerSecond: registry.NewGauge("lanty_download_bytes_per_second", "Current download throughput.", "game"),
+		downloadRetries:        registry.NewCounter("lanty_download_retries_total", "Failed attempts to start a download.", "game"),
+		downloads:              registry.NewCounter("lanty_downloads_total", "Finished downloads by result.", "result"),
+		connectionConnected:    registry.NewGauge("lanty_connection_connected", "1 if the server is reachable, 0 otherwise."),
+		connectionStateChanges: registry.NewCounter("lanty_connection_state_changes_total", "Connection state changes by new state.", "state"),
+		connectionRTT:          registry.NewGauge("lanty_connection_rtt_seconds", "Average round-trip time of the recent health checks."),
+		connectionLoss:         registry.NewGauge("lanty_connection_loss_ratio", "Ratio of failed recent health checks."),
+		pollDuration:           registry.NewHistogram("lanty_poll_duration_seconds", "Duration of polling the server.", metrics.DefaultBuckets, "poller"),
+		pollErrors:             registry.NewCounter("lanty_poll_errors_total", "Failed polls of the server.", "poller"),
+		chatReconnects:         registry.NewCounter("lanty_chat_reconnects_total", "Chat reconnect attempts by result.", "result"),
+		statusMessages:         registry.NewCounter("lanty_status_messages_total", "Status messages shown to the user by level.", "level"),
+		panics:                 registry.NewCounter("lanty_panics_total", "Recovered panics by routine.", "routine"),
+	}
+}
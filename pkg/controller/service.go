@@ -0,0 +1,209 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/seternate/go-lanty-client/pkg/discovery"
+	"github.com/seternate/go-lanty-client/pkg/gateway"
+	"github.com/seternate/go-lanty-client/pkg/retry"
+	"github.com/seternate/go-lanty-client/pkg/setting"
+	"github.com/seternate/go-lanty/pkg/api"
+	"github.com/seternate/go-lanty/pkg/chat"
+	"github.com/seternate/go-lanty/pkg/game"
+	"github.com/seternate/go-lanty/pkg/network"
+	"github.com/seternate/go-lanty/pkg/user"
+)
+
+type GameService interface {
+	GetGames() ([]string, error)
+	GetGame(slug string) (game.Game, error)
+	GetIcon(game game.Game) (image.Image, error)
+	Download(ctx context.Context, game game.Game, destination string) (*network.Download, error)
+}
+
+type UserService interface {
+	GetUsers() ([]string, error)
+	GetUser(ip string) (user.User, error)
+	CreateNewUser(user user.User) (user.User, error)
+	UpdateUser(user user.User) (user.User, error)
+}
+
+type ChatService interface {
+	Connect() error
+	Disconnect() error
+	Reconnect() error
+	SendMessage(message chat.Message) error
+	Messages() <-chan chat.Message
+	Err() error
+}
+
+type FileService interface {
+	GetFile(ctx context.Context, url url.URL, destination string) (*network.Download, error)
+	// UploadFile uploads the file and returns the chat message of the user announcing it.
+	UploadFile(path string, user user.User) (chat.Message, error)
+}
+
+type HealthService interface {
+	Health() error
+}
+
+type EndpointService interface {
+	SetBaseURL(url string) error
+}
+
+// ServerProbe checks the health of a server, which is not the one the API client uses.
+type ServerProbe interface {
+	Probe(ctx context.Context, serverurl string) error
+}
+
+type DiscoveryService interface {
+	Discover(ctx context.Context) ([]discovery.Server, error)
+}
+
+type Option func(controller *Controller)
+
+// WithSettings uses the settings instead of loading the settings file, e.g. in tests.
+func WithSettings(settings *setting.Settings) Option {
+	return func(controller *Controller) {
+		controller.settings = settings
+	}
+}
+
+func WithGameService(service GameService) Option {
+	return func(controller *Controller) {
+		controller.gameService = service
+	}
+}
+
+func WithUserService(service UserService) Option {
+	return func(controller *Controller) {
+		controller.userService = service
+	}
+}
+
+func WithChatService(service ChatService) Option {
+	return func(controller *Controller) {
+		controller.chatService = service
+	}
+}
+
+func WithFileService(service FileService) Option {
+	return func(controller *Controller) {
+		controller.fileService = service
+	}
+}
+
+func WithHealthService(service HealthService) Option {
+	return func(controller *Controller) {
+		controller.healthService = service
+	}
+}
+
+func WithEndpointService(service EndpointService) Option {
+	return func(controller *Controller) {
+		controller.endpointService = service
+	}
+}
+
+func WithDiscoveryService(service DiscoveryService) Option {
+	return func(controller *Controller) {
+		controller.discoveryService = service
+	}
+}
+
+func WithServerProbe(probe ServerProbe) Option {
+	return func(controller *Controller) {
+		controller.serverProbe = probe
+	}
+}
+
+// WithRetryPolicy sets the backoff of the controllers retrying requests to the server.
+func WithRetryPolicy(policy retry.Policy) Option {
+	return func(controller *Controller) {
+		controller.retryPolicy = policy
+	}
+}
+
+// gatewayEndpointService sets the server the gateway of the API client forwards to, ws:// and
+// wss:// server URLs are accepted for http:// and https://.
+type gatewayEndpointService struct {
+	gateway *gateway.Gateway
+}
+
+func (service *gatewayEndpointService) SetBaseURL(serverurl string) error {
+	return service.gateway.SetServerURL(setting.BaseURL(serverurl))
+}
+
+type apiChatService struct {
+	client *api.Client
+}
+
+func (service *apiChatService) Connect() error {
+	_, err := service.client.Chat.Connect()
+	return err
+}
+
+func (service *apiChatService) Disconnect() error {
+	return service.client.Chat.Disconnect()
+}
+
+func (service *apiChatService) Reconnect() error {
+	return service.client.Chat.Reconnect()
+}
+
+func (service *apiChatService) SendMessage(message chat.Message) error {
+	return service.client.Chat.SendMessage(message)
+}
+
+func (service *apiChatService) Messages() <-chan chat.Message {
+	return service.client.Chat.Messages
+}
+
+func (service *apiChatService) Err() error {
+	return service.client.Chat.Error
+}
+
+// httpServerProbe requests the health endpoint of the server.
+type httpServerProbe struct {
+	client *http.Client
+}
+
+func (probe *httpServerProbe) Probe(ctx context.Context, serverurl string) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(setting.BaseURL(serverurl), "/")+"/health", nil)
+	if err != nil {
+		return err
+	}
+	response, err := probe.client.Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("health check of %s returned %s", serverurl, response.Status)
+	}
+	return nil
+}
+
+// apiFileService downloads files of the server through the gateway, so they are requested with the
+// TLS settings and event password as well.
+type apiFileService struct {
+	client  *api.Client
+	gateway *gateway.Gateway
+}
+
+func (service *apiFileService) GetFile(ctx context.Context, url url.URL, destination string) (*network.Download, error) {
+	return service.client.File.GetFile(ctx, service.gateway.Resolve(url), destination)
+}
+
+func (service *apiFileService) UploadFile(path string, user user.User) (chat.Message, error) {
+	fileresponse, err := service.client.File.UploadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return chat.NewFileMessage(user, fileresponse), nil
+}
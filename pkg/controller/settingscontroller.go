@@ -1,76 +1,388 @@
 package controller
 
 import (
+	"context"
 	"errors"
-	"os"
+	"io"
 	"slices"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/rs/zerolog/log"
+	"github.com/seternate/go-lanty-client/pkg/discovery"
+	"github.com/seternate/go-lanty-client/pkg/event"
 	"github.com/seternate/go-lanty-client/pkg/setting"
-	"github.com/seternate/go-lanty/pkg/util"
+	"github.com/seternate/go-lanty-client/pkg/trust"
 )
 
 type SettingsController struct {
-	parent     *Controller
-	settings   *setting.Settings
-	subscriber []chan struct{}
-	mutex      sync.RWMutex
+	parent   *Controller
+	settings *setting.Settings
+	events   *event.EventBus[setting.Settings]
+	routines *routines
+	mutex    sync.RWMutex
 }
 
 func NewSettingsController(parent *Controller, settings *setting.Settings) (controller *SettingsController) {
 	controller = &SettingsController{
-		parent:     parent,
-		settings:   settings,
-		subscriber: make([]chan struct{}, 0, 50),
+		parent:   parent,
+		settings: settings,
+		events:   event.NewEventBus[setting.Settings](),
+		routines: newRoutines(parent, ComponentSettings),
 	}
 
 	return
 }
 
-func (controller *SettingsController) SetServerURL(serverurl string) {
-	err := controller.parent.client.SetBaseURL(serverurl)
+// Start shows a warning if the settings could not be loaded and were recovered or reset and watches
+// the settings file for changes made outside of the client.
+func (controller *SettingsController) Start(ctx context.Context) error {
+	if controller.parent.settingsErr != nil {
+		controller.parent.Status.Warning("Settings could not be loaded: "+controller.parent.settingsErr.Error(), 15*time.Second)
+	}
+	return controller.routines.start(ctx, controller.watch)
+}
+
+func (controller *SettingsController) Stop() {
+	controller.routines.stop()
+}
+
+// SetServerURL stores a well-formed server URL, the server does not have to be reachable.
+func (controller *SettingsController) SetServerURL(serverurl string) error {
+	err := setting.ValidateServerURL(serverurl)
+	if err == nil {
+		err = controller.setEndpoint(serverurl)
+	}
 	if err != nil {
 		controller.parent.Status.Error("Invalid server URL: "+err.Error(), 3*time.Second)
+		return err
 	}
 	controller.mutex.Lock()
 	controller.settings.ServerURL = serverurl
 	controller.mutex.Unlock()
-	controller.notifySubcriber()
-	controller.Save()
+	controller.setCredentials(controller.Password())
+	controller.notifySubcriber(TopicSettingsServerURL)
+	return controller.Save()
 }
 
-func (controller *SettingsController) SetGameDirectory(gamedirectory string) {
-	info, err := os.Stat(gamedirectory)
-	if errors.Is(err, os.ErrNotExist) {
-		controller.parent.Status.Error("Invalid gamedirectory path: does not exist", 3*time.Second)
-		return
+// SetBackupServerURLs stores the servers the connection fails over to, in order.
+func (controller *SettingsController) SetBackupServerURLs(serverurls []string) error {
+	err := setting.ValidateBackupServerURLs(serverurls)
+	if err != nil {
+		controller.parent.Status.Error("Invalid backup server URL: "+err.Error(), 3*time.Second)
+		return err
 	}
-	if !info.IsDir() {
-		controller.parent.Status.Error("Invalid gamedirectory path: not a folder", 3*time.Second)
-		return
+	controller.mutex.Lock()
+	controller.settings.BackupServerURLs = slices.Clone(serverurls)
+	controller.mutex.Unlock()
+	controller.setCredentials(controller.Password())
+	controller.notifySubcriber(TopicSettingsBackupServerURLs)
+	return controller.Save()
+}
+
+// SetPassword stores the event password of the active profile encrypted next to the settings, it is
+// sent with every request to the server. An empty password removes it.
+func (controller *SettingsController) SetPassword(password string) error {
+	err := setting.SavePassword(controller.Settings().ActiveProfile, password)
+	if err != nil {
+		controller.parent.Status.Error("Error saving event password: "+err.Error(), 3*time.Second)
+		return err
+	}
+	controller.setCredentials(password)
+	controller.notifySubcriber(TopicSettingsPassword)
+	return nil
+}
+
+// Password returns the event password of the active profile.
+func (controller *SettingsController) Password() string {
+	return controller.parent.credentials.Password()
+}
+
+// setEndpoint makes the API client use the server. A connection controller switches to the server
+// itself once it is reset after the change, so the server it reports is always the one in use.
+func (controller *SettingsController) setEndpoint(serverurl string) error {
+	if controller.parent.Connection != nil {
+		return nil
+	}
+	return controller.parent.endpointService.SetBaseURL(serverurl)
+}
+
+// setCredentials sends the password to the servers of the active profile.
+func (controller *SettingsController) setCredentials(password string) {
+	controller.parent.credentials.Set(password, controller.Settings().ServerURLs())
+}
+
+// SetCAFile stores the PEM file with the CAs trusted in addition to the system CAs, an empty path
+// trusts the system CAs only.
+func (controller *SettingsController) SetCAFile(cafile string) error {
+	err := setting.ValidateCAFile(cafile)
+	if err != nil {
+		controller.parent.Status.Error("Invalid CA file: "+err.Error(), 3*time.Second)
+		return err
+	}
+	controller.mutex.Lock()
+	controller.settings.CAFile = cafile
+	controller.mutex.Unlock()
+	controller.tlsChanged()
+	return controller.Save()
+}
+
+// SetPinnedCertificates stores the fingerprints of the trusted server certificates, which are
+// trusted instead of the CAs. An empty list trusts the CAs.
+func (controller *SettingsController) SetPinnedCertificates(fingerprints []string) error {
+	err := setting.ValidatePinnedCertificates(fingerprints)
+	if err != nil {
+		controller.parent.Status.Error("Invalid certificate fingerprint: "+err.Error(), 3*time.Second)
+		return err
+	}
+	controller.mutex.Lock()
+	controller.settings.PinnedCertificates = normalizeFingerprints(fingerprints)
+	controller.mutex.Unlock()
+	controller.tlsChanged()
+	return controller.Save()
+}
+
+// normalizeFingerprints returns the fingerprints in the format of trust.Fingerprint without duplicates.
+func normalizeFingerprints(fingerprints []string) []string {
+	normalized := make([]string, 0, len(fingerprints))
+	for _, fingerprint := range fingerprints {
+		fingerprint = trust.NormalizeFingerprint(fingerprint)
+		if !slices.Contains(normalized, fingerprint) {
+			normalized = append(normalized, fingerprint)
+		}
+	}
+	return normalized
+}
+
+// TrustCertificate pins the certificate with the fingerprint in addition to the pinned certificates,
+// e.g. after the user confirmed a self-signed certificate on first use.
+func (controller *SettingsController) TrustCertificate(fingerprint string) error {
+	pinned := slices.Clone(controller.Settings().PinnedCertificates)
+	return controller.SetPinnedCertificates(append(pinned, fingerprint))
+}
+
+func (controller *SettingsController) SetInsecureSkipVerify(insecure bool) error {
+	controller.mutex.Lock()
+	controller.settings.InsecureSkipVerify = insecure
+	controller.mutex.Unlock()
+	controller.tlsChanged()
+	return controller.Save()
+}
+
+// tlsChanged verifies the server certificates with the changed TLS settings and publishes them.
+func (controller *SettingsController) tlsChanged() {
+	err := controller.parent.setTrustConfig(controller.Settings().Profile)
+	if err != nil {
+		controller.parent.Status.Error("Error loading TLS settings: "+err.Error(), 3*time.Second)
+	}
+	controller.notifySubcriber(TopicSettingsTLS)
+}
+
+// CheckServerURL returns a setting.ErrUnreachable error if the server can not be connected to.
+func (controller *SettingsController) CheckServerURL(serverurl string) error {
+	return setting.CheckServerURLReachable(controller.parent.Context(), serverurl)
+}
+
+// Discover returns the Lanty servers announcing themselves on the local network.
+func (controller *SettingsController) Discover(ctx context.Context) ([]discovery.Server, error) {
+	servers, err := controller.parent.discoveryService.Discover(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("error discovering servers")
+		return nil, err
+	}
+	log.Debug().Int("servers", len(servers)).Msg("discovered servers")
+	return servers, nil
+}
+
+func (controller *SettingsController) SetGameDirectory(gamedirectory string) error {
+	err := setting.ValidateGameDirectory(gamedirectory)
+	if err != nil {
+		controller.parent.Status.Error("Invalid gamedirectory path: "+err.Error(), 3*time.Second)
+		return err
 	}
 	controller.mutex.Lock()
 	controller.settings.GameDirectory = gamedirectory
 	controller.mutex.Unlock()
-	controller.notifySubcriber()
-	controller.Save()
+	controller.notifySubcriber(TopicSettingsGameDirectory)
+	return controller.Save()
 }
 
-func (controller *SettingsController) SetUsername(username string) {
+// SetUsername stores a valid username, which is not used by another user of the server.
+func (controller *SettingsController) SetUsername(username string) error {
+	err := setting.CheckUsernameConflict(username, controller.takenUsernames())
+	if err != nil {
+		controller.parent.Status.Error("Invalid username: "+err.Error(), 3*time.Second)
+		return err
+	}
 	controller.mutex.Lock()
 	controller.settings.Username = username
 	controller.mutex.Unlock()
-	controller.notifySubcriber()
-	controller.Save()
+	controller.notifySubcriber(TopicSettingsUsername)
+	return controller.Save()
 }
 
-func (controller *SettingsController) SetDownloadDirectory(downloaddirectory string) {
+func (controller *SettingsController) SetDownloadDirectory(downloaddirectory string) error {
+	err := setting.ValidateDownloadDirectory(downloaddirectory)
+	if err != nil {
+		controller.parent.Status.Error("Invalid download directory path: "+err.Error(), 3*time.Second)
+		return err
+	}
 	controller.mutex.Lock()
 	controller.settings.DownloadDirectory = downloaddirectory
 	controller.mutex.Unlock()
-	controller.notifySubcriber()
-	controller.Save()
+	controller.notifySubcriber(TopicSettingsDownloadDirectory)
+	return controller.Save()
+}
+
+// takenUsernames returns the usernames of the other users of the server.
+func (controller *SettingsController) takenUsernames() []string {
+	taken := make([]string, 0)
+	if controller.parent.User == nil {
+		return taken
+	}
+	self := controller.parent.User.GetUser()
+	for _, user := range controller.parent.User.GetUsers() {
+		if user.IP != self.IP || self.IP == "" {
+			taken = append(taken, user.Name)
+		}
+	}
+	return taken
+}
+
+// SwitchProfile makes the profile with the name the active one. Changed settings are published to
+// their topics, so the server connection, user and chat reconnect to the server of the profile.
+func (controller *SettingsController) SwitchProfile(name string) error {
+	controller.mutex.Lock()
+	previous := controller.settings.Profile
+	err := controller.settings.SwitchProfile(name)
+	current := controller.settings.Profile
+	controller.mutex.Unlock()
+	if err != nil {
+		controller.parent.Status.Error("Error switching profile: "+err.Error(), 3*time.Second)
+		return err
+	}
+	if previous.Name == current.Name {
+		return nil
+	}
+	controller.profileChanged(previous, current)
+	controller.parent.Status.Info("Switched to profile "+current.Name, 3*time.Second)
+	return controller.Save()
+}
+
+// profileChanged updates the server URL of the API client and publishes the changed settings of
+// the active profile.
+func (controller *SettingsController) profileChanged(previous setting.Profile, current setting.Profile) {
+	err := controller.setEndpoint(current.ServerURL)
+	if err != nil {
+		controller.parent.Status.Error("Invalid server URL: "+err.Error(), 3*time.Second)
+	}
+	controller.notifySubcriber(TopicSettingsProfile)
+	password, err := setting.LoadPassword(current.Name)
+	if err != nil {
+		controller.parent.Status.Error("Error loading event password: "+err.Error(), 3*time.Second)
+	}
+	changed := password != controller.Password()
+	controller.setCredentials(password)
+	if changed {
+		controller.notifySubcriber(TopicSettingsPassword)
+	}
+	if previous.ServerURL != current.ServerURL {
+		controller.notifySubcriber(TopicSettingsServerURL)
+	}
+	if !slices.Equal(previous.BackupServerURLs, current.BackupServerURLs) {
+		controller.notifySubcriber(TopicSettingsBackupServerURLs)
+	}
+	if previous.CAFile != current.CAFile || !slices.Equal(previous.PinnedCertificates, current.PinnedCertificates) || previous.InsecureSkipVerify != current.InsecureSkipVerify {
+		controller.tlsChanged()
+	}
+	if previous.GameDirectory != current.GameDirectory {
+		controller.notifySubcriber(TopicSettingsGameDirectory)
+	}
+	if previous.Username != current.Username {
+		controller.notifySubcriber(TopicSettingsUsername)
+	}
+	if previous.DownloadDirectory != current.DownloadDirectory {
+		controller.notifySubcriber(TopicSettingsDownloadDirectory)
+	}
+}
+
+func (controller *SettingsController) ExportBundle(w io.Writer) error {
+	return controller.Settings().ExportBundle(w)
+}
+
+// PreviewImport returns the settings after importing the bundle and the changes to the current
+// settings without applying them.
+func (controller *SettingsController) PreviewImport(bundle *setting.Bundle, mode setting.ImportMode) (setting.Settings, []setting.Change) {
+	current := controller.Settings()
+	imported := current.Import(bundle.Settings, mode)
+	return imported, current.Diff(imported)
+}
+
+// ApplyImport replaces the settings with the imported settings of PreviewImport. Invalid settings of
+// the active profile, e.g. folders of the old computer, are applied and reported.
+func (controller *SettingsController) ApplyImport(imported setting.Settings) error {
+	controller.mutex.Lock()
+	previous := controller.settings.Profile
+	*controller.settings = imported
+	controller.mutex.Unlock()
+	controller.profileChanged(previous, imported.Profile)
+	err := imported.Profile.Validate()
+	if err != nil {
+		controller.parent.Status.Warning("Imported settings need changes: "+strings.ReplaceAll(err.Error(), "\n", ", "), 8*time.Second)
+	} else {
+		controller.parent.Status.Info("Settings imported", 3*time.Second)
+	}
+	return controller.Save()
+}
+
+func (controller *SettingsController) AddProfile(profile setting.Profile) error {
+	controller.mutex.Lock()
+	err := controller.settings.AddProfile(profile)
+	controller.mutex.Unlock()
+	if err != nil {
+		controller.parent.Status.Error("Error adding profile: "+err.Error(), 3*time.Second)
+		return err
+	}
+	controller.notifySubcriber(TopicSettingsProfile)
+	return controller.Save()
+}
+
+// RemoveProfile removes the profile with the name. If it is the active profile, the first other
+// profile becomes active before.
+func (controller *SettingsController) RemoveProfile(name string) error {
+	settings := controller.Settings()
+	if name == settings.ActiveProfile {
+		next := ""
+		for _, profile := range settings.ProfileNames() {
+			if profile != name {
+				next = profile
+				break
+			}
+		}
+		if next == "" {
+			err := errors.New("the last profile can not be removed")
+			controller.parent.Status.Error("Error removing profile: "+err.Error(), 3*time.Second)
+			return err
+		}
+		err := controller.SwitchProfile(next)
+		if err != nil {
+			return err
+		}
+	}
+	controller.mutex.Lock()
+	err := controller.settings.RemoveProfile(name)
+	controller.mutex.Unlock()
+	if err != nil {
+		controller.parent.Status.Error("Error removing profile: "+err.Error(), 3*time.Second)
+		return err
+	}
+	err = setting.SavePassword(name, "")
+	if err != nil {
+		log.Warn().Err(err).Str("profile", name).Msg("error removing event password of profile")
+	}
+	controller.notifySubcriber(TopicSettingsProfile)
+	return controller.Save()
 }
 
 func (controller *SettingsController) Settings() setting.Settings {
@@ -87,23 +399,14 @@ func (controller *SettingsController) Save() (err error) {
 	return
 }
 
-func (controller *SettingsController) Subscribe(subscriber chan struct{}) {
-	defer controller.mutex.Unlock()
-	controller.mutex.Lock()
-	controller.subscriber = append(controller.subscriber, subscriber)
+func (controller *SettingsController) Subscribe(subscriber chan event.Event[setting.Settings], topics ...event.Topic) {
+	controller.events.Subscribe(subscriber, topics...)
 }
 
-func (controller *SettingsController) Unsubscribe(subscriber chan struct{}) {
-	defer controller.mutex.Unlock()
-	controller.mutex.Lock()
-	index := slices.Index(controller.subscriber, subscriber)
-	_ = slices.Delete(controller.subscriber, index, index+1)
+func (controller *SettingsController) Unsubscribe(subscriber chan event.Event[setting.Settings]) {
+	controller.events.Unsubscribe(subscriber)
 }
 
-func (controller *SettingsController) notifySubcriber() {
-	defer controller.mutex.RUnlock()
-	controller.mutex.RLock()
-	for _, subscriber := range controller.subscriber {
-		util.ChannelWriteNonBlocking(subscriber, struct{}{})
-	}
+func (controller *SettingsController) notifySubcriber(topic event.Topic) {
+	controller.events.Publish(topic, controller.Settings())
 }
@@ -0,0 +1,174 @@
+package controller
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/seternate/go-lanty-client/pkg/discovery"
+	"github.com/seternate/go-lanty-client/pkg/event"
+	"github.com/seternate/go-lanty-client/pkg/lantytest"
+	"github.com/seternate/go-lanty-client/pkg/setting"
+)
+
+func TestSetUsername(t *testing.T) {
+	server := lantytest.NewServer()
+	defer server.Close()
+	server.SetUserIP("10.0.0.2")
+	controller := newTestController(t, server)
+	changed := make(chan event.Event[setting.Settings], 10)
+	controller.Settings.Subscribe(changed, TopicSettingsUsername)
+	defer controller.Settings.Unsubscribe(changed)
+	eventually(t, controller.User.IsLoggedIn, "login")
+
+	err := controller.Settings.SetUsername("gamer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case event := <-changed:
+		if event.Data.Username != "gamer" {
+			t.Errorf("got username %s in the event, want gamer", event.Data.Username)
+		}
+	case <-time.After(TEST_TIMEOUT):
+		t.Fatal("timed out waiting for the username event")
+	}
+	if saved := readSettings(t); saved.Username != "gamer" {
+		t.Errorf("got saved username %s, want gamer", saved.Username)
+	}
+	eventually(t, func() bool { return serverUser(server, "10.0.0.2").Name == "gamer" }, "renamed user at the server")
+
+	err = controller.Settings.SetUsername("bad name!")
+	if err == nil {
+		t.Fatal("got no error for an invalid username")
+	}
+	if controller.Settings.Settings().Username != "gamer" || readSettings(t).Username != "gamer" {
+		t.Error("invalid username was applied")
+	}
+}
+
+func TestSetDirectories(t *testing.T) {
+	server := lantytest.NewServer()
+	defer server.Close()
+	controller := newTestController(t, server)
+
+	// Missing folders are accepted, they are created when they are used.
+	gamedirectory := filepath.Join(t.TempDir(), "lan", "games")
+	err := controller.Settings.SetGameDirectory(gamedirectory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved := readSettings(t); saved.GameDirectory != gamedirectory {
+		t.Errorf("got saved game folder %s, want %s", saved.GameDirectory, gamedirectory)
+	}
+
+	file := filepath.Join(t.TempDir(), "file")
+	err = os.WriteFile(file, nil, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controller.Settings.SetDownloadDirectory(file)
+	if err == nil {
+		t.Fatal("got no error for a file as download folder")
+	}
+	if controller.Settings.Settings().DownloadDirectory == file {
+		t.Error("invalid download folder was applied")
+	}
+}
+
+func TestReloadSettings(t *testing.T) {
+	server := lantytest.NewServer()
+	defer server.Close()
+	controller := newTestController(t, server)
+	changed := make(chan event.Event[setting.Settings], 10)
+	controller.Settings.Subscribe(changed, TopicSettingsDownloadDirectory)
+	defer controller.Settings.Unsubscribe(changed)
+
+	// The file is saved again until the watcher, which starts in the background, reloads it.
+	edited := readSettings(t)
+	edited.DownloadDirectory = filepath.Join(t.TempDir(), "edited")
+	eventually(t, func() bool {
+		err := edited.Save()
+		if err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-changed:
+			return true
+		case <-time.After(2 * RELOAD_DELAY):
+			return false
+		}
+	}, "reloaded settings")
+	if controller.Settings.Settings().DownloadDirectory != edited.DownloadDirectory {
+		t.Errorf("got download folder %s, want %s", controller.Settings.Settings().DownloadDirectory, edited.DownloadDirectory)
+	}
+
+	// An invalid file is rejected as a whole and not overwritten.
+	invalid := readSettings(t)
+	invalid.Username = "bad name!"
+	invalid.DownloadDirectory = filepath.Join(t.TempDir(), "rejected")
+	err := invalid.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(RELOAD_DELAY + 200*time.Millisecond)
+	current := controller.Settings.Settings()
+	if current.Username != "player" || current.DownloadDirectory != edited.DownloadDirectory {
+		t.Errorf("got settings %+v, want the invalid file rejected", current.Profile)
+	}
+	if readSettings(t).Username != invalid.Username {
+		t.Error("invalid settings file was overwritten")
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	server := lantytest.NewServer()
+	defer server.Close()
+	announcer, err := server.Announce("127.0.0.1:0", "LAN party")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer announcer.Close()
+	controller := newTestController(t, server, WithDiscoveryService(discovery.NewClient(announcer.Addr().Port, time.Second)))
+
+	servers, err := controller.Settings.Discover(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(servers) != 1 || servers[0].Name != "LAN party" || servers[0].URL != server.URL {
+		t.Fatalf("got servers %+v, want the announced server", servers)
+	}
+
+	// The latency of the server delays its answer, which is measured by the discovery.
+	server.SetLatency(100 * time.Millisecond)
+	servers, err = controller.Settings.Discover(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(servers) != 1 || servers[0].Latency < 100*time.Millisecond {
+		t.Fatalf("got servers %+v, want a latency of at least 100ms", servers)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = controller.Settings.Discover(ctx)
+	if err == nil {
+		t.Error("got no error for a canceled discovery")
+	}
+}
+
+// readSettings reads the settings file of the config folder.
+func readSettings(t *testing.T) *setting.Settings {
+	t.Helper()
+	settingspath, err := setting.Path()
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings, err := setting.ReadSettings(settingspath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return settings
+}
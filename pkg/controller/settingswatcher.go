@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog/log"
+	"github.com/seternate/go-lanty-client/pkg/setting"
+)
+
+// RELOAD_DELAY collects the events of one write of the settings file, which are often several.
+const RELOAD_DELAY = 500 * time.Millisecond
+
+// watch reloads the settings file if it is changed outside of the client. The folder is watched, as
+// the file is replaced when saving.
+func (controller *SettingsController) watch(ctx context.Context) {
+	filepath, err := setting.Path()
+	if err != nil {
+		log.Error().Err(err).Msg("error getting settings path, settings are not reloaded")
+		return
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Error().Err(err).Msg("error creating settings watcher, settings are not reloaded")
+		return
+	}
+	defer watcher.Close()
+	err = watcher.Add(path.Dir(filepath))
+	if err != nil {
+		log.Error().Err(err).Str("path", filepath).Msg("error watching settings, settings are not reloaded")
+		return
+	}
+
+	reload := time.NewTimer(RELOAD_DELAY)
+	reload.Stop()
+	defer reload.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Trace().Err(ctx.Err()).Msg("exiting settingscontroller watch()")
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if path.Base(event.Name) != path.Base(filepath) || event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+				continue
+			}
+			reload.Reset(RELOAD_DELAY)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Warn().Err(err).Msg("error watching settings")
+		case <-reload.C:
+			controller.reload(filepath)
+		}
+	}
+}
+
+// reload applies the changes of the settings file. All changes are validated like in the client
+// before any is applied, so an invalid settings file is rejected as a whole. The changes are applied
+// in memory only, the file already contains them.
+func (controller *SettingsController) reload(filepath string) {
+	current := controller.Settings()
+	loaded, err := setting.ReadSettings(filepath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	if err == nil {
+		err = validateChanges(current, *loaded)
+	}
+	if err == nil && loaded.Username != current.Username {
+		err = setting.CheckUsernameConflict(loaded.Username, controller.takenUsernames())
+		if err != nil {
+			err = fmt.Errorf("invalid username: %w", err)
+		}
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("path", filepath).Msg("rejected changed settings file")
+		controller.parent.Status.Error("Rejected changed settings file: "+err.Error(), 5*time.Second)
+		return
+	}
+	loaded.PinnedCertificates = normalizeFingerprints(loaded.PinnedCertificates)
+	if !settingsChanged(current, *loaded) {
+		return
+	}
+
+	controller.mutex.Lock()
+	*controller.settings = *loaded
+	controller.mutex.Unlock()
+	controller.profileChanged(current.Profile, loaded.Profile)
+	log.Info().Str("path", filepath).Msg("reloaded changed settings file")
+	controller.parent.Status.Info("Settings reloaded", 3*time.Second)
+}
+
+// settingsChanged returns if the settings differ, unset and empty lists are equal.
+func settingsChanged(current setting.Settings, loaded setting.Settings) bool {
+	if current.ActiveProfile != loaded.ActiveProfile || !slices.Equal(current.ProfileNames(), loaded.ProfileNames()) ||
+		!reflect.DeepEqual(current.Hooks, loaded.Hooks) || !reflect.DeepEqual(current.Unknown, loaded.Unknown) {
+		return true
+	}
+	for _, profile := range loaded.Profiles {
+		previous, err := current.GetProfile(profile.Name)
+		if err == nil && profile.Name != loaded.ActiveProfile && !reflect.DeepEqual(previous, profile) {
+			return true
+		}
+	}
+	return current.ServerURL != loaded.ServerURL ||
+		!slices.Equal(current.BackupServerURLs, loaded.BackupServerURLs) ||
+		current.CAFile != loaded.CAFile ||
+		!slices.Equal(current.PinnedCertificates, loaded.PinnedCertificates) ||
+		current.InsecureSkipVerify != loaded.InsecureSkipVerify ||
+		current.GameDirectory != loaded.GameDirectory ||
+		current.Username != loaded.Username ||
+		current.DownloadDirectory != loaded.DownloadDirectory
+}
+
+// validateChanges validates the changed settings of the active profile.
+func validateChanges(current setting.Settings, changed setting.Settings) error {
+	if changed.ServerURL != current.ServerURL {
+		err := setting.ValidateServerURL(changed.ServerURL)
+		if err != nil {
+			return fmt.Errorf("invalid server URL: %w", err)
+		}
+	}
+	if !slices.Equal(changed.BackupServerURLs, current.BackupServerURLs) {
+		err := setting.ValidateBackupServerURLs(changed.BackupServerURLs)
+		if err != nil {
+			return fmt.Errorf("invalid backup server URL: %w", err)
+		}
+	}
+	if changed.CAFile != current.CAFile {
+		err := setting.ValidateCAFile(changed.CAFile)
+		if err != nil {
+			return fmt.Errorf("invalid CA file: %w", err)
+		}
+	}
+	if !slices.Equal(changed.PinnedCertificates, current.PinnedCertificates) {
+		err := setting.ValidatePinnedCertificates(changed.PinnedCertificates)
+		if err != nil {
+			return fmt.Errorf("invalid certificate fingerprint: %w", err)
+		}
+	}
+	if changed.GameDirectory != current.GameDirectory {
+		err := setting.ValidateGameDirectory(changed.GameDirectory)
+		if err != nil {
+			return fmt.Errorf("invalid gamedirectory path: %w", err)
+		}
+	}
+	if changed.Username != current.Username {
+		err := setting.ValidateUsername(changed.Username)
+		if err != nil {
+			return fmt.Errorf("invalid username: %w", err)
+		}
+	}
+	if changed.DownloadDirectory != current.DownloadDirectory {
+		err := setting.ValidateDownloadDirectory(changed.DownloadDirectory)
+		if err != nil {
+			return fmt.Errorf("invalid download directory path: %w", err)
+		}
+	}
+	return nil
+}
@@ -1,12 +1,12 @@
 package controller
 
 import (
-	"slices"
+	"context"
 	"sync"
 	"time"
 
 	"github.com/golang-collections/collections/queue"
-	"github.com/seternate/go-lanty/pkg/util"
+	"github.com/seternate/go-lanty-client/pkg/event"
 )
 
 type StatusLevel int
@@ -17,6 +17,16 @@ const (
 	StatusLevelError
 )
 
+func (level StatusLevel) String() string {
+	switch level {
+	case StatusLevelWarning:
+		return "warning"
+	case StatusLevelError:
+		return "error"
+	}
+	return "info"
+}
+
 type Status struct {
 	Level    StatusLevel
 	Text     string
@@ -48,37 +58,53 @@ func NewError(text string, duration time.Duration) Status {
 }
 
 type StatusController struct {
+	parent        *Controller
 	infostatus    queue.Queue
 	warningstatus queue.Queue
 	errorstatus   queue.Queue
-	subscriber    []chan struct{}
+	events        *event.EventBus[Status]
 	mutex         sync.Mutex
 }
 
-func NewStatusController() (controller *StatusController) {
-	controller = &StatusController{}
+func NewStatusController(parent *Controller) (controller *StatusController) {
+	controller = &StatusController{
+		parent: parent,
+		events: event.NewEventBus[Status](),
+	}
 	return
 }
 
+func (controller *StatusController) Start(ctx context.Context) error {
+	return nil
+}
+
+func (controller *StatusController) Stop() {}
+
 func (controller *StatusController) Info(text string, duration time.Duration) {
 	controller.mutex.Lock()
-	controller.infostatus.Enqueue(NewInfo(text, duration))
+	status := NewInfo(text, duration)
+	controller.infostatus.Enqueue(status)
 	controller.mutex.Unlock()
-	controller.notifySubcriber()
+	controller.parent.metrics.statusMessages.Inc(status.Level.String())
+	controller.events.Publish(TopicStatus, status)
 }
 
 func (controller *StatusController) Warning(text string, duration time.Duration) {
 	controller.mutex.Lock()
-	controller.warningstatus.Enqueue(NewWarning(text, duration))
+	status := NewWarning(text, duration)
+	controller.warningstatus.Enqueue(status)
 	controller.mutex.Unlock()
-	controller.notifySubcriber()
+	controller.parent.metrics.statusMessages.Inc(status.Level.String())
+	controller.events.Publish(TopicStatus, status)
 }
 
 func (controller *StatusController) Error(text string, duration time.Duration) {
 	controller.mutex.Lock()
-	controller.errorstatus.Enqueue(NewError(text, duration))
+	status := NewError(text, duration)
+	controller.errorstatus.Enqueue(status)
 	controller.mutex.Unlock()
-	controller.notifySubcriber()
+	controller.parent.metrics.statusMessages.Inc(status.Level.String())
+	controller.events.Publish(TopicStatus, status)
 }
 
 func (controller *StatusController) Next() (status Status) {
@@ -94,23 +120,10 @@ func (controller *StatusController) Next() (status Status) {
 	return
 }
 
-func (controller *StatusController) Subscribe(subscriber chan struct{}) {
-	defer controller.mutex.Unlock()
-	controller.mutex.Lock()
-	controller.subscriber = append(controller.subscriber, subscriber)
-}
-
-func (controller *StatusController) Unsubscribe(subscriber chan struct{}) {
-	defer controller.mutex.Unlock()
-	controller.mutex.Lock()
-	index := slices.Index(controller.subscriber, subscriber)
-	_ = slices.Delete(controller.subscriber, index, index+1)
+func (controller *StatusController) Subscribe(subscriber chan event.Event[Status]) {
+	controller.events.Subscribe(subscriber, TopicStatus)
 }
 
-func (controller *StatusController) notifySubcriber() {
-	defer controller.mutex.Unlock()
-	controller.mutex.Lock()
-	for _, subscriber := range controller.subscriber {
-		util.ChannelWriteNonBlocking(subscriber, struct{}{})
-	}
+func (controller *StatusController) Unsubscribe(subscriber chan event.Event[Status]) {
+	controller.events.Unsubscribe(subscriber)
 }
@@ -1,13 +1,15 @@
 package controller
 
 import (
-	"slices"
+	"context"
 	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"github.com/seternate/go-lanty-client/pkg/event"
+	"github.com/seternate/go-lanty-client/pkg/retry"
+	"github.com/seternate/go-lanty-client/pkg/setting"
 	"github.com/seternate/go-lanty/pkg/user"
-	"github.com/seternate/go-lanty/pkg/util"
 )
 
 type UserController struct {
@@ -15,28 +17,47 @@ type UserController struct {
 	user            user.User
 	users           user.Users
 	loggedIn        bool
-	subscriber      []chan struct{}
+	events          *event.EventBus[user.User]
+	routines        *routines
 	refreshinterval time.Duration
+	backoff         *retry.Backoff
 	err             error
-	usernameupdated chan struct{}
+	usernameupdated chan event.Event[setting.Settings]
 	mutex           sync.RWMutex
 }
 
 func NewUserController(parent *Controller, refreshinteval time.Duration) (controller *UserController) {
 	controller = &UserController{
 		parent:          parent,
-		user:            user.User{Name: parent.settings.Username},
+		user:            user.User{Name: parent.currentSettings().Username},
 		loggedIn:        false,
-		subscriber:      make([]chan struct{}, 0, 50),
+		events:          event.NewEventBus[user.User](),
+		routines:        newRoutines(parent, ComponentUser),
 		refreshinterval: refreshinteval,
-		usernameupdated: make(chan struct{}, 50),
+		backoff:         retry.NewBackoff(parent.retryPolicy),
+		usernameupdated: make(chan event.Event[setting.Settings], 50),
 	}
-	parent.Settings.Subscribe(controller.usernameupdated)
-	parent.WaitGroup().Add(1)
-	go controller.run()
 	return
 }
 
+func (controller *UserController) Start(ctx context.Context) error {
+	controller.parent.Settings.Subscribe(controller.usernameupdated, TopicSettingsUsername)
+	// The profile may have been switched while stopped, so the user is logged in again with the
+	// username of the active profile.
+	controller.mutex.Lock()
+	controller.user = user.User{Name: controller.parent.currentSettings().Username}
+	controller.mutex.Unlock()
+	return controller.routines.start(ctx, controller.run)
+}
+
+func (controller *UserController) Stop() {
+	controller.routines.stop()
+	controller.parent.Settings.Unsubscribe(controller.usernameupdated)
+	controller.mutex.Lock()
+	controller.loggedIn = false
+	controller.mutex.Unlock()
+}
+
 func (controller *UserController) GetUser() user.User {
 	defer controller.mutex.RUnlock()
 	controller.mutex.RLock()
@@ -61,31 +82,46 @@ func (controller *UserController) Err() error {
 	return controller.err
 }
 
-func (controller *UserController) run() {
-	defer controller.parent.WaitGroup().Done()
-	controller.login()
+func (controller *UserController) Refresh() error {
+	controller.updateUsers()
+	return controller.Err()
+}
+
+// run keeps the user logged in and the userlist updated. A failed login is retried with backoff
+// instead of waiting for the next keepalive.
+func (controller *UserController) run(ctx context.Context) {
+	controller.backoff.Success()
+	var retry <-chan time.Time
+	if !controller.login() {
+		retry = time.After(controller.backoff.Wait())
+	}
 	controller.updateUsers()
 	ticker := time.NewTicker(controller.refreshinterval)
+	defer ticker.Stop()
 	updateChannel := make(chan struct{}, 10)
 	for {
 		select {
-		case <-controller.parent.Context().Done():
-			log.Trace().Err(controller.parent.Context().Err()).Msg("exiting usercontroller run()")
+		case <-ctx.Done():
+			log.Trace().Err(ctx.Err()).Msg("exiting usercontroller run()")
 			return
 		case <-updateChannel:
 			if controller.IsLoggedIn() {
 				controller.loginKeepAlive()
-			} else {
-				controller.login()
+			} else if controller.backoff.Ready() && !controller.login() {
+				retry = time.After(controller.backoff.Wait())
 			}
 			controller.updateUsers()
+		case <-retry:
+			if !controller.IsLoggedIn() {
+				updateChannel <- struct{}{}
+			}
 		case <-ticker.C:
 			updateChannel <- struct{}{}
-		case <-controller.usernameupdated:
+		case event := <-controller.usernameupdated:
 			name := controller.GetUser().Name
-			if name != controller.parent.settings.Username {
+			if name != event.Data.Username {
 				controller.mutex.Lock()
-				controller.user.Name = controller.parent.settings.Username
+				controller.user.Name = event.Data.Username
 				controller.mutex.Unlock()
 				updateChannel <- struct{}{}
 				log.Debug().Msg("updated user")
@@ -95,8 +131,14 @@ func (controller *UserController) run() {
 }
 
 func (controller *UserController) updateUsers() {
-	ips, err := controller.parent.client.User.GetUsers()
+	start := time.Now()
+	defer func() {
+		controller.parent.metrics.pollDuration.Observe(time.Since(start).Seconds(), pollerUser)
+	}()
+	ips, err := controller.parent.userService.GetUsers()
+	controller.parent.reportConnection(SubsystemAPI, err)
 	if err != nil {
+		controller.parent.metrics.pollErrors.Inc(pollerUser)
 		controller.mutex.Lock()
 		controller.err = err
 		controller.mutex.Unlock()
@@ -105,8 +147,9 @@ func (controller *UserController) updateUsers() {
 	}
 	users := user.Users{}
 	for _, ip := range ips {
-		user, err := controller.parent.client.User.GetUser(ip)
+		user, err := controller.parent.userService.GetUser(ip)
 		if err != nil {
+			controller.parent.metrics.pollErrors.Inc(pollerUser)
 			controller.mutex.Lock()
 			controller.err = err
 			controller.mutex.Unlock()
@@ -122,9 +165,10 @@ func (controller *UserController) updateUsers() {
 			return
 		}
 	}
-	controller.mutex.RLock()
+	controller.mutex.Lock()
+	controller.err = nil
 	localUsers := controller.users
-	controller.mutex.RUnlock()
+	controller.mutex.Unlock()
 	if localUsers.Equal(users) {
 		return
 	}
@@ -132,14 +176,33 @@ func (controller *UserController) updateUsers() {
 	controller.users = users
 	controller.mutex.Unlock()
 	log.Debug().Interface("users", users).Msg("updated users in usercontroller")
-	controller.notifySubcriber()
+	controller.publishChanges(localUsers, users)
+}
+
+func (controller *UserController) publishChanges(oldUsers user.Users, newUsers user.Users) {
+	old := make(map[string]user.User, len(oldUsers.Users()))
+	for _, u := range oldUsers.Users() {
+		old[u.IP] = u
+	}
+	for _, u := range newUsers.Users() {
+		oldUser, found := old[u.IP]
+		delete(old, u.IP)
+		if !found {
+			controller.events.Publish(TopicUserJoined, u)
+		} else if oldUser != u {
+			controller.events.Publish(TopicUserUpdated, u)
+		}
+	}
+	for _, u := range old {
+		controller.events.Publish(TopicUserLeft, u)
+	}
 }
 
 func (controller *UserController) loginKeepAlive() {
 	controller.mutex.RLock()
 	user := controller.user
 	controller.mutex.RUnlock()
-	_, err := controller.parent.client.User.UpdateUser(user)
+	_, err := controller.parent.userService.UpdateUser(user)
 	if err != nil {
 		controller.mutex.Lock()
 		controller.loggedIn = false
@@ -148,36 +211,27 @@ func (controller *UserController) loginKeepAlive() {
 	}
 }
 
-func (controller *UserController) login() {
-	user, err := controller.parent.client.User.CreateNewUser(controller.user)
+// login logs in the user and returns false if it failed.
+func (controller *UserController) login() bool {
+	user, err := controller.parent.userService.CreateNewUser(controller.GetUser())
 	if err != nil {
-		log.Error().Err(err).Msgf("could not login at server - retries exceeded")
-		return
+		delay := controller.backoff.Failure()
+		log.Error().Err(err).Dur("retry", delay).Msg("could not login at server")
+		return false
 	}
+	controller.backoff.Success()
 	log.Debug().Interface("user", user).Msg("user logged in")
 	controller.mutex.Lock()
 	controller.user = user
 	controller.loggedIn = true
 	controller.mutex.Unlock()
+	return true
 }
 
-func (controller *UserController) Subscribe(subscriber chan struct{}) {
-	defer controller.mutex.Unlock()
-	controller.mutex.Lock()
-	controller.subscriber = append(controller.subscriber, subscriber)
-}
-
-func (controller *UserController) Unsubscribe(subscriber chan struct{}) {
-	defer controller.mutex.Unlock()
-	controller.mutex.Lock()
-	index := slices.Index(controller.subscriber, subscriber)
-	_ = slices.Delete(controller.subscriber, index, index+1)
+func (controller *UserController) Subscribe(subscriber chan event.Event[user.User], topics ...event.Topic) {
+	controller.events.Subscribe(subscriber, topics...)
 }
 
-func (controller *UserController) notifySubcriber() {
-	defer controller.mutex.RUnlock()
-	controller.mutex.RLock()
-	for _, subscriber := range controller.subscriber {
-		util.ChannelWriteNonBlocking(subscriber, struct{}{})
-	}
+func (controller *UserController) Unsubscribe(subscriber chan event.Event[user.User]) {
+	controller.events.Unsubscribe(subscriber)
 }
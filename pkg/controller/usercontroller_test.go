@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/seternate/go-lanty-client/pkg/lantytest"
+	"github.com/seternate/go-lanty/pkg/user"
+)
+
+func TestUserLogin(t *testing.T) {
+	server := lantytest.NewServer()
+	defer server.Close()
+	server.SetUserIP("10.0.0.2")
+	server.Fail(lantytest.RouteUsers, http.StatusInternalServerError, 2)
+	controller := newTestController(t, server)
+
+	eventually(t, controller.User.IsLoggedIn, "login")
+	logged := controller.User.GetUser()
+	if logged.IP != "10.0.0.2" || logged.Name != "player" {
+		t.Fatalf("got user %+v, want player with the IP of the server", logged)
+	}
+	eventually(t, func() bool { return serverUser(server, logged.IP).Name == "player" }, "user at the server")
+	eventually(t, func() bool { return len(controller.User.GetUsers()) == 1 }, "userlist")
+}
+
+func TestUserKeepAlive(t *testing.T) {
+	server := lantytest.NewServer()
+	defer server.Close()
+	server.SetUserIP("10.0.0.2")
+	controller := newTestController(t, server)
+	eventually(t, controller.User.IsLoggedIn, "login")
+
+	keepalives := server.Requests(lantytest.RouteUser)
+	eventually(t, func() bool { return server.Requests(lantytest.RouteUser) >= keepalives+3 }, "keepalives")
+
+	// A server which forgot the user, e.g. after a restart, gets a new login.
+	logins := server.Requests(lantytest.RouteUsers)
+	server.RemoveUser("10.0.0.2")
+	eventually(t, func() bool { return serverUser(server, "10.0.0.2").Name == "player" }, "login after the user was removed")
+	if server.Requests(lantytest.RouteUsers) <= logins {
+		t.Error("got no new login")
+	}
+}
+
+// serverUser returns the user of the server with the IP or an empty user.
+func serverUser(server *lantytest.Server, ip string) user.User {
+	for _, u := range server.Users() {
+		if u.IP == ip {
+			return u
+		}
+	}
+	return user.User{}
+}
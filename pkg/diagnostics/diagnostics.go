@@ -0,0 +1,321 @@
+package diagnostics
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/seternate/go-lanty-client/pkg/controller"
+	"github.com/seternate/go-lanty-client/pkg/setting"
+	"github.com/seternate/go-lanty/pkg/logging"
+	"github.com/seternate/go-lanty/pkg/network"
+)
+
+const REDACTED = "<redacted>"
+
+type system struct {
+	Application     string    `json:"application"`
+	Version         string    `json:"version"`
+	OS              string    `json:"os"`
+	Arch            string    `json:"arch"`
+	GoVersion       string    `json:"goversion"`
+	Time            time.Time `json:"time"`
+	OutboundIP      string    `json:"outboundip,omitempty"`
+	OutboundIPError string    `json:"outboundiperror,omitempty"`
+	Connection      string    `json:"connection,omitempty"`
+	Portable        bool      `json:"portable"`
+}
+
+type componentView struct {
+	Name     string    `json:"name"`
+	State    string    `json:"state"`
+	Restarts int       `json:"restarts"`
+	Error    string    `json:"error,omitempty"`
+	Since    time.Time `json:"since"`
+}
+
+type connectionView struct {
+	Status     string            `json:"status"`
+	RTT        string            `json:"rtt"`
+	Loss       float64           `json:"loss"`
+	Subsystems []subsystemView   `json:"subsystems"`
+	History    []stateChangeView `json:"history"`
+}
+
+type subsystemView struct {
+	Name  string    `json:"name"`
+	OK    bool      `json:"ok"`
+	Error string    `json:"error,omitempty"`
+	Since time.Time `json:"since"`
+}
+
+type stateChangeView struct {
+	From  string    `json:"from"`
+	To    string    `json:"to"`
+	Time  time.Time `json:"time"`
+	Error string    `json:"error,omitempty"`
+}
+
+type gameView struct {
+	Slug      string `json:"slug"`
+	Name      string `json:"name"`
+	Installed bool   `json:"installed"`
+}
+
+type downloadView struct {
+	Slug     string  `json:"slug"`
+	State    string  `json:"state"`
+	Progress float64 `json:"progress"`
+	Retries  uint64  `json:"retries"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// Exporter writes a zip file with the logs and the current state of the controller, which can be
+// attached to bug reports.
+type Exporter struct {
+	controller *controller.Controller
+	logconfig  logging.Config
+}
+
+func NewExporter(controller *controller.Controller, logconfig logging.Config) *Exporter {
+	return &Exporter{
+		controller: controller,
+		logconfig:  logconfig,
+	}
+}
+
+func (exporter *Exporter) Filename() string {
+	return fmt.Sprintf("lanty-diagnostics-%s.zip", time.Now().Format("20060102-150405"))
+}
+
+func (exporter *Exporter) Export(path string) (err error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return
+	}
+	defer func() {
+		closeErr := file.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}()
+	return exporter.Write(file)
+}
+
+func (exporter *Exporter) Write(w io.Writer) error {
+	archive := zip.NewWriter(w)
+	files := []struct {
+		name string
+		v    any
+	}{
+		{"system.json", exporter.system()},
+		{"components.json", exporter.components()},
+		{"connection.json", exporter.connection()},
+		{"settings.json", exporter.settings()},
+		{"games.json", exporter.games()},
+		{"downloads.json", exporter.downloads()},
+		{"users.json", exporter.users()},
+	}
+	for _, file := range files {
+		err := writeJSON(archive, file.name, file.v)
+		if err != nil {
+			return err
+		}
+	}
+	err := exporter.writeLogs(archive)
+	if err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+func (exporter *Exporter) system() system {
+	system := system{
+		Application: setting.APPLICATION_NAME,
+		Version:     setting.VERSION,
+		OS:          runtime.GOOS,
+		Arch:        runtime.GOARCH,
+		GoVersion:   runtime.Version(),
+		Time:        time.Now(),
+	}
+	ip, err := network.GetOutboundIP()
+	if err != nil {
+		system.OutboundIPError = err.Error()
+	} else {
+		system.OutboundIP = ip.String()
+	}
+	if exporter.controller.Connection != nil {
+		system.Connection = exporter.controller.Connection.GetStatus().String()
+	}
+	directories, err := setting.Dirs()
+	if err == nil {
+		system.Portable = directories.Portable
+	}
+	return system
+}
+
+func (exporter *Exporter) components() []componentView {
+	health := exporter.controller.Health()
+	views := make([]componentView, 0, len(health))
+	for _, component := range health {
+		view := componentView{
+			Name:     component.Name,
+			State:    component.State.String(),
+			Restarts: component.Restarts,
+			Since:    component.Since,
+		}
+		if component.Err != nil {
+			view.Error = component.Err.Error()
+		}
+		views = append(views, view)
+	}
+	return views
+}
+
+func (exporter *Exporter) connection() *connectionView {
+	if exporter.controller.Connection == nil {
+		return nil
+	}
+	quality := exporter.controller.Connection.Quality()
+	view := &connectionView{
+		Status:     quality.Status.String(),
+		RTT:        quality.RTT.String(),
+		Loss:       quality.Loss,
+		Subsystems: make([]subsystemView, 0, len(quality.Subsystems)),
+		History:    make([]stateChangeView, 0),
+	}
+	for _, subsystem := range quality.Subsystems {
+		view.Subsystems = append(view.Subsystems, subsystemView{
+			Name:  string(subsystem.Subsystem),
+			OK:    subsystem.OK,
+			Error: subsystem.Err,
+			Since: subsystem.Since,
+		})
+	}
+	for _, change := range exporter.controller.Connection.History() {
+		view.History = append(view.History, stateChangeView{
+			From:  change.From.String(),
+			To:    change.To.String(),
+			Time:  change.Time,
+			Error: change.Err,
+		})
+	}
+	return view
+}
+
+// settings returns the settings without the username and with the home directory replaced by ~.
+func (exporter *Exporter) settings() any {
+	if exporter.controller.Settings == nil {
+		return nil
+	}
+	settings := exporter.controller.Settings.Settings()
+	home, _ := os.UserHomeDir()
+	settings.Profile = redactProfile(settings.Profile, home)
+	profiles := make([]setting.Profile, 0, len(settings.Profiles))
+	for _, profile := range settings.Profiles {
+		profiles = append(profiles, redactProfile(profile, home))
+	}
+	settings.Profiles = profiles
+	return settings
+}
+
+func redactProfile(profile setting.Profile, home string) setting.Profile {
+	if profile.Username != "" {
+		profile.Username = REDACTED
+	}
+	if home != "" {
+		profile.GameDirectory = strings.Replace(profile.GameDirectory, home, "~", 1)
+		profile.DownloadDirectory = strings.Replace(profile.DownloadDirectory, home, "~", 1)
+	}
+	return profile
+}
+
+func (exporter *Exporter) games() []gameView {
+	views := make([]gameView, 0)
+	if exporter.controller.Game == nil {
+		return views
+	}
+	for _, game := range exporter.controller.Game.GetGames().Games() {
+		views = append(views, gameView{
+			Slug:      game.Slug,
+			Name:      game.Name,
+			Installed: exporter.controller.Game.IsInstalled(game),
+		})
+	}
+	return views
+}
+
+func (exporter *Exporter) downloads() []downloadView {
+	views := make([]downloadView, 0)
+	if exporter.controller.Download == nil {
+		return views
+	}
+	for _, download := range exporter.controller.Download.GetDownloads() {
+		view := downloadView{
+			Slug:     download.Game().Slug,
+			State:    download.State(),
+			Progress: download.Progress(),
+			Retries:  download.Retries(),
+		}
+		if download.Err() != nil {
+			view.Error = download.Err().Error()
+		}
+		views = append(views, view)
+	}
+	return views
+}
+
+func (exporter *Exporter) users() any {
+	if exporter.controller.User == nil {
+		return nil
+	}
+	return exporter.controller.User.GetUsers()
+}
+
+// writeLogs adds the current and all rotated logfiles, e.g. log/lanty.log and log/lanty-<time>.log.
+func (exporter *Exporter) writeLogs(archive *zip.Writer) error {
+	extension := filepath.Ext(exporter.logconfig.Filename)
+	prefix := strings.TrimSuffix(exporter.logconfig.Filename, extension)
+	paths, err := filepath.Glob(filepath.Join(exporter.logconfig.Directory, prefix+"*"+extension+"*"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		err = writeFile(archive, filepath.Join("log", filepath.Base(path)), path)
+		if err != nil {
+			log.Warn().Err(err).Str("path", path).Msg("skipping logfile in diagnostics")
+		}
+	}
+	return nil
+}
+
+func writeJSON(archive *zip.Writer, name string, v any) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func writeFile(archive *zip.Writer, name string, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, file)
+	return err
+}
@@ -0,0 +1,85 @@
+package discovery
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Announcer answers discovery probes with the message returned by announce, which is called for
+// every probe, so a server can announce a changing name or URL.
+type Announcer struct {
+	connection *net.UDPConn
+	announce   func() Message
+	done       chan struct{}
+}
+
+// NewAnnouncer listens for probes on the address, e.g. ":41337".
+func NewAnnouncer(address string, announce func() Message) (*Announcer, error) {
+	udpaddress, err := net.ResolveUDPAddr("udp4", address)
+	if err != nil {
+		return nil, err
+	}
+	connection, err := net.ListenUDP("udp4", udpaddress)
+	if err != nil {
+		return nil, err
+	}
+	announcer := &Announcer{
+		connection: connection,
+		announce:   announce,
+		done:       make(chan struct{}),
+	}
+	go announcer.serve()
+	return announcer, nil
+}
+
+// NewAnnouncement returns the announcement of a server.
+func NewAnnouncement(name string, url string) Message {
+	return Message{
+		Type:    TypeAnnouncement,
+		Version: PROTOCOL_VERSION,
+		Name:    name,
+		URL:     url,
+	}
+}
+
+func (announcer *Announcer) Addr() *net.UDPAddr {
+	return announcer.connection.LocalAddr().(*net.UDPAddr)
+}
+
+func (announcer *Announcer) Close() error {
+	err := announcer.connection.Close()
+	<-announcer.done
+	return err
+}
+
+func (announcer *Announcer) serve() {
+	defer close(announcer.done)
+	buffer := make([]byte, MAX_MESSAGE_SIZE)
+	for {
+		n, from, err := announcer.connection.ReadFromUDP(buffer)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			log.Debug().Err(err).Msg("error reading discovery probe")
+			continue
+		}
+		var probe Message
+		err = json.Unmarshal(buffer[:n], &probe)
+		if err != nil || probe.Type != TypeProbe {
+			continue
+		}
+		announcement, err := json.Marshal(announcer.announce())
+		if err != nil {
+			log.Error().Err(err).Msg("error encoding discovery announcement")
+			continue
+		}
+		_, err = announcer.connection.WriteToUDP(announcement, from)
+		if err != nil {
+			log.Debug().Err(err).Str("to", from.String()).Msg("error sending discovery announcement")
+		}
+	}
+}
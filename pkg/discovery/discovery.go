@@ -0,0 +1,209 @@
+package discovery
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// PORT is the UDP port Lanty servers listen on for discovery probes.
+	PORT             = 41337
+	TIMEOUT          = 2 * time.Second
+	PROTOCOL_VERSION = 1
+
+	TypeProbe        = "lanty.discover"
+	TypeAnnouncement = "lanty.announce"
+
+	MAX_MESSAGE_SIZE = 1024
+)
+
+// Message is a discovery datagram. Clients broadcast a probe and servers answer with an announcement
+// holding their name and URL. An announcement URL without host, e.g. "http://:8080", is completed
+// with the address the announcement came from, so servers do not need to know their own address.
+type Message struct {
+	Type    string `json:"type"`
+	Version int    `json:"version"`
+	Name    string `json:"name,omitempty"`
+	URL     string `json:"url,omitempty"`
+}
+
+// Server is a discovered Lanty server, the latency is the time between probe and announcement.
+type Server struct {
+	Name    string
+	URL     string
+	Address string
+	Latency time.Duration
+}
+
+type Client struct {
+	port    int
+	timeout time.Duration
+}
+
+func NewClient(port int, timeout time.Duration) *Client {
+	return &Client{
+		port:    port,
+		timeout: timeout,
+	}
+}
+
+// Discover broadcasts a probe on all networks and collects the announcements until the timeout, a
+// done context aborts the discovery. The servers are sorted by latency.
+func (client *Client) Discover(ctx context.Context) ([]Server, error) {
+	connection, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, err
+	}
+	defer connection.Close()
+	deadline := time.Now().Add(client.timeout)
+	if ctxdeadline, ok := ctx.Deadline(); ok && ctxdeadline.Before(deadline) {
+		deadline = ctxdeadline
+	}
+	connection.SetReadDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() {
+		connection.SetReadDeadline(time.Now())
+	})
+	defer stop()
+
+	probe, err := json.Marshal(Message{Type: TypeProbe, Version: PROTOCOL_VERSION})
+	if err != nil {
+		return nil, err
+	}
+	broadcast, local := networkAddresses()
+	sent := time.Now()
+	var senderr error
+	delivered := false
+	for _, address := range broadcast {
+		_, err := connection.WriteToUDP(probe, &net.UDPAddr{IP: address, Port: client.port})
+		if err != nil {
+			log.Trace().Err(err).Str("address", address.String()).Msg("error sending discovery probe")
+			senderr = err
+			continue
+		}
+		delivered = true
+	}
+	if !delivered {
+		return nil, senderr
+	}
+
+	servers := make(map[string]Server)
+	buffer := make([]byte, MAX_MESSAGE_SIZE)
+	for {
+		n, from, err := connection.ReadFromUDP(buffer)
+		var neterr net.Error
+		if errors.As(err, &neterr) && neterr.Timeout() {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		server, err := parseAnnouncement(buffer[:n], from, time.Since(sent))
+		if err != nil {
+			log.Debug().Err(err).Str("from", from.String()).Msg("ignoring invalid discovery announcement")
+			continue
+		}
+		// A server on this machine answers the broadcast and the loopback probe, the answer from the
+		// network address is kept, as its URL works for other players as well.
+		key := server.URL
+		if from.IP.IsLoopback() || slices.ContainsFunc(local, from.IP.Equal) {
+			key = "local " + string(buffer[:n])
+		}
+		known, found := servers[key]
+		if !found || (net.ParseIP(known.Address).IsLoopback() && !from.IP.IsLoopback()) {
+			servers[key] = server
+		}
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	discovered := make([]Server, 0, len(servers))
+	for _, server := range servers {
+		discovered = append(discovered, server)
+	}
+	slices.SortFunc(discovered, func(a Server, b Server) int {
+		return cmp.Compare(a.Latency, b.Latency)
+	})
+	return discovered, nil
+}
+
+func parseAnnouncement(data []byte, from *net.UDPAddr, latency time.Duration) (Server, error) {
+	var message Message
+	err := json.Unmarshal(data, &message)
+	if err != nil {
+		return Server{}, err
+	}
+	if message.Type != TypeAnnouncement {
+		return Server{}, errors.New("unexpected message type " + strconv.Quote(message.Type))
+	}
+	serverurl, err := url.Parse(message.URL)
+	if err != nil {
+		return Server{}, err
+	}
+	if serverurl.Scheme == "" {
+		return Server{}, errors.New("announcement URL without scheme")
+	}
+	if ip := net.ParseIP(serverurl.Hostname()); serverurl.Hostname() == "" || (ip != nil && ip.IsUnspecified()) {
+		host := from.IP.String()
+		if port := serverurl.Port(); port != "" {
+			host = net.JoinHostPort(host, port)
+		}
+		serverurl.Host = host
+	}
+	name := message.Name
+	if name == "" {
+		name = serverurl.Host
+	}
+	return Server{
+		Name:    name,
+		URL:     serverurl.String(),
+		Address: from.IP.String(),
+		Latency: latency,
+	}, nil
+}
+
+// networkAddresses returns the addresses to send probes to and the IPv4 addresses of this machine.
+// Probes go to the limited broadcast address, the broadcast address of every IPv4 network and the
+// loopback address, as a server on the same machine does not receive broadcasts on every platform.
+func networkAddresses() (broadcast []net.IP, local []net.IP) {
+	broadcast = []net.IP{net.IPv4bcast, net.IPv4(127, 0, 0, 1)}
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return broadcast, local
+	}
+	for _, networkinterface := range interfaces {
+		if networkinterface.Flags&net.FlagUp == 0 || networkinterface.Flags&net.FlagBroadcast == 0 {
+			continue
+		}
+		interfaceaddresses, err := networkinterface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, address := range interfaceaddresses {
+			ipnet, ok := address.(*net.IPNet)
+			if !ok || ipnet.IP.To4() == nil {
+				continue
+			}
+			mask := ipnet.Mask
+			if len(mask) == net.IPv6len {
+				mask = mask[12:]
+			}
+			networkbroadcast := make(net.IP, net.IPv4len)
+			for i, b := range ipnet.IP.To4() {
+				networkbroadcast[i] = b | ^mask[i]
+			}
+			broadcast = append(broadcast, networkbroadcast)
+			local = append(local, ipnet.IP)
+		}
+	}
+	return broadcast, local
+}
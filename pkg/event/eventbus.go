@@ -0,0 +1,129 @@
+package event
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+type Topic string
+
+type Event[T any] struct {
+	Topic Topic
+	Data  T
+	Time  time.Time
+}
+
+type subscription struct {
+	topics  []Topic
+	dropped uint64
+}
+
+func (subscription *subscription) matches(topic Topic) bool {
+	return len(subscription.topics) == 0 || slices.Contains(subscription.topics, topic)
+}
+
+// EventBus delivers events to subscribed channels without blocking the publisher. Subscribers
+// only receive events of the topics they subscribed to, or all events if no topic was given.
+// Events are dropped for subscribers whose channel is full.
+type EventBus[T any] struct {
+	subscriber map[chan Event[T]]*subscription
+	last       map[Topic]Event[T]
+	published  atomic.Uint64
+	dropped    atomic.Uint64
+	mutex      sync.RWMutex
+}
+
+func NewEventBus[T any]() *EventBus[T] {
+	return &EventBus[T]{
+		subscriber: make(map[chan Event[T]]*subscription, 50),
+		last:       make(map[Topic]Event[T]),
+	}
+}
+
+func (bus *EventBus[T]) Subscribe(subscriber chan Event[T], topics ...Topic) {
+	defer bus.mutex.Unlock()
+	bus.mutex.Lock()
+	bus.subscriber[subscriber] = &subscription{topics: topics}
+}
+
+// SubscribeWithReplay subscribes like Subscribe and immediately delivers the last published
+// event of every matching topic, so subscribers do not miss the current state.
+func (bus *EventBus[T]) SubscribeWithReplay(subscriber chan Event[T], topics ...Topic) {
+	defer bus.mutex.Unlock()
+	bus.mutex.Lock()
+	subscription := &subscription{topics: topics}
+	bus.subscriber[subscriber] = subscription
+	replay := make([]Event[T], 0, len(bus.last))
+	for topic, event := range bus.last {
+		if subscription.matches(topic) {
+			replay = append(replay, event)
+		}
+	}
+	slices.SortFunc(replay, func(a, b Event[T]) int {
+		return a.Time.Compare(b.Time)
+	})
+	for _, event := range replay {
+		bus.deliver(subscriber, subscription, event)
+	}
+}
+
+func (bus *EventBus[T]) Unsubscribe(subscriber chan Event[T]) {
+	defer bus.mutex.Unlock()
+	bus.mutex.Lock()
+	delete(bus.subscriber, subscriber)
+}
+
+func (bus *EventBus[T]) Publish(topic Topic, data T) {
+	event := Event[T]{
+		Topic: topic,
+		Data:  data,
+		Time:  time.Now(),
+	}
+	defer bus.mutex.Unlock()
+	bus.mutex.Lock()
+	bus.published.Add(1)
+	bus.last[topic] = event
+	for subscriber, subscription := range bus.subscriber {
+		if subscription.matches(topic) {
+			bus.deliver(subscriber, subscription, event)
+		}
+	}
+}
+
+func (bus *EventBus[T]) Last(topic Topic) (event Event[T], ok bool) {
+	defer bus.mutex.RUnlock()
+	bus.mutex.RLock()
+	event, ok = bus.last[topic]
+	return
+}
+
+func (bus *EventBus[T]) Published() uint64 {
+	return bus.published.Load()
+}
+
+func (bus *EventBus[T]) Dropped() uint64 {
+	return bus.dropped.Load()
+}
+
+func (bus *EventBus[T]) DroppedFor(subscriber chan Event[T]) uint64 {
+	defer bus.mutex.RUnlock()
+	bus.mutex.RLock()
+	if subscription, ok := bus.subscriber[subscriber]; ok {
+		return subscription.dropped
+	}
+	return 0
+}
+
+func (bus *EventBus[T]) deliver(subscriber chan Event[T], subscription *subscription, event Event[T]) {
+	select {
+	case subscriber <- event:
+	default:
+		subscription.dropped++
+		bus.dropped.Add(1)
+		log.Trace().Str("topic", string(event.Topic)).Uint64("dropped", subscription.dropped).Msg("dropped event for subscriber")
+	}
+}
@@ -0,0 +1,148 @@
+package gateway
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// SECRET_SIZE is the number of random bytes of the secret, which is required in the path of requests.
+const SECRET_SIZE = 32
+
+var ErrNoServer = errors.New("no server set")
+
+// Gateway forwards requests to the Lanty server in use. The API client of go-lanty sends its
+// requests with the default HTTP transport and websocket dialer, so it talks to the gateway on a
+// loopback address and the gateway connects to the server with its own transport, e.g. one which
+// verifies the certificate and adds the event password. Other users of the default transport are
+// not affected. Requests have to start with the random secret of the gateway, so other processes on
+// the machine can not use it to send requests with the event password.
+type Gateway struct {
+	listener net.Listener
+	server   *http.Server
+	proxy    *httputil.ReverseProxy
+	handler  http.Handler
+	secret   string
+	target   *url.URL
+	mutex    sync.RWMutex
+}
+
+// New starts the gateway on a random loopback port, it forwards the requests with the transport.
+func New(transport http.RoundTripper) (*Gateway, error) {
+	secret := make([]byte, SECRET_SIZE)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	gateway := &Gateway{listener: listener, secret: hex.EncodeToString(secret)}
+	gateway.proxy = &httputil.ReverseProxy{
+		Rewrite:      gateway.rewrite,
+		Transport:    transport,
+		ErrorHandler: gateway.error,
+	}
+	gateway.handler = http.StripPrefix("/"+gateway.secret, gateway.proxy)
+	gateway.server = &http.Server{
+		Handler:           gateway,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		err := gateway.server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Msg("gateway stopped")
+		}
+	}()
+	return gateway, nil
+}
+
+// URL returns the base URL the API client has to use, it contains the secret of the gateway.
+func (gateway *Gateway) URL() string {
+	return "http://" + gateway.listener.Addr().String() + "/" + gateway.secret
+}
+
+// SetServerURL forwards the following requests to the server, the URL has to be http:// or https://.
+func (gateway *Gateway) SetServerURL(serverurl string) error {
+	target, err := url.Parse(serverurl)
+	if err != nil {
+		return err
+	}
+	if (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("unsupported server URL %s", serverurl)
+	}
+	gateway.mutex.Lock()
+	gateway.target = target
+	gateway.mutex.Unlock()
+	return nil
+}
+
+// Resolve returns the URL through the gateway for a URL of the server, e.g. of a file shared in
+// the chat. Other URLs are returned unchanged.
+func (gateway *Gateway) Resolve(u url.URL) url.URL {
+	gateway.mutex.RLock()
+	target := gateway.target
+	gateway.mutex.RUnlock()
+	if target == nil || u.Scheme != target.Scheme || u.Host != target.Host {
+		return u
+	}
+	u.Scheme = "http"
+	u.Host = gateway.listener.Addr().String()
+	u.Path = "/" + gateway.secret + u.Path
+	u.RawPath = ""
+	return u
+}
+
+// Close stops the gateway, it closes the connections of running requests.
+func (gateway *Gateway) Close() error {
+	return gateway.server.Close()
+}
+
+// CloseAfter closes the gateway once the context is done.
+func (gateway *Gateway) CloseAfter(ctx context.Context) {
+	context.AfterFunc(ctx, func() {
+		gateway.Close()
+	})
+}
+
+// ServeHTTP only accepts requests for the loopback address, so websites can not reach the server
+// through the gateway by rebinding their domain to it, and with the secret of the gateway.
+func (gateway *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	prefix := "/" + gateway.secret + "/"
+	if r.Host != gateway.listener.Addr().String() || !strings.HasPrefix(r.URL.Path, prefix) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	gateway.mutex.RLock()
+	target := gateway.target
+	gateway.mutex.RUnlock()
+	if target == nil {
+		gateway.error(w, r, ErrNoServer)
+		return
+	}
+	gateway.handler.ServeHTTP(w, r)
+}
+
+func (gateway *Gateway) rewrite(request *httputil.ProxyRequest) {
+	gateway.mutex.RLock()
+	target := gateway.target
+	gateway.mutex.RUnlock()
+	request.SetURL(target)
+}
+
+func (gateway *Gateway) error(w http.ResponseWriter, r *http.Request, err error) {
+	log.Debug().Err(err).Str("path", r.URL.Path).Msg("error forwarding request to server")
+	http.Error(w, err.Error(), http.StatusBadGateway)
+}
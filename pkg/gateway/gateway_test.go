@@ -0,0 +1,82 @@
+package gateway
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestGatewaySecret(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	}))
+	defer server.Close()
+	gateway, err := New(http.DefaultTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gateway.Close()
+	err = gateway.SetServerURL(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := strings.TrimPrefix(gateway.URL(), "http://")
+	address = address[:strings.Index(address, "/")]
+
+	tests := []struct {
+		name   string
+		url    string
+		status int
+		path   string
+	}{
+		{name: "secret", url: gateway.URL() + "/games", status: http.StatusOK, path: "/games"},
+		{name: "no secret", url: "http://" + address + "/games", status: http.StatusForbidden},
+		{name: "wrong secret", url: "http://" + address + "/" + strings.Repeat("0", 2*SECRET_SIZE) + "/games", status: http.StatusForbidden},
+		{name: "secret without path", url: gateway.URL(), status: http.StatusForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, err := http.Get(test.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer response.Body.Close()
+			var body strings.Builder
+			_, err = io.Copy(&body, response.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if response.StatusCode != test.status {
+				t.Fatalf("got status %d, want %d", response.StatusCode, test.status)
+			}
+			if test.path != "" && body.String() != test.path {
+				t.Errorf("got path %s at the server, want %s", body.String(), test.path)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	gateway, err := New(http.DefaultTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gateway.Close()
+	err = gateway.SetServerURL("http://lanty:8080")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, _ := url.Parse("http://lanty:8080/files/map.zip")
+	resolved := gateway.Resolve(*file)
+	if resolved.String() != gateway.URL()+"/files/map.zip" {
+		t.Errorf("got %s, want the file through the gateway", resolved.String())
+	}
+	other, _ := url.Parse("http://example.com/files/map.zip")
+	if resolved := gateway.Resolve(*other); resolved != *other {
+		t.Errorf("got %s, want the URL of another host unchanged", resolved.String())
+	}
+}
@@ -0,0 +1,251 @@
+package hook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/seternate/go-lanty-client/pkg/controller"
+	"github.com/seternate/go-lanty-client/pkg/event"
+	"github.com/seternate/go-lanty-client/pkg/setting"
+	"github.com/seternate/go-lanty-client/pkg/supervisor"
+	"github.com/seternate/go-lanty/pkg/chat"
+	"github.com/seternate/go-lanty/pkg/game"
+	"github.com/seternate/go-lanty/pkg/user"
+)
+
+const (
+	COMPONENT_NAME  = "hook"
+	DEFAULT_TIMEOUT = 30 * time.Second
+
+	EventDownloadCompleted  = "download.completed"
+	EventDownloadFailed     = "download.failed"
+	EventGameStarted        = "game.started"
+	EventGameExited         = "game.exited"
+	EventServerStarted      = "server.started"
+	EventUserJoined         = "user.joined"
+	EventUserLeft           = "user.left"
+	EventChatMessage        = "chat.message"
+	EventConnectionLost     = "connection.lost"
+	EventConnectionRestored = "connection.restored"
+)
+
+var Dependencies = []string{
+	controller.ComponentSettings,
+	controller.ComponentConnection,
+	controller.ComponentGame,
+	controller.ComponentDownload,
+	controller.ComponentUser,
+	controller.ComponentChat,
+}
+
+type Event struct {
+	Event   string     `json:"event"`
+	Time    time.Time  `json:"time"`
+	Game    *gameData  `json:"game,omitempty"`
+	User    *user.User `json:"user,omitempty"`
+	Message string     `json:"message,omitempty"`
+	Error   string     `json:"error,omitempty"`
+}
+
+type gameData struct {
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+// Runner runs the hooks of the settings on client events.
+type Runner struct {
+	controller *controller.Controller
+	cancel     context.CancelFunc
+	done       <-chan struct{}
+	mutex      sync.Mutex
+}
+
+func NewRunner(controller *controller.Controller) *Runner {
+	return &Runner{
+		controller: controller,
+	}
+}
+
+func (runner *Runner) Start(ctx context.Context) error {
+	defer runner.mutex.Unlock()
+	runner.mutex.Lock()
+	ctx, runner.cancel = context.WithCancel(ctx)
+	runner.done = runner.controller.Go("hook.Runner.run", func() {
+		runner.run(ctx)
+	})
+	return nil
+}
+
+func (runner *Runner) Stop() {
+	defer runner.mutex.Unlock()
+	runner.mutex.Lock()
+	if runner.cancel == nil {
+		return
+	}
+	runner.cancel()
+	<-runner.done
+	runner.cancel = nil
+}
+
+func (runner *Runner) run(ctx context.Context) {
+	connectionchanged := make(chan event.Event[controller.ConnectionStatus], 10)
+	gamechanged := make(chan event.Event[game.Game], 50)
+	downloadqueued := make(chan event.Event[*controller.Download], 50)
+	downloadchanged := make(chan event.Event[controller.DownloadEvent], 200)
+	userchanged := make(chan event.Event[user.User], 50)
+	messagereceived := make(chan event.Event[chat.Message], 50)
+	runner.controller.Connection.Subscribe(connectionchanged)
+	defer runner.controller.Connection.Unsubscribe(connectionchanged)
+	runner.controller.Game.Subscribe(gamechanged, controller.TopicGameStarted, controller.TopicGameExited, controller.TopicServerStarted)
+	defer runner.controller.Game.Unsubscribe(gamechanged)
+	runner.controller.Download.Subscribe(downloadqueued)
+	defer runner.controller.Download.Unsubscribe(downloadqueued)
+	runner.controller.User.Subscribe(userchanged, controller.TopicUserJoined, controller.TopicUserLeft)
+	defer runner.controller.User.Unsubscribe(userchanged)
+	runner.controller.Chat.Subscribe(messagereceived)
+	defer runner.controller.Chat.Unsubscribe(messagereceived)
+
+	// downloads holds the downloads which are watched until they finished or failed.
+	downloads := make(map[*controller.Download]struct{})
+	defer func() {
+		for download := range downloads {
+			download.Unsubscribe(downloadchanged)
+		}
+	}()
+	connected := runner.controller.Connection.GetStatus().IsConnected()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Trace().Err(ctx.Err()).Msg("exiting hook runner run()")
+			return
+		case e := <-connectionchanged:
+			if e.Data.IsConnected() && !connected {
+				runner.Fire(Event{Event: EventConnectionRestored})
+			} else if !e.Data.IsConnected() && e.Data != controller.Connecting && connected {
+				runner.Fire(Event{Event: EventConnectionLost})
+			}
+			// Connecting after the server changed is neither lost nor restored.
+			if e.Data != controller.Connecting {
+				connected = e.Data.IsConnected()
+			}
+		case e := <-gamechanged:
+			runner.Fire(Event{Event: string(e.Topic), Game: newGameData(e.Data)})
+		case e := <-downloadqueued:
+			downloads[e.Data] = struct{}{}
+			e.Data.Subscribe(downloadchanged)
+		case e := <-downloadchanged:
+			download := e.Data.Download
+			if _, found := downloads[download]; !found {
+				continue
+			}
+			// Errors of attempts which are retried are not final, only a finished or stopped download is.
+			if !download.IsComplete() && !download.IsStopped() {
+				continue
+			}
+			switch {
+			case download.Err() != nil && !errors.Is(download.Err(), context.Canceled):
+				runner.Fire(Event{Event: EventDownloadFailed, Game: newGameData(download.Game()), Error: download.Err().Error()})
+			case download.IsComplete():
+				runner.Fire(Event{Event: EventDownloadCompleted, Game: newGameData(download.Game())})
+			}
+			delete(downloads, download)
+			download.Unsubscribe(downloadchanged)
+		case e := <-userchanged:
+			u := e.Data
+			runner.Fire(Event{Event: string(e.Topic), User: &u})
+		case e := <-messagereceived:
+			u := e.Data.GetUser()
+			runner.Fire(Event{Event: EventChatMessage, User: &u, Message: e.Data.GetMessage()})
+		}
+	}
+}
+
+// Fire runs all hooks of the event in the background.
+func (runner *Runner) Fire(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	for _, hook := range runner.controller.Settings.Settings().Hooks {
+		if hook.Event != e.Event {
+			continue
+		}
+		hook := hook
+		runner.controller.Go("hook.Runner.execute", func() {
+			runner.execute(hook, e)
+		}, supervisor.WithField("event", e.Event), supervisor.WithField("command", hook.Command), supervisor.WithoutRestart())
+	}
+}
+
+func (runner *Runner) execute(hook setting.Hook, e Event) {
+	timeout := hook.Timeout
+	if timeout <= 0 {
+		timeout = DEFAULT_TIMEOUT
+	}
+	ctx, cancel := context.WithTimeout(runner.controller.Context(), timeout)
+	defer cancel()
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		log.Error().Err(err).Str("event", e.Event).Msg("error encoding hook event")
+		return
+	}
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, hook.Command, hook.Args...)
+	cmd.Env = append(os.Environ(), e.environment()...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	cmd.WaitDelay = time.Second
+	start := time.Now()
+	err = cmd.Run()
+	logger := log.With().
+		Str("event", e.Event).
+		Str("command", hook.Command).
+		Dur("duration", time.Since(start)).
+		Str("output", strings.TrimSpace(output.String())).
+		Logger()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		logger.Error().Dur("timeout", timeout).Msg("hook timed out")
+		return
+	}
+	if err != nil {
+		logger.Error().Err(err).Msg("hook failed")
+		return
+	}
+	logger.Info().Msg("hook finished")
+}
+
+func (e Event) environment() []string {
+	env := []string{
+		"LANTY_HOOK_EVENT=" + e.Event,
+		"LANTY_HOOK_TIME=" + e.Time.Format(time.RFC3339),
+	}
+	if e.Game != nil {
+		env = append(env, "LANTY_HOOK_GAME_SLUG="+e.Game.Slug, "LANTY_HOOK_GAME_NAME="+e.Game.Name)
+	}
+	if e.User != nil {
+		env = append(env, "LANTY_HOOK_USER_NAME="+e.User.Name, "LANTY_HOOK_USER_IP="+e.User.IP)
+	}
+	if e.Message != "" {
+		env = append(env, "LANTY_HOOK_MESSAGE="+e.Message)
+	}
+	if e.Error != "" {
+		env = append(env, "LANTY_HOOK_ERROR="+e.Error)
+	}
+	return env
+}
+
+func newGameData(game game.Game) *gameData {
+	return &gameData{
+		Slug: game.Slug,
+		Name: game.Name,
+	}
+}
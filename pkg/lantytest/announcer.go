@@ -0,0 +1,20 @@
+package lantytest
+
+import (
+	"time"
+
+	"github.com/seternate/go-lanty-client/pkg/discovery"
+)
+
+// Announce answers discovery probes on the address, e.g. "127.0.0.1:0", with the name and URL of the
+// server. The latency of the server delays the announcements as well. The announcer has to be
+// closed by the caller.
+func (server *Server) Announce(address string, name string) (*discovery.Announcer, error) {
+	return discovery.NewAnnouncer(address, func() discovery.Message {
+		server.mutex.RLock()
+		latency := server.latency
+		server.mutex.RUnlock()
+		time.Sleep(latency)
+		return discovery.NewAnnouncement(name, server.URL)
+	})
+}
@@ -0,0 +1,514 @@
+package lantytest
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/seternate/go-lanty/pkg/game"
+	"github.com/seternate/go-lanty/pkg/user"
+)
+
+const (
+	RouteHealth   = "/health"
+	RouteGames    = "/games"
+	RouteGame     = "/games/{slug}"
+	RouteIcon     = "/games/{slug}/icon"
+	RouteDownload = "/games/{slug}/download"
+	RouteUsers    = "/users"
+	RouteUser     = "/users/{ip}"
+	RouteFiles    = "/files"
+	RouteFile     = "/files/{name}"
+	RouteChat     = "/chat"
+)
+
+type fault struct {
+	status int
+	count  int
+}
+
+// Server is an in-process stand-in for the Lanty server. Games, users and files are kept in memory
+// and faults like latency, error responses and dropped chat connections can be injected at runtime.
+type Server struct {
+	*httptest.Server
+
+	games      []game.Game
+	icons      map[string][]byte
+	archives   map[string][]byte
+	users      map[string]user.User
+	files      map[string][]byte
+	messages   []json.RawMessage
+	chats      map[*websocket.Conn]struct{}
+	latency    time.Duration
+	faults     map[string]*fault
+	requests   map[string]int
+	password   string
+	userIP     string
+	upgrader   websocket.Upgrader
+	mutex      sync.RWMutex
+	chatsMutex sync.Mutex
+}
+
+func NewServer() *Server {
+	server := newServer()
+	server.Server = httptest.NewServer(server.router())
+	return server
+}
+
+// NewTLSServer starts the server with a self-signed certificate, its Certificate() is trusted by
+// its Client().
+func NewTLSServer() *Server {
+	server := newServer()
+	server.Server = httptest.NewTLSServer(server.router())
+	return server
+}
+
+func newServer() *Server {
+	return &Server{
+		games:    make([]game.Game, 0),
+		icons:    make(map[string][]byte),
+		archives: make(map[string][]byte),
+		users:    make(map[string]user.User),
+		files:    make(map[string][]byte),
+		messages: make([]json.RawMessage, 0),
+		chats:    make(map[*websocket.Conn]struct{}),
+		faults:   make(map[string]*fault),
+		requests: make(map[string]int),
+	}
+}
+
+func (server *Server) router() *mux.Router {
+	router := mux.NewRouter()
+	router.Use(server.middleware)
+	router.HandleFunc(RouteHealth, server.health).Methods(http.MethodGet)
+	router.HandleFunc(RouteGames, server.getGames).Methods(http.MethodGet)
+	router.HandleFunc(RouteGame, server.getGame).Methods(http.MethodGet)
+	router.HandleFunc(RouteIcon, server.getIcon).Methods(http.MethodGet)
+	router.HandleFunc(RouteDownload, server.download).Methods(http.MethodGet)
+	router.HandleFunc(RouteUsers, server.getUsers).Methods(http.MethodGet)
+	router.HandleFunc(RouteUsers, server.createUser).Methods(http.MethodPost)
+	router.HandleFunc(RouteUser, server.getUser).Methods(http.MethodGet)
+	router.HandleFunc(RouteUser, server.updateUser).Methods(http.MethodPatch, http.MethodPut)
+	router.HandleFunc(RouteFiles, server.uploadFile).Methods(http.MethodPost)
+	router.HandleFunc(RouteFile, server.getFile).Methods(http.MethodGet)
+	router.HandleFunc(RouteChat, server.chat)
+	return router
+}
+
+func (server *Server) Close() {
+	server.DropChats()
+	server.Server.Close()
+}
+
+func (server *Server) AddGame(g game.Game, icon image.Image, files map[string][]byte) error {
+	var iconData bytes.Buffer
+	if icon != nil {
+		err := png.Encode(&iconData, icon)
+		if err != nil {
+			return err
+		}
+	}
+	archive, err := zipFiles(files)
+	if err != nil {
+		return err
+	}
+
+	defer server.mutex.Unlock()
+	server.mutex.Lock()
+	index := slices.IndexFunc(server.games, func(existing game.Game) bool { return existing.Slug == g.Slug })
+	if index >= 0 {
+		server.games[index] = g
+	} else {
+		server.games = append(server.games, g)
+	}
+	server.icons[g.Slug] = iconData.Bytes()
+	server.archives[g.Slug] = archive
+	return nil
+}
+
+func (server *Server) RemoveGame(slug string) {
+	defer server.mutex.Unlock()
+	server.mutex.Lock()
+	server.games = slices.DeleteFunc(server.games, func(g game.Game) bool { return g.Slug == slug })
+	delete(server.icons, slug)
+	delete(server.archives, slug)
+}
+
+func (server *Server) AddUser(user user.User) {
+	defer server.mutex.Unlock()
+	server.mutex.Lock()
+	server.users[user.IP] = user
+}
+
+func (server *Server) RemoveUser(ip string) {
+	defer server.mutex.Unlock()
+	server.mutex.Lock()
+	delete(server.users, ip)
+}
+
+func (server *Server) Users() []user.User {
+	defer server.mutex.RUnlock()
+	server.mutex.RLock()
+	users := make([]user.User, 0, len(server.users))
+	for _, user := range server.users {
+		users = append(users, user)
+	}
+	slices.SortFunc(users, func(a, b user.User) int { return strings.Compare(a.IP, b.IP) })
+	return users
+}
+
+// SetUserIP overrides the IP the server assigns to created users, which otherwise is the remote address.
+func (server *Server) SetUserIP(ip string) {
+	defer server.mutex.Unlock()
+	server.mutex.Lock()
+	server.userIP = ip
+}
+
+func (server *Server) AddFile(name string, data []byte) string {
+	defer server.mutex.Unlock()
+	server.mutex.Lock()
+	server.files[name] = data
+	return server.URL + strings.Replace(RouteFile, "{name}", name, 1)
+}
+
+func (server *Server) File(name string) ([]byte, bool) {
+	defer server.mutex.RUnlock()
+	server.mutex.RLock()
+	data, found := server.files[name]
+	return data, found
+}
+
+// Messages returns all chat messages received from clients as raw JSON.
+func (server *Server) Messages() []json.RawMessage {
+	defer server.mutex.RUnlock()
+	server.mutex.RLock()
+	return slices.Clone(server.messages)
+}
+
+// Broadcast sends the message as JSON to all connected chat clients.
+func (server *Server) Broadcast(message any) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	server.broadcast(data)
+	return nil
+}
+
+func (server *Server) ChatConnections() int {
+	defer server.chatsMutex.Unlock()
+	server.chatsMutex.Lock()
+	return len(server.chats)
+}
+
+// DropChats closes all chat websocket connections without a close handshake.
+func (server *Server) DropChats() {
+	defer server.chatsMutex.Unlock()
+	server.chatsMutex.Lock()
+	for conn := range server.chats {
+		conn.NetConn().Close()
+		delete(server.chats, conn)
+	}
+}
+
+func (server *Server) SetLatency(latency time.Duration) {
+	defer server.mutex.Unlock()
+	server.mutex.Lock()
+	server.latency = latency
+}
+
+// Fail answers the next count requests of the route with the given status code. A negative count
+// fails all requests until ClearFaults is called.
+func (server *Server) Fail(route string, status int, count int) {
+	defer server.mutex.Unlock()
+	server.mutex.Lock()
+	server.faults[route] = &fault{status: status, count: count}
+}
+
+func (server *Server) ClearFaults() {
+	defer server.mutex.Unlock()
+	server.mutex.Lock()
+	server.faults = make(map[string]*fault)
+	server.latency = 0
+}
+
+// SetPassword makes the server reject requests without the event password, except health checks.
+// An empty password accepts all requests.
+func (server *Server) SetPassword(password string) {
+	defer server.mutex.Unlock()
+	server.mutex.Lock()
+	server.password = password
+}
+
+// authorized returns true if the request has the password as bearer token.
+func (server *Server) authorized(r *http.Request, password string) bool {
+	return password == "" || r.Header.Get("Authorization") == "Bearer "+password
+}
+
+// Requests returns how many requests of the route were received, including failed ones.
+func (server *Server) Requests(route string) int {
+	defer server.mutex.RUnlock()
+	server.mutex.RLock()
+	return server.requests[route]
+}
+
+func (server *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, err := mux.CurrentRoute(r).GetPathTemplate()
+		if err != nil {
+			route = r.URL.Path
+		}
+
+		server.mutex.Lock()
+		server.requests[route]++
+		latency := server.latency
+		password := server.password
+		status := 0
+		if fault, found := server.faults[route]; found && fault.count != 0 {
+			status = fault.status
+			if fault.count > 0 {
+				fault.count--
+			}
+		}
+		server.mutex.Unlock()
+
+		if latency > 0 {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(latency):
+			}
+		}
+		if status != 0 {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		if route != RouteHealth && !server.authorized(r, password) {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (server *Server) health(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func (server *Server) getGames(w http.ResponseWriter, r *http.Request) {
+	server.mutex.RLock()
+	slugs := make([]string, 0, len(server.games))
+	for _, game := range server.games {
+		slugs = append(slugs, game.Slug)
+	}
+	server.mutex.RUnlock()
+	writeJSON(w, http.StatusOK, slugs)
+}
+
+func (server *Server) getGame(w http.ResponseWriter, r *http.Request) {
+	game, found := server.game(mux.Vars(r)["slug"])
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, http.StatusOK, game)
+}
+
+func (server *Server) getIcon(w http.ResponseWriter, r *http.Request) {
+	server.mutex.RLock()
+	icon, found := server.icons[mux.Vars(r)["slug"]]
+	server.mutex.RUnlock()
+	if !found || len(icon) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(icon)
+}
+
+func (server *Server) download(w http.ResponseWriter, r *http.Request) {
+	slug := mux.Vars(r)["slug"]
+	server.mutex.RLock()
+	archive, found := server.archives[slug]
+	server.mutex.RUnlock()
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.zip", slug))
+	http.ServeContent(w, r, slug+".zip", time.Time{}, bytes.NewReader(archive))
+}
+
+func (server *Server) getUsers(w http.ResponseWriter, r *http.Request) {
+	ips := make([]string, 0)
+	for _, user := range server.Users() {
+		ips = append(ips, user.IP)
+	}
+	writeJSON(w, http.StatusOK, ips)
+}
+
+func (server *Server) getUser(w http.ResponseWriter, r *http.Request) {
+	server.mutex.RLock()
+	user, found := server.users[mux.Vars(r)["ip"]]
+	server.mutex.RUnlock()
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, http.StatusOK, user)
+}
+
+func (server *Server) createUser(w http.ResponseWriter, r *http.Request) {
+	var user user.User
+	err := json.NewDecoder(r.Body).Decode(&user)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	server.mutex.Lock()
+	user.IP = server.userIP
+	server.mutex.Unlock()
+	if user.IP == "" {
+		user.IP, _, _ = net.SplitHostPort(r.RemoteAddr)
+	}
+	server.AddUser(user)
+	writeJSON(w, http.StatusCreated, user)
+}
+
+func (server *Server) updateUser(w http.ResponseWriter, r *http.Request) {
+	var user user.User
+	err := json.NewDecoder(r.Body).Decode(&user)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	user.IP = mux.Vars(r)["ip"]
+	server.mutex.RLock()
+	_, found := server.users[user.IP]
+	server.mutex.RUnlock()
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+	server.AddUser(user)
+	writeJSON(w, http.StatusOK, user)
+}
+
+func (server *Server) uploadFile(w http.ResponseWriter, r *http.Request) {
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	name := path.Base(header.Filename)
+	url := server.AddFile(name, data)
+	writeJSON(w, http.StatusCreated, map[string]string{"name": name, "url": url})
+}
+
+func (server *Server) getFile(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	data, found := server.File(name)
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", name))
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(data))
+}
+
+func (server *Server) chat(w http.ResponseWriter, r *http.Request) {
+	conn, err := server.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	server.chatsMutex.Lock()
+	server.chats[conn] = struct{}{}
+	server.chatsMutex.Unlock()
+	defer func() {
+		server.chatsMutex.Lock()
+		delete(server.chats, conn)
+		server.chatsMutex.Unlock()
+		conn.Close()
+	}()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		server.mutex.Lock()
+		server.messages = append(server.messages, json.RawMessage(data))
+		server.mutex.Unlock()
+		server.broadcast(data)
+	}
+}
+
+func (server *Server) broadcast(data []byte) {
+	defer server.chatsMutex.Unlock()
+	server.chatsMutex.Lock()
+	for conn := range server.chats {
+		err := conn.WriteMessage(websocket.TextMessage, data)
+		if err != nil {
+			conn.NetConn().Close()
+			delete(server.chats, conn)
+		}
+	}
+}
+
+func (server *Server) game(slug string) (game.Game, bool) {
+	defer server.mutex.RUnlock()
+	server.mutex.RLock()
+	index := slices.IndexFunc(server.games, func(g game.Game) bool { return g.Slug == slug })
+	if index < 0 {
+		return game.Game{}, false
+	}
+	return server.games[index], true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func zipFiles(files map[string][]byte) ([]byte, error) {
+	var archive bytes.Buffer
+	writer := zip.NewWriter(&archive)
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		file, err := writer.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		_, err = file.Write(files[name])
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := writer.Close()
+	return archive.Bytes(), err
+}
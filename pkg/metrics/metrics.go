@@ -0,0 +1,250 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type metricType string
+
+const (
+	typeCounter   metricType = "counter"
+	typeGauge     metricType = "gauge"
+	typeHistogram metricType = "histogram"
+)
+
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry holds metric families and writes them in the Prometheus text exposition format.
+type Registry struct {
+	families []*family
+	mutex    sync.RWMutex
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		families: make([]*family, 0),
+	}
+}
+
+func (registry *Registry) NewCounter(name string, help string, labels ...string) *Counter {
+	return &Counter{family: registry.register(name, help, typeCounter, nil, labels)}
+}
+
+func (registry *Registry) NewGauge(name string, help string, labels ...string) *Gauge {
+	return &Gauge{family: registry.register(name, help, typeGauge, nil, labels)}
+}
+
+func (registry *Registry) NewHistogram(name string, help string, buckets []float64, labels ...string) *Histogram {
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+	return &Histogram{family: registry.register(name, help, typeHistogram, buckets, labels)}
+}
+
+func (registry *Registry) register(name string, help string, metricType metricType, buckets []float64, labels []string) *family {
+	defer registry.mutex.Unlock()
+	registry.mutex.Lock()
+	for _, family := range registry.families {
+		if family.name == name {
+			panic(fmt.Sprintf("metric %s already registered", name))
+		}
+	}
+	family := &family{
+		name:       name,
+		help:       help,
+		metricType: metricType,
+		labels:     labels,
+		buckets:    buckets,
+		series:     make(map[string]*series),
+	}
+	registry.families = append(registry.families, family)
+	return family
+}
+
+func (registry *Registry) WriteTo(w io.Writer) (int64, error) {
+	registry.mutex.RLock()
+	families := slices.Clone(registry.families)
+	registry.mutex.RUnlock()
+	writer := &countingWriter{writer: bufio.NewWriter(w)}
+	for _, family := range families {
+		family.write(writer)
+	}
+	if writer.err != nil {
+		return writer.count, writer.err
+	}
+	return writer.count, writer.writer.Flush()
+}
+
+func (registry *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		registry.WriteTo(w)
+	})
+}
+
+type Counter struct {
+	family *family
+}
+
+func (counter *Counter) Inc(labelValues ...string) {
+	counter.Add(1, labelValues...)
+}
+
+// Add increases the counter, negative values are ignored as counters only go up.
+func (counter *Counter) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		return
+	}
+	counter.family.update(labelValues, func(series *series) {
+		series.value += value
+	})
+}
+
+type Gauge struct {
+	family *family
+}
+
+func (gauge *Gauge) Set(value float64, labelValues ...string) {
+	gauge.family.update(labelValues, func(series *series) {
+		series.value = value
+	})
+}
+
+func (gauge *Gauge) Add(value float64, labelValues ...string) {
+	gauge.family.update(labelValues, func(series *series) {
+		series.value += value
+	})
+}
+
+type Histogram struct {
+	family *family
+}
+
+func (histogram *Histogram) Observe(value float64, labelValues ...string) {
+	histogram.family.update(labelValues, func(series *series) {
+		for i, bucket := range histogram.family.buckets {
+			if value <= bucket {
+				series.buckets[i]++
+			}
+		}
+		series.count++
+		series.value += value
+	})
+}
+
+type family struct {
+	name       string
+	help       string
+	metricType metricType
+	labels     []string
+	buckets    []float64
+	series     map[string]*series
+	mutex      sync.Mutex
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+	count       uint64
+	buckets     []uint64
+}
+
+func (family *family) update(labelValues []string, update func(series *series)) {
+	if len(labelValues) != len(family.labels) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", family.name, len(family.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	defer family.mutex.Unlock()
+	family.mutex.Lock()
+	s, found := family.series[key]
+	if !found {
+		s = &series{
+			labelValues: slices.Clone(labelValues),
+			buckets:     make([]uint64, len(family.buckets)),
+		}
+		family.series[key] = s
+	}
+	update(s)
+}
+
+func (family *family) write(w io.Writer) {
+	defer family.mutex.Unlock()
+	family.mutex.Lock()
+	fmt.Fprintf(w, "# HELP %s %s\n", family.name, escapeHelp(family.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", family.name, family.metricType)
+	keys := make([]string, 0, len(family.series))
+	for key := range family.series {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		s := family.series[key]
+		if family.metricType != typeHistogram {
+			fmt.Fprintf(w, "%s%s %s\n", family.name, family.labelString(s.labelValues, ""), formatFloat(s.value))
+			continue
+		}
+		for i, bucket := range family.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", family.name, family.labelString(s.labelValues, formatFloat(bucket)), s.buckets[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", family.name, family.labelString(s.labelValues, "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", family.name, family.labelString(s.labelValues, ""), formatFloat(s.value))
+		fmt.Fprintf(w, "%s_count%s %d\n", family.name, family.labelString(s.labelValues, ""), s.count)
+	}
+}
+
+func (family *family) labelString(labelValues []string, le string) string {
+	pairs := make([]string, 0, len(labelValues)+1)
+	for i, value := range labelValues {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", family.labels[i], escapeLabel(value)))
+	}
+	if le != "" {
+		pairs = append(pairs, fmt.Sprintf("le=\"%s\"", le))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(value)
+}
+
+type countingWriter struct {
+	writer *bufio.Writer
+	count  int64
+	err    error
+}
+
+func (writer *countingWriter) Write(p []byte) (int, error) {
+	if writer.err != nil {
+		return 0, writer.err
+	}
+	n, err := writer.writer.Write(p)
+	writer.count += int64(n)
+	writer.err = err
+	return n, err
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const COMPONENT_NAME = "metrics"
+
+// Server serves the registry on /metrics, it implements the controller component interface.
+type Server struct {
+	registry *Registry
+	address  string
+	server   *http.Server
+	waitgrp  sync.WaitGroup
+}
+
+func NewServer(registry *Registry, address string) *Server {
+	return &Server{
+		registry: registry,
+		address:  address,
+	}
+}
+
+func (server *Server) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", server.address)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", server.registry.Handler())
+	server.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	server.waitgrp.Add(1)
+	go func() {
+		defer server.waitgrp.Done()
+		err := server.server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Msg("metrics server stopped unexpectedly")
+		}
+	}()
+	log.Info().Str("address", listener.Addr().String()).Msg("serving metrics")
+	return nil
+}
+
+func (server *Server) Stop() {
+	if server.server == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := server.server.Shutdown(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("error shutting down metrics server")
+	}
+	server.waitgrp.Wait()
+	server.server = nil
+}
@@ -0,0 +1,156 @@
+package retry
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Policy configures the backoff between attempts of an operation talking to the server.
+type Policy struct {
+	// Initial is the delay after the first failure, which is multiplied with every further failure
+	// up to Max.
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+	// Jitter randomizes every delay by up to this fraction, e.g. 0.5 waits between 50% and 150%,
+	// so the clients of a restarted server do not retry all at once.
+	Jitter float64
+	// MaxAttempts stops retrying after this many failures in a row, 0 retries forever.
+	MaxAttempts int
+	// After BreakerThreshold failures in a row the circuit opens and no attempt is made for
+	// BreakerCooldown. Then a single attempt decides if the circuit closes again. 0 disables it.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		Initial:          time.Second,
+		Max:              30 * time.Second,
+		Multiplier:       2,
+		Jitter:           0.5,
+		BreakerThreshold: 10,
+		BreakerCooldown:  time.Minute,
+	}
+}
+
+// WithMaxAttempts returns the policy which gives up after the number of failures in a row.
+func (policy Policy) WithMaxAttempts(attempts int) Policy {
+	policy.MaxAttempts = attempts
+	return policy
+}
+
+type State int
+
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+)
+
+func (state State) String() string {
+	switch state {
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// Backoff tracks the failures in a row of one operation and when it may be attempted next. It is
+// safe for concurrent use.
+type Backoff struct {
+	policy   Policy
+	failures int
+	next     time.Time
+	open     bool
+	random   *rand.Rand
+	mutex    sync.Mutex
+}
+
+func NewBackoff(policy Policy) *Backoff {
+	return &Backoff{
+		policy: policy,
+		random: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Ready returns true if the operation may be attempted now.
+func (backoff *Backoff) Ready() bool {
+	return backoff.Wait() == 0 && !backoff.Exhausted()
+}
+
+// Wait returns how long to wait until the next attempt.
+func (backoff *Backoff) Wait() time.Duration {
+	defer backoff.mutex.Unlock()
+	backoff.mutex.Lock()
+	return max(time.Until(backoff.next), 0)
+}
+
+// Failure records a failed attempt and returns the delay until the next attempt.
+func (backoff *Backoff) Failure() time.Duration {
+	defer backoff.mutex.Unlock()
+	backoff.mutex.Lock()
+	backoff.failures++
+	delay := backoff.delay()
+	if backoff.policy.BreakerThreshold > 0 && backoff.failures >= backoff.policy.BreakerThreshold {
+		backoff.open = true
+		delay = backoff.jitter(backoff.policy.BreakerCooldown)
+	}
+	backoff.next = time.Now().Add(delay)
+	return delay
+}
+
+// Success resets the backoff and closes the circuit.
+func (backoff *Backoff) Success() {
+	defer backoff.mutex.Unlock()
+	backoff.mutex.Lock()
+	backoff.failures = 0
+	backoff.open = false
+	backoff.next = time.Time{}
+}
+
+// Failures returns the number of failures in a row.
+func (backoff *Backoff) Failures() int {
+	defer backoff.mutex.Unlock()
+	backoff.mutex.Lock()
+	return backoff.failures
+}
+
+// Exhausted returns true if the maximum attempts failed.
+func (backoff *Backoff) Exhausted() bool {
+	defer backoff.mutex.Unlock()
+	backoff.mutex.Lock()
+	return backoff.policy.MaxAttempts > 0 && backoff.failures >= backoff.policy.MaxAttempts
+}
+
+func (backoff *Backoff) State() State {
+	defer backoff.mutex.Unlock()
+	backoff.mutex.Lock()
+	if !backoff.open {
+		return Closed
+	}
+	if time.Now().Before(backoff.next) {
+		return Open
+	}
+	return HalfOpen
+}
+
+func (backoff *Backoff) delay() time.Duration {
+	delay := float64(backoff.policy.Initial) * math.Pow(backoff.policy.Multiplier, float64(backoff.failures-1))
+	if backoff.policy.Max > 0 && delay > float64(backoff.policy.Max) {
+		delay = float64(backoff.policy.Max)
+	}
+	return backoff.jitter(time.Duration(delay))
+}
+
+func (backoff *Backoff) jitter(delay time.Duration) time.Duration {
+	if backoff.policy.Jitter <= 0 {
+		return delay
+	}
+	factor := 1 + backoff.policy.Jitter*(2*backoff.random.Float64()-1)
+	return time.Duration(float64(delay) * factor)
+}
@@ -0,0 +1,148 @@
+package retry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFailureDelay(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		want   []time.Duration
+	}{
+		{
+			name:   "growth",
+			policy: Policy{Initial: time.Second, Max: time.Minute, Multiplier: 2},
+			want:   []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second},
+		},
+		{
+			name:   "max",
+			policy: Policy{Initial: time.Second, Max: 5 * time.Second, Multiplier: 3},
+			want:   []time.Duration{time.Second, 3 * time.Second, 5 * time.Second, 5 * time.Second},
+		},
+		{
+			name:   "no max",
+			policy: Policy{Initial: time.Second, Multiplier: 10},
+			want:   []time.Duration{time.Second, 10 * time.Second, 100 * time.Second},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			backoff := NewBackoff(test.policy)
+			for index, want := range test.want {
+				delay := backoff.Failure()
+				if delay != want {
+					t.Errorf("got delay %v after %d failures, want %v", delay, index+1, want)
+				}
+			}
+			if backoff.Failures() != len(test.want) {
+				t.Errorf("got %d failures, want %d", backoff.Failures(), len(test.want))
+			}
+			if backoff.Ready() {
+				t.Error("got ready backoff before the delay passed")
+			}
+
+			backoff.Success()
+			if !backoff.Ready() || backoff.Failures() != 0 {
+				t.Errorf("got ready %v with %d failures after a success, want a reset backoff", backoff.Ready(), backoff.Failures())
+			}
+			if delay := backoff.Failure(); delay != test.want[0] {
+				t.Errorf("got delay %v after a success, want %v", delay, test.want[0])
+			}
+		})
+	}
+}
+
+func TestJitter(t *testing.T) {
+	policy := Policy{Initial: time.Second, Max: 4 * time.Second, Multiplier: 2, Jitter: 0.5}
+	backoff := NewBackoff(policy)
+	spread := false
+	for index := 0; index < 100; index++ {
+		// The delay is capped at Max before the jitter is applied.
+		base := min(policy.Initial<<min(index, 2), policy.Max)
+		delay := backoff.Failure()
+		if delay < base/2 || delay > base*3/2 {
+			t.Fatalf("got delay %v after %d failures, want between %v and %v", delay, index+1, base/2, base*3/2)
+		}
+		spread = spread || delay != base
+	}
+	if !spread {
+		t.Error("got no randomized delay")
+	}
+}
+
+func TestMaxAttempts(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int
+		failures int
+		want     bool
+	}{
+		{name: "no failures", attempts: 3, failures: 0, want: false},
+		{name: "below", attempts: 3, failures: 2, want: false},
+		{name: "reached", attempts: 3, failures: 3, want: true},
+		{name: "forever", attempts: 0, failures: 100, want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			backoff := NewBackoff(Policy{Multiplier: 2}.WithMaxAttempts(test.attempts))
+			for index := 0; index < test.failures; index++ {
+				backoff.Failure()
+			}
+			if backoff.Exhausted() != test.want {
+				t.Errorf("got exhausted %v after %d of %d attempts, want %v", backoff.Exhausted(), test.failures, test.attempts, test.want)
+			}
+			// Without a delay only an exhausted backoff is not ready.
+			if backoff.Ready() == test.want {
+				t.Errorf("got ready %v for exhausted %v", backoff.Ready(), test.want)
+			}
+
+			backoff.Success()
+			if backoff.Exhausted() {
+				t.Error("got exhausted backoff after a success")
+			}
+		})
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	cooldown := 100 * time.Millisecond
+	backoff := NewBackoff(Policy{Multiplier: 1, BreakerThreshold: 3, BreakerCooldown: cooldown})
+
+	for index := 1; index < 3; index++ {
+		backoff.Failure()
+		if backoff.State() != Closed {
+			t.Fatalf("got state %v after %d failures, want %v", backoff.State(), index, Closed)
+		}
+	}
+	delay := backoff.Failure()
+	if delay != cooldown || backoff.State() != Open {
+		t.Fatalf("got state %v with delay %v at the threshold, want %v with %v", backoff.State(), delay, Open, cooldown)
+	}
+	if backoff.Ready() {
+		t.Error("got ready backoff with an open circuit")
+	}
+
+	time.Sleep(cooldown)
+	if backoff.State() != HalfOpen || !backoff.Ready() {
+		t.Fatalf("got state %v, ready %v after the cooldown, want %v and ready", backoff.State(), backoff.Ready(), HalfOpen)
+	}
+
+	// A failed attempt while half-open opens the circuit again.
+	backoff.Failure()
+	if backoff.State() != Open {
+		t.Fatalf("got state %v after a half-open failure, want %v", backoff.State(), Open)
+	}
+	time.Sleep(cooldown)
+	if backoff.State() != HalfOpen {
+		t.Fatalf("got state %v after the cooldown, want %v", backoff.State(), HalfOpen)
+	}
+
+	backoff.Success()
+	if backoff.State() != Closed || !backoff.Ready() {
+		t.Errorf("got state %v, ready %v after a success, want %v and ready", backoff.State(), backoff.Ready(), Closed)
+	}
+}
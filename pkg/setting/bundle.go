@@ -0,0 +1,204 @@
+package setting
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BUNDLE_VERSION is the version of the bundle format, the settings in it have their own version.
+const BUNDLE_VERSION = 1
+
+type ImportMode int
+
+const (
+	// ImportMerge adds the profiles and unknown settings of the bundle, profiles with the same name
+	// are replaced and the active profile is kept.
+	ImportMerge ImportMode = iota
+	// ImportReplace replaces all settings except the hooks with the settings of the bundle.
+	ImportReplace
+)
+
+func (mode ImportMode) String() string {
+	switch mode {
+	case ImportMerge:
+		return "merge"
+	case ImportReplace:
+		return "replace"
+	}
+	return "unknown"
+}
+
+// Bundle is an export of the settings to set up the client on another computer. Everything the
+// client stores is part of the settings, including settings of newer client versions.
+type Bundle struct {
+	Version       int       `yaml:"bundle"`
+	Application   string    `yaml:"application"`
+	ClientVersion string    `yaml:"clientversion"`
+	Exported      time.Time `yaml:"exported"`
+	Settings      Settings  `yaml:"settings"`
+}
+
+// Change is the difference of one setting between two settings, Old or New are empty if the setting
+// is added or removed.
+type Change struct {
+	Key string
+	Old string
+	New string
+}
+
+func (change Change) String() string {
+	switch {
+	case change.Old == "":
+		return fmt.Sprintf("+ %s: %s", change.Key, change.New)
+	case change.New == "":
+		return fmt.Sprintf("- %s: %s", change.Key, change.Old)
+	}
+	return fmt.Sprintf("~ %s: %s -> %s", change.Key, change.Old, change.New)
+}
+
+// ExportBundle writes the settings as they are saved, so overrides are not exported.
+func (settings Settings) ExportBundle(w io.Writer) error {
+	bundle := Bundle{
+		Version:       BUNDLE_VERSION,
+		Application:   APPLICATION_NAME,
+		ClientVersion: VERSION,
+		Exported:      time.Now(),
+		Settings:      settings.persisted(),
+	}
+	encoder := yaml.NewEncoder(w)
+	defer encoder.Close()
+	return encoder.Encode(bundle)
+}
+
+// ReadBundle reads a bundle and migrates its settings to the current schema version.
+func ReadBundle(r io.Reader) (*Bundle, error) {
+	var raw struct {
+		Version       int            `yaml:"bundle"`
+		Application   string         `yaml:"application"`
+		ClientVersion string         `yaml:"clientversion"`
+		Exported      time.Time      `yaml:"exported"`
+		Settings      map[string]any `yaml:"settings"`
+	}
+	err := yaml.NewDecoder(r).Decode(&raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	if raw.Version < 1 || raw.Settings == nil {
+		return nil, errors.New("invalid bundle: not a settings bundle")
+	}
+	if raw.Version > BUNDLE_VERSION {
+		return nil, fmt.Errorf("bundle version %d is not supported, update the client", raw.Version)
+	}
+	data, err := yaml.Marshal(raw.Settings)
+	if err != nil {
+		return nil, err
+	}
+	settings, _, err := parse("", data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle settings: %w", err)
+	}
+	return &Bundle{
+		Version:       raw.Version,
+		Application:   raw.Application,
+		ClientVersion: raw.ClientVersion,
+		Exported:      raw.Exported,
+		Settings:      *settings,
+	}, nil
+}
+
+// Import returns the settings with the settings of the bundle merged or replaced. The overrides and
+// the hooks of the settings are kept, the hooks of the bundle are never imported, as they run
+// commands on this computer.
+func (settings Settings) Import(bundle Settings, mode ImportMode) Settings {
+	bundle = bundle.persisted()
+	result := settings.persisted()
+	result.sources = settings.sources
+	result.overrides = settings.overrides
+	result.Version = SCHEMA_VERSION
+	switch mode {
+	case ImportReplace:
+		result.ActiveProfile = bundle.ActiveProfile
+		result.Profiles = slices.Clone(bundle.Profiles)
+		result.Unknown = maps.Clone(bundle.Unknown)
+	case ImportMerge:
+		profiles := slices.Clone(result.Profiles)
+		for _, profile := range bundle.Profiles {
+			index := slices.IndexFunc(profiles, func(p Profile) bool {
+				return p.Name == profile.Name
+			})
+			if index < 0 {
+				profiles = append(profiles, profile)
+			} else {
+				profiles[index] = profile
+			}
+		}
+		result.Profiles = profiles
+		unknown := maps.Clone(result.Unknown)
+		if unknown == nil {
+			unknown = make(map[string]any)
+		}
+		maps.Copy(unknown, bundle.Unknown)
+		result.Unknown = unknown
+	}
+	result.activate()
+	return result
+}
+
+// Diff returns the changes from the settings to the other settings, as they are saved.
+func (settings Settings) Diff(other Settings) []Change {
+	from := settings.persisted().flatten()
+	to := other.persisted().flatten()
+	keys := make([]string, 0, len(from)+len(to))
+	for key := range from {
+		keys = append(keys, key)
+	}
+	for key := range to {
+		if _, found := from[key]; !found {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	changes := make([]Change, 0)
+	for _, key := range keys {
+		if from[key] != to[key] {
+			changes = append(changes, Change{Key: key, Old: from[key], New: to[key]})
+		}
+	}
+	return changes
+}
+
+// flatten returns the settings by keys like profiles.<name>.serverurl to compare them.
+func (settings Settings) flatten() map[string]string {
+	flat := map[string]string{
+		"activeprofile": settings.ActiveProfile,
+	}
+	for _, profile := range settings.Profiles {
+		prefix := "profiles." + profile.Name
+		flat[prefix+".serverurl"] = profile.ServerURL
+		flat[prefix+".gamedirectory"] = profile.GameDirectory
+		flat[prefix+".username"] = profile.Username
+		flat[prefix+".downloaddirectory"] = profile.DownloadDirectory
+		flat[prefix+".backupserverurls"] = strings.Join(profile.BackupServerURLs, ",")
+		flat[prefix+".cafile"] = profile.CAFile
+		flat[prefix+".pinnedcertificates"] = strings.Join(profile.PinnedCertificates, ",")
+		if profile.InsecureSkipVerify {
+			flat[prefix+".insecureskipverify"] = strconv.FormatBool(profile.InsecureSkipVerify)
+		}
+	}
+	for index, hook := range settings.Hooks {
+		command := strings.TrimSpace(hook.Command + " " + strings.Join(hook.Args, " "))
+		flat[fmt.Sprintf("hooks.%d", index)] = fmt.Sprintf("%s: %s", hook.Event, command)
+	}
+	for key, value := range settings.Unknown {
+		flat[key] = fmt.Sprint(value)
+	}
+	return flat
+}
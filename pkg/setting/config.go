@@ -0,0 +1,337 @@
+package setting
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ENVIRONMENT_PREFIX is the prefix of the environment variables overriding settings, e.g. LANTY_SERVERURL.
+const ENVIRONMENT_PREFIX = "LANTY_"
+
+// Source is the configuration layer a setting value came from.
+type Source string
+
+const (
+	SourceDefault     Source = "default"
+	SourceFile        Source = "file"
+	SourceEnvironment Source = "environment"
+	SourceFlag        Source = "flag"
+)
+
+// Layer holds setting values by key, which override the values of the settings file.
+type Layer struct {
+	Source Source
+	Values map[string]string
+}
+
+// ConfigValue is the effective value of a setting and where it came from.
+type ConfigValue struct {
+	Key    string
+	Value  string
+	Source Source
+}
+
+// override is the value of a setting before it was overridden, which is written when saving as
+// long as the setting is not changed at runtime.
+type override struct {
+	profile string
+	value   string
+	file    string
+}
+
+// field is a scalar setting, which can be overridden by its key. Fields of the embedded Profile
+// override the active profile.
+type field struct {
+	key     string
+	index   []int
+	profile bool
+}
+
+var overrideLayers []Layer
+
+// SetOverrides sets the layers which LoadSettings applies on top of the settings file, values of
+// later layers take precedence.
+func SetOverrides(layers ...Layer) {
+	overrideLayers = layers
+}
+
+// Keys returns the keys of all settings which can be overridden.
+func Keys() []string {
+	keys := make([]string, 0)
+	for _, field := range fields() {
+		keys = append(keys, field.key)
+	}
+	return keys
+}
+
+func EnvironmentVariable(key string) string {
+	return ENVIRONMENT_PREFIX + strings.ToUpper(key)
+}
+
+// EnvironmentLayer returns the values of the LANTY_<KEY> environment variables which are set.
+func EnvironmentLayer() Layer {
+	layer := Layer{Source: SourceEnvironment, Values: make(map[string]string)}
+	for _, key := range Keys() {
+		value, found := os.LookupEnv(EnvironmentVariable(key))
+		if found {
+			layer.Values[key] = value
+		}
+	}
+	return layer
+}
+
+// RegisterFlags adds a flag for every setting to the flagset, bool settings can be set without a
+// value like -insecureskipverify. The returned layer holds the values of the flags which are set
+// after parsing.
+func RegisterFlags(flagset *flag.FlagSet) Layer {
+	layer := Layer{Source: SourceFlag, Values: make(map[string]string)}
+	for _, field := range fields() {
+		key := field.key
+		usage := fmt.Sprintf("Overrides the %s setting (env %s)", key, EnvironmentVariable(key))
+		set := func(value string) error {
+			layer.Values[key] = value
+			return nil
+		}
+		if reflect.TypeOf(Settings{}).FieldByIndex(field.index).Type.Kind() == reflect.Bool {
+			flagset.BoolFunc(key, usage, set)
+		} else {
+			flagset.Func(key, usage, set)
+		}
+	}
+	return layer
+}
+
+// Config returns the effective value and source of every setting which can be overridden.
+func (settings Settings) Config() []ConfigValue {
+	values := make([]ConfigValue, 0)
+	for _, field := range fields() {
+		source, found := settings.sources[field.key]
+		if !found {
+			source = SourceFile
+		}
+		values = append(values, ConfigValue{
+			Key:    field.key,
+			Value:  settings.get(field),
+			Source: source,
+		})
+	}
+	return values
+}
+
+// WriteConfig writes the effective settings as table with the source of every value.
+func (settings Settings) WriteConfig(w io.Writer) error {
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "KEY\tVALUE\tSOURCE")
+	for _, value := range settings.Config() {
+		source := string(value.Source)
+		switch value.Source {
+		case SourceEnvironment:
+			source = fmt.Sprintf("%s (%s)", value.Source, EnvironmentVariable(value.Key))
+		case SourceFlag:
+			source = fmt.Sprintf("%s (-%s)", value.Source, value.Key)
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", value.Key, value.Value, source)
+	}
+	return writer.Flush()
+}
+
+// applyLayers fills empty settings with the defaults and applies the override layers. The active
+// profile comes first, so it is switched before the profile settings are filled and overridden. An
+// empty value set in the settings file, which is also the default, keeps the file as source.
+func (settings *Settings) applyLayers(layers []Layer) {
+	settings.sources = make(map[string]Source)
+	settings.overrides = make(map[string]override)
+	defaults := Default()
+	for _, field := range fields() {
+		if settings.value(field).IsZero() {
+			settings.value(field).Set(defaults.value(field))
+			if !settings.value(field).IsZero() || !settings.inFile(field) {
+				settings.sources[field.key] = SourceDefault
+			}
+		}
+
+		var value string
+		var source Source
+		for _, layer := range layers {
+			if v, found := layer.Values[field.key]; found {
+				value, source = v, layer.Source
+			}
+		}
+		if source == "" {
+			continue
+		}
+		previous := settings.get(field)
+		var err error
+		if field.key == "activeprofile" {
+			err = settings.SwitchProfile(value)
+		} else {
+			err = set(settings.value(field), value)
+		}
+		if err != nil {
+			log.Warn().Err(err).Str("key", field.key).Str("value", value).Str("source", string(source)).Msg("ignoring invalid setting override")
+			continue
+		}
+		settings.sources[field.key] = source
+		settings.overrides[field.key] = override{
+			profile: settings.ActiveProfile,
+			value:   settings.get(field),
+			file:    previous,
+		}
+	}
+}
+
+// restoreOverrides replaces overridden values, which were not changed at runtime, with the values
+// of the settings file, so overrides are not saved.
+func (settings *Settings) restoreOverrides() {
+	for _, field := range fields() {
+		original, found := settings.overrides[field.key]
+		if !found {
+			continue
+		}
+		value := settings.value(field)
+		if field.profile {
+			value = reflect.Value{}
+			for index := range settings.Profiles {
+				if settings.Profiles[index].Name == original.profile {
+					value = reflect.ValueOf(&settings.Profiles[index]).Elem().FieldByIndex(field.index[1:])
+				}
+			}
+			if !value.IsValid() {
+				continue
+			}
+		}
+		if format(value) == original.value {
+			set(value, original.file)
+		}
+	}
+}
+
+// inFile returns if the setting is set in the settings file, for the active profile if it is a
+// setting of the profile.
+func (settings *Settings) inFile(field field) bool {
+	if field.profile {
+		return settings.keys["profiles."+settings.ActiveProfile+"."+field.key]
+	}
+	return settings.keys[field.key]
+}
+
+// fileKeys returns the keys set in the settings file, the keys of the profiles are prefixed like
+// profiles.<name>.serverurl.
+func fileKeys(raw map[string]any) map[string]bool {
+	keys := make(map[string]bool)
+	for key := range raw {
+		keys[key] = true
+	}
+	profiles, _ := raw["profiles"].([]any)
+	for _, entry := range profiles {
+		profile, ok := entry.(map[string]any)
+		if !ok {
+			continue
+		}
+		name := fmt.Sprint(profile["name"])
+		for key := range profile {
+			keys["profiles."+name+"."+key] = true
+		}
+	}
+	return keys
+}
+
+func (settings *Settings) value(field field) reflect.Value {
+	return reflect.ValueOf(settings).Elem().FieldByIndex(field.index)
+}
+
+func (settings *Settings) get(field field) string {
+	return format(settings.value(field))
+}
+
+// format returns the value as it is written in environment variables and flags, lists are comma separated.
+func format(value reflect.Value) string {
+	if value.Kind() == reflect.Slice {
+		values := make([]string, 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			values = append(values, fmt.Sprint(value.Index(i).Interface()))
+		}
+		return strings.Join(values, ",")
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+// SplitList returns the non-empty values of a comma separated list.
+func SplitList(s string) []string {
+	values := make([]string, 0)
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func set(value reflect.Value, s string) error {
+	switch value.Kind() {
+	case reflect.Slice:
+		if value.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported setting type %s", value.Type())
+		}
+		value.Set(reflect.ValueOf(SplitList(s)))
+	case reflect.String:
+		value.SetString(s)
+	case reflect.Int:
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(i))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported setting type %s", value.Type())
+	}
+	return nil
+}
+
+// fields returns all scalar settings by their yaml key, so new settings can be overridden without
+// further changes.
+func fields() []field {
+	fields := make([]field, 0)
+	var walk func(t reflect.Type, index []int, profile bool)
+	walk = func(t reflect.Type, index []int, profile bool) {
+		for i := 0; i < t.NumField(); i++ {
+			structfield := t.Field(i)
+			if !structfield.IsExported() {
+				continue
+			}
+			fieldindex := append(append([]int{}, index...), i)
+			if structfield.Anonymous && structfield.Type.Kind() == reflect.Struct {
+				walk(structfield.Type, fieldindex, true)
+				continue
+			}
+			key, _, _ := strings.Cut(structfield.Tag.Get("yaml"), ",")
+			if key == "" || key == "-" || key == "version" || key == "name" {
+				continue
+			}
+			switch structfield.Type.Kind() {
+			case reflect.String, reflect.Int, reflect.Bool:
+				fields = append(fields, field{key: key, index: fieldindex, profile: profile})
+			case reflect.Slice:
+				if structfield.Type.Elem().Kind() == reflect.String {
+					fields = append(fields, field{key: key, index: fieldindex, profile: profile})
+				}
+			}
+		}
+	}
+	walk(reflect.TypeOf(Settings{}), nil, false)
+	return fields
+}
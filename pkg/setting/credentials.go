@@ -0,0 +1,158 @@
+package setting
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// CREDENTIALS_PATH is the file next to the settings file with the encrypted event passwords of
+	// the profiles. It is not part of the settings, so the passwords are not exported or logged.
+	CREDENTIALS_PATH = "credentials.yaml"
+	// CREDENTIALS_KEY_PATH is the file with the local key the passwords are encrypted with.
+	CREDENTIALS_KEY_PATH = "credentials.key"
+	CREDENTIALS_KEY_SIZE = 32
+)
+
+// credentials are the encrypted passwords by profile name.
+type credentials struct {
+	Passwords map[string]string `yaml:"passwords"`
+}
+
+// LoadPassword returns the event password of the profile, it is empty if none is stored.
+func LoadPassword(profile string) (string, error) {
+	directory, err := Directory()
+	if err != nil {
+		return "", err
+	}
+	stored, err := readCredentials(directory)
+	if err != nil {
+		return "", err
+	}
+	encrypted, found := stored.Passwords[profile]
+	if !found {
+		return "", nil
+	}
+	key, err := os.ReadFile(path.Join(directory, CREDENTIALS_KEY_PATH))
+	if err != nil {
+		return "", fmt.Errorf("error reading credentials key: %w", err)
+	}
+	return decrypt(key, encrypted)
+}
+
+// SavePassword stores the event password of the profile encrypted, an empty password removes it.
+// The key is created with the first password.
+func SavePassword(profile string, password string) error {
+	directory, err := Directory()
+	if err != nil {
+		return err
+	}
+	stored, err := readCredentials(directory)
+	if err != nil {
+		return err
+	}
+	if password == "" {
+		if _, found := stored.Passwords[profile]; !found {
+			return nil
+		}
+		delete(stored.Passwords, profile)
+	} else {
+		key, err := loadKey(directory)
+		if err != nil {
+			return err
+		}
+		stored.Passwords[profile], err = encrypt(key, password)
+		if err != nil {
+			return err
+		}
+	}
+	data, err := yaml.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	return writeFile(path.Join(directory, CREDENTIALS_PATH), data, 0600)
+}
+
+func readCredentials(directory string) (credentials, error) {
+	stored := credentials{Passwords: make(map[string]string)}
+	data, err := os.ReadFile(path.Join(directory, CREDENTIALS_PATH))
+	if errors.Is(err, os.ErrNotExist) {
+		return stored, nil
+	}
+	if err != nil {
+		return stored, err
+	}
+	err = yaml.Unmarshal(data, &stored)
+	if stored.Passwords == nil {
+		stored.Passwords = make(map[string]string)
+	}
+	return stored, err
+}
+
+// loadKey reads the key or creates a random key if there is none. A damaged key is not replaced, as
+// the stored passwords could not be decrypted anymore.
+func loadKey(directory string) ([]byte, error) {
+	filepath := path.Join(directory, CREDENTIALS_KEY_PATH)
+	key, err := os.ReadFile(filepath)
+	if err == nil && len(key) != CREDENTIALS_KEY_SIZE {
+		return nil, fmt.Errorf("invalid credentials key %s: %d bytes instead of %d", filepath, len(key), CREDENTIALS_KEY_SIZE)
+	}
+	if err == nil || !errors.Is(err, os.ErrNotExist) {
+		return key, err
+	}
+	key = make([]byte, CREDENTIALS_KEY_SIZE)
+	_, err = rand.Read(key)
+	if err != nil {
+		return nil, err
+	}
+	return key, writeFile(filepath, key, 0600)
+}
+
+// encrypt returns the password encrypted with AES-GCM, the nonce is prepended.
+func encrypt(key []byte, password string) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(password), nil)), nil
+}
+
+func decrypt(key []byte, encrypted string) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < aead.NonceSize() {
+		return "", errors.New("encrypted password too short")
+	}
+	password, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("error decrypting password, the credentials key may have changed: %w", err)
+	}
+	return string(password), nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,191 @@
+package setting
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/kardianos/osext"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// PORTABLE_MARKER is the file next to the executable, which keeps all files in the executable folder.
+	PORTABLE_MARKER = "portable"
+	DIRECTORY_NAME  = "lanty"
+	LOG_DIRECTORY   = "log"
+	CACHE_DIRECTORY = "cache"
+
+	// TOKEN_PATH is the file of the control API token in the config folder.
+	TOKEN_PATH = "api-token"
+)
+
+// Directories are the folders the client writes to. They follow the platform conventions, e.g. the
+// XDG base directories on Linux, unless the client is portable.
+type Directories struct {
+	Config   string
+	Data     string
+	Cache    string
+	Log      string
+	Portable bool
+}
+
+// Dirs returns the folders of the client. In portable mode all of them are in the executable folder.
+func Dirs() (Directories, error) {
+	executable, err := osext.ExecutableFolder()
+	if err != nil {
+		return Directories{}, err
+	}
+	if IsPortable(executable) {
+		return Directories{
+			Config:   executable,
+			Data:     executable,
+			Cache:    filepath.Join(executable, CACHE_DIRECTORY),
+			Log:      filepath.Join(executable, LOG_DIRECTORY),
+			Portable: true,
+		}, nil
+	}
+
+	config, err := os.UserConfigDir()
+	if err != nil {
+		return Directories{}, err
+	}
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return Directories{}, err
+	}
+	data, state, err := dataDirs()
+	if err != nil {
+		return Directories{}, err
+	}
+	return Directories{
+		Config: filepath.Join(config, DIRECTORY_NAME),
+		Data:   filepath.Join(data, DIRECTORY_NAME),
+		Cache:  filepath.Join(cache, DIRECTORY_NAME),
+		Log:    filepath.Join(state, DIRECTORY_NAME, LOG_DIRECTORY),
+	}, nil
+}
+
+// LogDirectory returns the log folder, or the log folder in the working directory if it is unknown.
+func LogDirectory() string {
+	directories, err := Dirs()
+	if err != nil {
+		return LOG_DIRECTORY
+	}
+	return directories.Log
+}
+
+func IsPortable(executable string) bool {
+	_, err := os.Stat(filepath.Join(executable, PORTABLE_MARKER))
+	return err == nil
+}
+
+// dataDirs returns the base folders for data and state like logs, as the standard library only
+// knows the config and cache folders.
+func dataDirs() (data string, state string, err error) {
+	switch runtime.GOOS {
+	case "windows":
+		data = os.Getenv("LocalAppData")
+		if data == "" {
+			return "", "", errors.New("%LocalAppData% is not defined")
+		}
+		return data, data, nil
+	case "darwin":
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", err
+		}
+		return filepath.Join(home, "Library", "Application Support"), filepath.Join(home, "Library", "Logs"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", "", err
+	}
+	data = xdgDir("XDG_DATA_HOME", filepath.Join(home, ".local", "share"))
+	state = xdgDir("XDG_STATE_HOME", filepath.Join(home, ".local", "state"))
+	return data, state, nil
+}
+
+// xdgDir returns the folder of the environment variable, relative paths are invalid and ignored.
+func xdgDir(variable string, fallback string) string {
+	directory := os.Getenv(variable)
+	if directory == "" || !filepath.IsAbs(directory) {
+		return fallback
+	}
+	return directory
+}
+
+// migrateLegacySettings copies a settings file of older versions, which were next to the executable
+// or in the working directory, to the config folder. Relative game and download folders are made
+// absolute, as they were relative to the working directory.
+func migrateLegacySettings(directories Directories) {
+	if directories.Portable {
+		return
+	}
+	_, err := os.Stat(filepath.Join(directories.Config, SETTINGS_PATH))
+	if !errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	legacy := make([]string, 0)
+	executable, err := osext.ExecutableFolder()
+	if err == nil {
+		legacy = append(legacy, executable)
+	}
+	workdir, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	legacy = append(legacy, workdir)
+	for _, directory := range legacy {
+		legacypath := filepath.Join(directory, SETTINGS_PATH)
+		data, err := os.ReadFile(legacypath)
+		if err != nil {
+			continue
+		}
+		settings, _, err := parse("", data)
+		if err != nil {
+			log.Warn().Err(err).Str("path", legacypath).Msg("not migrating invalid settings file")
+			continue
+		}
+		for index := range settings.Profiles {
+			profile := &settings.Profiles[index]
+			profile.GameDirectory = absoluteTo(workdir, profile.GameDirectory)
+			profile.DownloadDirectory = absoluteTo(workdir, profile.DownloadDirectory)
+		}
+		settings.activate()
+		err = os.MkdirAll(directories.Config, 0755)
+		if err == nil {
+			err = settings.save(filepath.Join(directories.Config, SETTINGS_PATH))
+		}
+		if err != nil {
+			log.Error().Err(err).Str("from", legacypath).Str("to", directories.Config).Msg("error migrating settings")
+			return
+		}
+		// The control API token is copied along, so API clients keep working.
+		token := filepath.Join(directory, TOKEN_PATH)
+		if _, err := os.Stat(token); err == nil {
+			copyFile(token, filepath.Join(directories.Config, TOKEN_PATH))
+		}
+		log.Info().Str("from", legacypath).Str("to", directories.Config).Msgf("migrated settings, create a \"%s\" file next to the executable to keep them there", PORTABLE_MARKER)
+		return
+	}
+}
+
+func absoluteTo(directory string, path string) string {
+	if path == "" || filepath.IsAbs(path) || strings.HasPrefix(path, "~") {
+		return path
+	}
+	return filepath.Join(directory, path)
+}
+
+func copyFile(from string, to string) {
+	data, err := os.ReadFile(from)
+	if err == nil {
+		err = os.WriteFile(to, data, 0600)
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("from", from).Str("to", to).Msg("error copying file")
+	}
+}
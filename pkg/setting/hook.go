@@ -0,0 +1,12 @@
+package setting
+
+import "time"
+
+// Hook runs a command on a client event, e.g. download.completed or user.joined. The event data is
+// passed as LANTY_HOOK_* environment variables, which do not override settings, and as JSON on stdin.
+type Hook struct {
+	Event   string        `yaml:"event"`
+	Command string        `yaml:"command"`
+	Args    []string      `yaml:"args,omitempty"`
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
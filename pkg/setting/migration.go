@@ -0,0 +1,92 @@
+package setting
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rs/zerolog/log"
+)
+
+// SCHEMA_VERSION is the version of the settings file written by this client. Adding a setting
+// which needs a conversion of old files requires a new migration and a higher version.
+const SCHEMA_VERSION = 2
+
+// migration converts the raw settings of one schema version to the next version.
+type migration func(raw map[string]any) error
+
+// migrations[i] migrates from version i to version i+1.
+var migrations = []migration{
+	migrateV0,
+	migrateV1,
+}
+
+// migrateV0 migrates the unversioned settings, which had no hooks.
+func migrateV0(raw map[string]any) error {
+	if _, found := raw["hooks"]; !found {
+		raw["hooks"] = []any{}
+	}
+	return nil
+}
+
+// migrateV1 moves the server settings into the default profile.
+func migrateV1(raw map[string]any) error {
+	profile := map[string]any{"name": DEFAULT_PROFILE}
+	for _, key := range []string{"serverurl", "gamedirectory", "username", "downloaddirectory"} {
+		if value, found := raw[key]; found {
+			profile[key] = value
+			delete(raw, key)
+		}
+	}
+	raw["activeprofile"] = DEFAULT_PROFILE
+	raw["profiles"] = []any{profile}
+	return nil
+}
+
+// migrate runs all migrations from the version of the raw settings to SCHEMA_VERSION. The original
+// file is copied to a backup before unless filepath is empty, it returns if the settings were migrated.
+func migrate(filepath string, data []byte, raw map[string]any) (bool, error) {
+	version, err := schemaVersion(raw)
+	if err != nil {
+		return false, err
+	}
+	if version > SCHEMA_VERSION {
+		log.Warn().Int("version", version).Int("supported", SCHEMA_VERSION).Msg("settings file was written by a newer client version")
+		return false, nil
+	}
+	if version == SCHEMA_VERSION {
+		return false, nil
+	}
+
+	backup := ""
+	if filepath != "" {
+		backup = fmt.Sprintf("%s.v%d.bak", filepath, version)
+		err = os.WriteFile(backup, data, 0644)
+		if err != nil {
+			return false, fmt.Errorf("error writing settings backup before migration: %w", err)
+		}
+	}
+	for ; version < SCHEMA_VERSION; version++ {
+		err = migrations[version](raw)
+		if err != nil {
+			return false, fmt.Errorf("error migrating settings from version %d: %w", version, err)
+		}
+		raw["version"] = version + 1
+		log.Info().Int("from", version).Int("to", version+1).Msg("migrated settings")
+	}
+	if backup != "" {
+		log.Info().Str("backup", backup).Msg("wrote settings backup before migration")
+	}
+	return true, nil
+}
+
+func schemaVersion(raw map[string]any) (int, error) {
+	value, found := raw["version"]
+	if !found || value == nil {
+		return 0, nil
+	}
+	version, ok := value.(int)
+	if !ok || version < 0 {
+		return 0, fmt.Errorf("invalid settings version %v", value)
+	}
+	return version, nil
+}
@@ -0,0 +1,160 @@
+package setting
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v3"
+)
+
+func TestMain(m *testing.M) {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	os.Exit(m.Run())
+}
+
+// settingsFile moves the config, data and cache folders to a temporary folder and returns the path
+// of the settings file, the config folder is created.
+func settingsFile(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	for _, variable := range []string{"HOME", "XDG_CONFIG_HOME", "XDG_CACHE_HOME", "XDG_DATA_HOME", "XDG_STATE_HOME", "APPDATA", "LocalAppData"} {
+		t.Setenv(variable, filepath.Join(home, variable))
+	}
+	settingspath, err := Path()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.MkdirAll(filepath.Dir(settingspath), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return settingspath
+}
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		backup  string
+		profile Profile
+		hooks   int
+	}{
+		{
+			name:    "v0",
+			file:    "serverurl: http://lan:8080\ngamedirectory: /games\nusername: gamer\ndownloaddirectory: /downloads\n",
+			backup:  SETTINGS_PATH + ".v0.bak",
+			profile: Profile{Name: DEFAULT_PROFILE, ServerURL: "http://lan:8080", GameDirectory: "/games", Username: "gamer", DownloadDirectory: "/downloads"},
+		},
+		{
+			name:    "v1",
+			file:    "version: 1\nserverurl: http://lan:8080\nusername: gamer\nhooks:\n  - event: game.started\n    command: notify\n",
+			backup:  SETTINGS_PATH + ".v1.bak",
+			profile: Profile{Name: DEFAULT_PROFILE, ServerURL: "http://lan:8080", Username: "gamer"},
+			hooks:   1,
+		},
+		{
+			name:    "current",
+			file:    "version: 2\nactiveprofile: venue\nprofiles:\n  - name: venue\n    serverurl: http://lan:8080\n    username: gamer\n",
+			profile: Profile{Name: "venue", ServerURL: "http://lan:8080", Username: "gamer"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settingspath := settingsFile(t)
+			err := os.WriteFile(settingspath, []byte(test.file), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			settings, err := load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if settings.Version != SCHEMA_VERSION || settings.ActiveProfile != test.profile.Name || len(settings.Hooks) != test.hooks {
+				t.Errorf("got version %d, active profile %s and %d hooks, want %d, %s and %d", settings.Version, settings.ActiveProfile,
+					len(settings.Hooks), SCHEMA_VERSION, test.profile.Name, test.hooks)
+			}
+			if len(settings.Profiles) != 1 || !reflect.DeepEqual(settings.Profiles[0], test.profile) || !reflect.DeepEqual(settings.Profile, test.profile) {
+				t.Errorf("got profiles %+v, want %+v", settings.Profiles, test.profile)
+			}
+
+			saved := make(map[string]any)
+			data, err := os.ReadFile(settingspath)
+			if err == nil {
+				err = yaml.Unmarshal(data, &saved)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if saved["version"] != SCHEMA_VERSION {
+				t.Errorf("got saved version %v, want %d", saved["version"], SCHEMA_VERSION)
+			}
+			for _, key := range []string{"serverurl", "username"} {
+				if _, found := saved[key]; found {
+					t.Errorf("got %s outside of the profiles in the saved file", key)
+				}
+			}
+
+			backups, err := filepath.Glob(settingspath + ".v*.bak")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.backup == "" {
+				if len(backups) != 0 {
+					t.Errorf("got backups %v, want none", backups)
+				}
+				return
+			}
+			backup, err := os.ReadFile(filepath.Join(filepath.Dir(settingspath), test.backup))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(backup) != test.file {
+				t.Errorf("got backup %q, want the original file %q", backup, test.file)
+			}
+		})
+	}
+}
+
+func TestMigrateNewerVersion(t *testing.T) {
+	settingspath := settingsFile(t)
+	file := "version: 3\nactiveprofile: venue\nprofiles:\n  - name: venue\n    serverurl: http://lan:8080\nfuture: setting\n"
+	err := os.WriteFile(settingspath, []byte(file), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	settings, err := load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.Version != 3 || settings.ServerURL != "http://lan:8080" || settings.Unknown["future"] != "setting" {
+		t.Errorf("got settings %+v, want the settings of the file", settings)
+	}
+	data, err := os.ReadFile(settingspath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != file {
+		t.Errorf("got file %q, want it untouched", data)
+	}
+	backups, err := filepath.Glob(settingspath + ".*bak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 0 {
+		t.Errorf("got backups %v, want none", backups)
+	}
+}
+
+func TestMigrateInvalidVersion(t *testing.T) {
+	for _, version := range []string{"-1", "two"} {
+		_, _, err := parse("", []byte("version: "+version+"\n"))
+		if err == nil {
+			t.Errorf("got no error for version %s", version)
+		}
+	}
+}
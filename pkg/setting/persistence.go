@@ -0,0 +1,157 @@
+package setting
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// BACKUP_COUNT is the number of backups of previous settings files, which are rotated on every save.
+const BACKUP_COUNT = 3
+
+// RecoveryError is returned together with usable settings, if the settings file could not be loaded
+// and the settings were recovered from a backup or the defaults.
+type RecoveryError struct {
+	Path string
+	// Backup is the backup the settings were recovered from, it is empty if the defaults are used.
+	Backup string
+	Err    error
+}
+
+func (err *RecoveryError) Error() string {
+	if err.Backup == "" {
+		return fmt.Sprintf("invalid settings file %s, using the default settings: %v", err.Path, err.Err)
+	}
+	return fmt.Sprintf("invalid settings file %s, using the backup %s: %v", err.Path, err.Backup, err.Err)
+}
+
+func (err *RecoveryError) Unwrap() error {
+	return err.Err
+}
+
+// recoverSettings moves the invalid settings file aside and loads the newest valid backup or the
+// defaults, which are saved as new settings file.
+func recoverSettings(filepath string, cause error) (*Settings, error) {
+	recovery := &RecoveryError{Path: filepath, Err: cause}
+	log.Error().Err(cause).Str("path", filepath).Msg("error loading settings file")
+	corrupt := filepath + ".corrupt"
+	err := os.Rename(filepath, corrupt)
+	if err == nil {
+		log.Warn().Str("path", corrupt).Msg("moved invalid settings file")
+	}
+
+	var settings *Settings
+	for index := 1; index <= BACKUP_COUNT; index++ {
+		backup := backupPath(filepath, index)
+		data, err := os.ReadFile(backup)
+		if err != nil {
+			continue
+		}
+		settings, _, err = parse(filepath, data)
+		if err != nil {
+			log.Warn().Err(err).Str("path", backup).Msg("skipping invalid settings backup")
+			continue
+		}
+		recovery.Backup = backup
+		break
+	}
+	if settings == nil {
+		settings = Default()
+	}
+	log.Warn().Str("backup", recovery.Backup).Msg("recovered settings")
+
+	err = settings.save(filepath)
+	if err != nil {
+		log.Error().Err(err).Str("path", filepath).Msg("error saving recovered settings")
+	}
+	return settings, recovery
+}
+
+// writeFile replaces the file atomically by writing a synced temporary file with the mode, which is
+// renamed to the file after the current file was added to the backups.
+func writeFile(filepath string, data []byte, mode os.FileMode) error {
+	directory := path.Dir(filepath)
+	temp, err := os.CreateTemp(directory, path.Base(filepath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	_, err = temp.Write(data)
+	if err == nil {
+		err = temp.Chmod(mode)
+	}
+	if err == nil {
+		err = temp.Sync()
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	err = rotateBackups(filepath, data, mode)
+	if err != nil {
+		log.Warn().Err(err).Str("path", filepath).Msg("error rotating settings backups")
+	}
+	err = os.Rename(temp.Name(), filepath)
+	if err != nil {
+		return err
+	}
+	syncDirectory(directory)
+	return nil
+}
+
+// rotateBackups copies the current file to the newest backup with the mode, if it is valid, differs
+// from it and is replaced by other data.
+func rotateBackups(filepath string, replacement []byte, mode os.FileMode) error {
+	data, err := os.ReadFile(filepath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(data, replacement) {
+		return nil
+	}
+	raw := make(map[string]any)
+	if len(bytes.TrimSpace(data)) == 0 || yaml.Unmarshal(data, &raw) != nil {
+		return nil
+	}
+	newest, err := os.ReadFile(backupPath(filepath, 1))
+	if err == nil && bytes.Equal(newest, data) {
+		return nil
+	}
+
+	for index := BACKUP_COUNT; index > 1; index-- {
+		err = os.Rename(backupPath(filepath, index-1), backupPath(filepath, index))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return os.WriteFile(backupPath(filepath, 1), data, mode)
+}
+
+func backupPath(filepath string, index int) string {
+	return fmt.Sprintf("%s.%d.bak", filepath, index)
+}
+
+// syncDirectory persists the rename of a file, it is not supported on every platform and errors are
+// only logged.
+func syncDirectory(directory string) {
+	dir, err := os.Open(directory)
+	if err != nil {
+		return
+	}
+	defer dir.Close()
+	err = dir.Sync()
+	if err != nil {
+		log.Trace().Err(err).Str("directory", directory).Msg("error syncing settings directory")
+	}
+}
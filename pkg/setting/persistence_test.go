@@ -0,0 +1,136 @@
+package setting
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+const VALID_SETTINGS = "version: 2\nactiveprofile: default\nprofiles:\n  - name: default\n    serverurl: http://%s:8080\n"
+
+func TestRecoverSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		backups map[int]string
+		// backup is the index of the backup the settings are recovered from, 0 for the defaults.
+		backup int
+	}{
+		{
+			name:    "corrupt",
+			file:    "version: [2\n",
+			backups: map[int]string{1: fmt.Sprintf(VALID_SETTINGS, "backup1")},
+			backup:  1,
+		},
+		{
+			name:    "truncated",
+			file:    "version: 2\nactiveprofile: default\nprofiles:\n  - name: default\n    serverurl: \"http://la",
+			backups: map[int]string{1: "", 2: "profiles: {", 3: fmt.Sprintf(VALID_SETTINGS, "backup3")},
+			backup:  3,
+		},
+		{
+			name: "empty",
+			file: "\n",
+		},
+		{
+			name:    "invalid backups",
+			file:    "version: two\n",
+			backups: map[int]string{1: "version: -1\n"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settingspath := settingsFile(t)
+			err := os.WriteFile(settingspath, []byte(test.file), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for index, backup := range test.backups {
+				err = os.WriteFile(backupPath(settingspath, index), []byte(backup), 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			settings, err := load()
+			var recovery *RecoveryError
+			if !errors.As(err, &recovery) {
+				t.Fatalf("got error %v, want a RecoveryError", err)
+			}
+			serverurl := DEFAULT_SERVERURL
+			backup := ""
+			if test.backup != 0 {
+				serverurl = fmt.Sprintf("http://backup%d:8080", test.backup)
+				backup = backupPath(settingspath, test.backup)
+			}
+			if recovery.Backup != backup || settings.ServerURL != serverurl {
+				t.Errorf("got server URL %s from backup %q, want %s from %q", settings.ServerURL, recovery.Backup, serverurl, backup)
+			}
+
+			corrupt, err := os.ReadFile(settingspath + ".corrupt")
+			if err != nil || string(corrupt) != test.file {
+				t.Errorf("got corrupt file %q, %v, want the invalid file", corrupt, err)
+			}
+			saved, err := ReadSettings(settingspath)
+			if err != nil {
+				t.Fatalf("error reading recovered settings file: %v", err)
+			}
+			if saved.ServerURL != serverurl {
+				t.Errorf("got saved server URL %s, want %s", saved.ServerURL, serverurl)
+			}
+		})
+	}
+}
+
+func TestRotateBackups(t *testing.T) {
+	settingspath := settingsFile(t)
+	saves := BACKUP_COUNT + 2
+	for index := 1; index <= saves; index++ {
+		err := writeFile(settingspath, []byte(fmt.Sprintf(VALID_SETTINGS, fmt.Sprint("save", index))), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Saving the same settings again does not rotate the backups.
+	err := writeFile(settingspath, []byte(fmt.Sprintf(VALID_SETTINGS, fmt.Sprint("save", saves))), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for index := 1; index <= BACKUP_COUNT; index++ {
+		data, err := os.ReadFile(backupPath(settingspath, index))
+		want := fmt.Sprintf(VALID_SETTINGS, fmt.Sprint("save", saves-index))
+		if err != nil || string(data) != want {
+			t.Errorf("got backup %d %q, %v, want %q", index, data, err, want)
+		}
+	}
+	_, err = os.Stat(backupPath(settingspath, BACKUP_COUNT+1))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("got %v for backup %d, want at most %d backups", err, BACKUP_COUNT+1, BACKUP_COUNT)
+	}
+}
+
+func TestRotateBackupsSkipsInvalidFile(t *testing.T) {
+	settingspath := settingsFile(t)
+	valid := fmt.Sprintf(VALID_SETTINGS, "valid")
+	err := writeFile(settingspath, []byte(valid), 0644)
+	if err == nil {
+		err = writeFile(settingspath, []byte(fmt.Sprintf(VALID_SETTINGS, "next")), 0644)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(settingspath, []byte("version: [2\n"), 0644)
+	if err == nil {
+		err = writeFile(settingspath, []byte(fmt.Sprintf(VALID_SETTINGS, "recovered")), 0644)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(backupPath(settingspath, 1))
+	if err != nil || string(data) != valid {
+		t.Errorf("got newest backup %q, %v, want the last valid file %q", data, err, valid)
+	}
+}
@@ -0,0 +1,122 @@
+package setting
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+const DEFAULT_PROFILE = "default"
+
+// Profile holds the settings of one Lanty server, e.g. of every LAN party.
+type Profile struct {
+	Name              string `yaml:"name"`
+	ServerURL         string `yaml:"serverurl"`
+	GameDirectory     string `yaml:"gamedirectory"`
+	Username          string `yaml:"username"`
+	DownloadDirectory string `yaml:"downloaddirectory"`
+	// BackupServerURLs are the servers, in order, the client fails over to if the server is down.
+	BackupServerURLs []string `yaml:"backupserverurls,omitempty"`
+	// CAFile is a PEM file with the certificates of CAs trusted in addition to the system CAs.
+	CAFile string `yaml:"cafile,omitempty"`
+	// PinnedCertificates are the SHA-256 fingerprints of the trusted server certificates, which
+	// are trusted instead of CAs.
+	PinnedCertificates []string `yaml:"pinnedcertificates,omitempty"`
+	// InsecureSkipVerify trusts every server certificate and is meant for testing only.
+	InsecureSkipVerify bool `yaml:"insecureskipverify,omitempty"`
+}
+
+// ServerURLs returns the server URL followed by the backup server URLs.
+func (profile Profile) ServerURLs() []string {
+	serverurls := []string{profile.ServerURL}
+	for _, serverurl := range profile.BackupServerURLs {
+		if serverurl != "" && !slices.Contains(serverurls, serverurl) {
+			serverurls = append(serverurls, serverurl)
+		}
+	}
+	return serverurls
+}
+
+func (settings Settings) ProfileNames() []string {
+	names := make([]string, 0, len(settings.Profiles))
+	for _, profile := range settings.syncedProfiles() {
+		names = append(names, profile.Name)
+	}
+	return names
+}
+
+func (settings Settings) GetProfile(name string) (Profile, error) {
+	for _, profile := range settings.syncedProfiles() {
+		if profile.Name == name {
+			return profile, nil
+		}
+	}
+	return Profile{}, fmt.Errorf("profile %s not found", name)
+}
+
+// SwitchProfile stores the active profile and makes the profile with the name the active one.
+func (settings *Settings) SwitchProfile(name string) error {
+	profile, err := settings.GetProfile(name)
+	if err != nil {
+		return err
+	}
+	settings.Profiles = settings.syncedProfiles()
+	settings.ActiveProfile = name
+	settings.Profile = profile
+	return nil
+}
+
+func (settings *Settings) AddProfile(profile Profile) error {
+	if profile.Name == "" {
+		return errors.New("profile name must not be empty")
+	}
+	if _, err := settings.GetProfile(profile.Name); err == nil {
+		return fmt.Errorf("profile %s already exists", profile.Name)
+	}
+	settings.Profiles = append(settings.syncedProfiles(), profile)
+	return nil
+}
+
+// RemoveProfile removes an inactive profile.
+func (settings *Settings) RemoveProfile(name string) error {
+	if name == settings.ActiveProfile {
+		return errors.New("the active profile can not be removed")
+	}
+	profiles := settings.syncedProfiles()
+	index := slices.IndexFunc(profiles, func(profile Profile) bool {
+		return profile.Name == name
+	})
+	if index < 0 {
+		return fmt.Errorf("profile %s not found", name)
+	}
+	settings.Profiles = slices.Delete(profiles, index, index+1)
+	return nil
+}
+
+// syncedProfiles returns a copy of the profiles with the values of the active profile, which are
+// only changed in the embedded Profile.
+func (settings Settings) syncedProfiles() []Profile {
+	profiles := slices.Clone(settings.Profiles)
+	for index := range profiles {
+		if profiles[index].Name == settings.ActiveProfile {
+			profiles[index] = settings.Profile
+			return profiles
+		}
+	}
+	return append(profiles, settings.Profile)
+}
+
+// activate sets the embedded Profile to the active profile after loading the settings.
+func (settings *Settings) activate() {
+	for _, profile := range settings.Profiles {
+		if profile.Name == settings.ActiveProfile {
+			settings.Profile = profile
+			return
+		}
+	}
+	if len(settings.Profiles) == 0 {
+		settings.Profiles = []Profile{{Name: DEFAULT_PROFILE}}
+	}
+	settings.Profile = settings.Profiles[0]
+	settings.ActiveProfile = settings.Profile.Name
+}
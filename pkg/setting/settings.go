@@ -1,10 +1,13 @@
 package setting
 
 import (
+	"bytes"
+	"errors"
+	"os"
 	"path"
 
-	"github.com/kardianos/osext"
-	"github.com/seternate/go-lanty/pkg/filesystem"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
 )
 
 const (
@@ -12,35 +15,181 @@ const (
 	SETTINGS_PATH    = "settings.yaml"
 	VERSION          = "v0.2.0"
 	DEFAULT_USERNAME = "lanty"
+
+	DEFAULT_SERVERURL         = "http://localhost:8080"
+	DEFAULT_GAMEDIRECTORY     = "extract"
+	DEFAULT_DOWNLOADDIRECTORY = "download"
 )
 
 type Settings struct {
-	ServerURL         string `yaml:"serverurl"`
-	GameDirectory     string `yaml:"gamedirectory"`
-	Username          string `yaml:"username"`
-	DownloadDirectory string `yaml:"downloaddirectory"`
+	Version       int    `yaml:"version"`
+	ActiveProfile string `yaml:"activeprofile"`
+	// Profile is the active profile, its values are written to the profiles when saving.
+	Profile  `yaml:"-"`
+	Profiles []Profile `yaml:"profiles"`
+	Hooks    []Hook    `yaml:"hooks,omitempty"`
+	// Unknown keys, e.g. written by a newer client version, are kept when the settings are saved.
+	Unknown map[string]any `yaml:",inline"`
+
+	sources   map[string]Source
+	overrides map[string]override
+	// keys are the keys set in the settings file.
+	keys map[string]bool
+}
+
+// LoadSettings loads the settings file of the config folder and migrates it to the current schema
+// version. A settings file of the executable folder or the working directory of older versions is
+// copied to the config folder first. A missing settings file is created with the defaults.
+// If the settings file is invalid, the newest valid backup or the defaults are returned together
+// with a RecoveryError. Empty settings are filled with the defaults and the layers of SetOverrides
+// are applied on top.
+func LoadSettings() (*Settings, error) {
+	settings, err := load()
+	if settings != nil {
+		settings.applyLayers(overrideLayers)
+	}
+	return settings, err
 }
 
-func LoadSettings() (s *Settings, err error) {
-	root, err := osext.ExecutableFolder()
+func load() (*Settings, error) {
+	directories, err := Dirs()
 	if err != nil {
-		return
+		return nil, err
 	}
-	err = filesystem.LoadFromYAMLFile(path.Join(root, SETTINGS_PATH), &s)
+	migrateLegacySettings(directories)
+	err = os.MkdirAll(directories.Config, 0755)
 	if err != nil {
-		err = filesystem.LoadFromYAMLFile(SETTINGS_PATH, &s)
+		return nil, err
+	}
+	filepath := path.Join(directories.Config, SETTINGS_PATH)
+	data, err := os.ReadFile(filepath)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Info().Str("path", filepath).Msg("settings file not found, creating it with the defaults")
+		settings := Default()
+		return settings, settings.save(filepath)
 	}
-	return
+	if err == nil {
+		var settings *Settings
+		var migrated bool
+		settings, migrated, err = parse(filepath, data)
+		if err == nil {
+			if migrated {
+				err = settings.save(filepath)
+			}
+			return settings, err
+		}
+	}
+	return recoverSettings(filepath, err)
 }
 
-func (settings Settings) Save() (err error) {
-	root, err := osext.ExecutableFolder()
+// ReadSettings reads the settings file with the override layers applied, but unlike LoadSettings it
+// never writes, migrates or recovers the file.
+func ReadSettings(filepath string) (*Settings, error) {
+	data, err := os.ReadFile(filepath)
 	if err != nil {
-		return
+		return nil, err
 	}
-	err = filesystem.SaveToYAMLFile(path.Join(root, SETTINGS_PATH), settings)
+	settings, _, err := parse("", data)
 	if err != nil {
-		err = filesystem.LoadFromYAMLFile(SETTINGS_PATH, settings)
+		return nil, err
+	}
+	settings.applyLayers(overrideLayers)
+	return settings, nil
+}
+
+// parse migrates the data of a settings file to the current schema version and returns if it was
+// migrated, filepath is the path of the settings file the migration backup is written for. No backup
+// is written if it is empty.
+func parse(filepath string, data []byte) (*Settings, bool, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, false, errors.New("settings file is empty")
+	}
+	raw := make(map[string]any)
+	err := yaml.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, false, err
+	}
+	migrated, err := migrate(filepath, data, raw)
+	if err != nil {
+		return nil, false, err
+	}
+	data, err = yaml.Marshal(raw)
+	if err != nil {
+		return nil, false, err
+	}
+	settings := &Settings{}
+	err = yaml.Unmarshal(data, settings)
+	if err != nil {
+		return nil, false, err
+	}
+	settings.keys = fileKeys(raw)
+	settings.activate()
+	return settings, migrated, nil
+}
+
+// Default returns the settings used when there is no valid settings file. Games are extracted and
+// downloaded to the data folder.
+func Default() *Settings {
+	profile := Profile{
+		Name:              DEFAULT_PROFILE,
+		ServerURL:         DEFAULT_SERVERURL,
+		GameDirectory:     DEFAULT_GAMEDIRECTORY,
+		Username:          DEFAULT_USERNAME,
+		DownloadDirectory: DEFAULT_DOWNLOADDIRECTORY,
 	}
-	return
+	directories, err := Dirs()
+	if err == nil {
+		profile.GameDirectory = path.Join(directories.Data, DEFAULT_GAMEDIRECTORY)
+		profile.DownloadDirectory = path.Join(directories.Data, DEFAULT_DOWNLOADDIRECTORY)
+	}
+	return &Settings{
+		Profile:       profile,
+		Version:       SCHEMA_VERSION,
+		ActiveProfile: profile.Name,
+		Profiles:      []Profile{profile},
+		Hooks:         []Hook{},
+	}
+}
+
+// Path returns the path of the settings file in the config folder.
+func Path() (string, error) {
+	directory, err := Directory()
+	if err != nil {
+		return "", err
+	}
+	return path.Join(directory, SETTINGS_PATH), nil
+}
+
+// Directory returns the config folder, which holds the settings file.
+func Directory() (string, error) {
+	directories, err := Dirs()
+	if err != nil {
+		return "", err
+	}
+	return directories.Config, nil
+}
+
+// Save writes the settings file of the config folder atomically.
+func (settings Settings) Save() error {
+	filepath, err := Path()
+	if err != nil {
+		return err
+	}
+	return settings.save(filepath)
+}
+
+func (settings Settings) save(filepath string) error {
+	data, err := yaml.Marshal(settings.persisted())
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath, data, 0644)
+}
+
+// persisted returns the settings as they are written to the settings file, with the active profile
+// in the profiles and without overrides.
+func (settings Settings) persisted() Settings {
+	settings.Profiles = settings.syncedProfiles()
+	settings.restoreOverrides()
+	return settings
 }
@@ -0,0 +1,260 @@
+package setting
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	FieldServerURL          = "serverurl"
+	FieldGameDirectory      = "gamedirectory"
+	FieldUsername           = "username"
+	FieldDownloadDirectory  = "downloaddirectory"
+	FieldBackupServerURLs   = "backupserverurls"
+	FieldCAFile             = "cafile"
+	FieldPinnedCertificates = "pinnedcertificates"
+
+	REACHABLE_TIMEOUT = 2 * time.Second
+)
+
+var (
+	ErrEmpty            = errors.New("must not be empty")
+	ErrMalformedURL     = errors.New("malformed URL")
+	ErrUnreachable      = errors.New("server unreachable")
+	ErrNotExist         = errors.New("does not exist")
+	ErrNotDirectory     = errors.New("not a folder")
+	ErrNotWritable      = errors.New("not writable")
+	ErrInvalidUsername  = errors.New("only alphanumeric characters and \"-\" allowed")
+	ErrUsernameConflict = errors.New("username already used by another user")
+	ErrNoCertificates   = errors.New("no PEM certificates found")
+	ErrFingerprint      = errors.New("not a SHA-256 fingerprint")
+)
+
+var usernameRegexp = regexp.MustCompile("^(?:[a-zA-Z]|[0-9]|-)+$")
+var fingerprintRegexp = regexp.MustCompile("^[0-9a-fA-F]{2}(?::?[0-9a-fA-F]{2}){31}$")
+
+// FieldError is a validation error of one setting. Err is one of the Err* errors, which can be
+// checked with errors.Is, and Cause holds details like the error of the operating system.
+type FieldError struct {
+	Field string
+	Value string
+	Err   error
+	Cause error
+}
+
+func (err *FieldError) Error() string {
+	if err.Cause != nil {
+		return fmt.Sprintf("%s: %v", err.Err, err.Cause)
+	}
+	return err.Err.Error()
+}
+
+func (err *FieldError) Unwrap() []error {
+	return []error{err.Err, err.Cause}
+}
+
+func newFieldError(field string, value string, err error, cause error) *FieldError {
+	return &FieldError{Field: field, Value: value, Err: err, Cause: cause}
+}
+
+// Validate validates all settings of the profile, which do not need the network or other users.
+func (profile Profile) Validate() error {
+	return errors.Join(
+		withField(ValidateServerURL(profile.ServerURL)),
+		withField(ValidateGameDirectory(profile.GameDirectory)),
+		withField(ValidateUsername(profile.Username)),
+		withField(ValidateDownloadDirectory(profile.DownloadDirectory)),
+		withField(ValidateBackupServerURLs(profile.BackupServerURLs)),
+		withField(ValidateCAFile(profile.CAFile)),
+		withField(ValidatePinnedCertificates(profile.PinnedCertificates)),
+	)
+}
+
+// withField prefixes the error with its field, the FieldError itself is shown next to the field.
+func withField(err error) error {
+	var fielderr *FieldError
+	if errors.As(err, &fielderr) {
+		return fmt.Errorf("%s: %w", fielderr.Field, err)
+	}
+	return err
+}
+
+func ValidateServerURL(serverurl string) error {
+	if serverurl == "" {
+		return newFieldError(FieldServerURL, serverurl, ErrEmpty, nil)
+	}
+	u, err := url.Parse(serverurl)
+	if err != nil {
+		return newFieldError(FieldServerURL, serverurl, ErrMalformedURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "ws" && u.Scheme != "wss" {
+		return newFieldError(FieldServerURL, serverurl, ErrMalformedURL, fmt.Errorf("unsupported scheme \"%s\"", u.Scheme))
+	}
+	if u.Hostname() == "" {
+		return newFieldError(FieldServerURL, serverurl, ErrMalformedURL, errors.New("missing host"))
+	}
+	return nil
+}
+
+// ValidateBackupServerURLs validates every backup server URL, an empty list is valid.
+func ValidateBackupServerURLs(serverurls []string) error {
+	for _, serverurl := range serverurls {
+		var fielderr *FieldError
+		if errors.As(ValidateServerURL(serverurl), &fielderr) {
+			return newFieldError(FieldBackupServerURLs, serverurl, fielderr.Err, fielderr.Cause)
+		}
+	}
+	return nil
+}
+
+// BaseURL returns the HTTP URL of the server URL, ws:// and wss:// server URLs are accepted for
+// http:// and https://.
+func BaseURL(serverurl string) string {
+	if scheme, rest, found := strings.Cut(serverurl, "://"); found {
+		switch strings.ToLower(scheme) {
+		case "ws":
+			return "http://" + rest
+		case "wss":
+			return "https://" + rest
+		}
+	}
+	return serverurl
+}
+
+// ValidateCAFile validates that the file holds PEM certificates, an empty path is valid.
+func ValidateCAFile(cafile string) error {
+	if cafile == "" {
+		return nil
+	}
+	pem, err := os.ReadFile(cafile)
+	if errors.Is(err, os.ErrNotExist) {
+		return newFieldError(FieldCAFile, cafile, ErrNotExist, nil)
+	}
+	if err != nil {
+		return newFieldError(FieldCAFile, cafile, ErrNotExist, err)
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(pem) {
+		return newFieldError(FieldCAFile, cafile, ErrNoCertificates, nil)
+	}
+	return nil
+}
+
+// ValidatePinnedCertificates validates that every pinned certificate is a SHA-256 fingerprint of
+// hex pairs, which may be separated by colons.
+func ValidatePinnedCertificates(fingerprints []string) error {
+	for _, fingerprint := range fingerprints {
+		if !fingerprintRegexp.MatchString(strings.TrimSpace(fingerprint)) {
+			return newFieldError(FieldPinnedCertificates, fingerprint, ErrFingerprint, nil)
+		}
+	}
+	return nil
+}
+
+// CheckServerURLReachable connects to the host of the server URL. An unreachable server is no
+// invalid setting, as the server may be started later.
+func CheckServerURLReachable(ctx context.Context, serverurl string) error {
+	err := ValidateServerURL(serverurl)
+	if err != nil {
+		return err
+	}
+	u, _ := url.Parse(BaseURL(serverurl))
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, REACHABLE_TIMEOUT)
+	defer cancel()
+	var dialer net.Dialer
+	connection, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return newFieldError(FieldServerURL, serverurl, ErrUnreachable, err)
+	}
+	connection.Close()
+	return nil
+}
+
+// ValidateGameDirectory validates that the folder, or its nearest existing parent if it is created
+// by the first download, is writable, so games can be extracted to it.
+func ValidateGameDirectory(gamedirectory string) error {
+	return validateDirectory(FieldGameDirectory, gamedirectory)
+}
+
+// ValidateDownloadDirectory validates that the folder, or its nearest existing parent if it is
+// created by the download, is writable.
+func ValidateDownloadDirectory(downloaddirectory string) error {
+	return validateDirectory(FieldDownloadDirectory, downloaddirectory)
+}
+
+func validateDirectory(field string, path string) error {
+	if path == "" {
+		return newFieldError(field, path, ErrEmpty, nil)
+	}
+	directory := path
+	for {
+		info, err := os.Stat(directory)
+		if err == nil {
+			if !info.IsDir() {
+				return newFieldError(field, path, ErrNotDirectory, fmt.Errorf("%s is a file", directory))
+			}
+			break
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return newFieldError(field, path, ErrNotWritable, err)
+		}
+		parent := filepath.Dir(directory)
+		if parent == directory {
+			return newFieldError(field, path, ErrNotExist, nil)
+		}
+		directory = parent
+	}
+	err := checkWritable(directory)
+	if err != nil {
+		return newFieldError(field, path, ErrNotWritable, err)
+	}
+	return nil
+}
+
+func ValidateUsername(username string) error {
+	if username == "" {
+		return newFieldError(FieldUsername, username, ErrEmpty, nil)
+	}
+	if !usernameRegexp.MatchString(username) {
+		return newFieldError(FieldUsername, username, ErrInvalidUsername, nil)
+	}
+	return nil
+}
+
+// CheckUsernameConflict validates the username and that it is not one of the taken usernames.
+func CheckUsernameConflict(username string, taken []string) error {
+	err := ValidateUsername(username)
+	if err != nil {
+		return err
+	}
+	for _, name := range taken {
+		if name == username {
+			return newFieldError(FieldUsername, username, ErrUsernameConflict, nil)
+		}
+	}
+	return nil
+}
+
+func checkWritable(directory string) error {
+	file, err := os.CreateTemp(directory, ".lanty-write-test-*")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
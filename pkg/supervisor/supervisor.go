@@ -0,0 +1,202 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/seternate/go-lanty-client/pkg/retry"
+)
+
+// MAX_RESTARTS is the number of restarts in a row after which a routine is stopped.
+const MAX_RESTARTS = 5
+
+type Panic struct {
+	Name     string
+	Fields   map[string]string
+	Value    any
+	Stack    []byte
+	Restarts int
+	// Stopped is true if the routine is not restarted anymore.
+	Stopped bool
+}
+
+type Option func(routine *routine)
+
+// WithField adds context like the game slug or user to the logs and reports of a panic.
+func WithField(key string, value string) Option {
+	return func(routine *routine) {
+		routine.fields[key] = value
+	}
+}
+
+// WithoutRestart is used for one-shot routines which can not be run again after a panic.
+func WithoutRestart() Option {
+	return func(routine *routine) {
+		routine.restart = false
+	}
+}
+
+// WithBackoff counts the restarts with the backoff instead of one of the routine, so the restarts of
+// routines which are started again, e.g. the routines of a restarted component, are counted together.
+func WithBackoff(backoff *retry.Backoff) Option {
+	return func(routine *routine) {
+		routine.backoff = backoff
+	}
+}
+
+// WithRestart calls restart after the backoff instead of running the function again, e.g. to restart
+// the component the routine belongs to together with its other routines.
+func WithRestart(restart func()) Option {
+	return func(routine *routine) {
+		routine.restartFunc = restart
+	}
+}
+
+// WithRecovered sets a function which is called after a panic, e.g. to clean up the state the routine left.
+func WithRecovered(recovered func(value any)) Option {
+	return func(routine *routine) {
+		routine.recovered = recovered
+	}
+}
+
+type routine struct {
+	name        string
+	fields      map[string]string
+	restart     bool
+	restartFunc func()
+	backoff     *retry.Backoff
+	recovered   func(value any)
+}
+
+// Supervisor runs goroutines which are recovered from panics and restarted with the backoff of the
+// policy.
+type Supervisor struct {
+	waitgrp *sync.WaitGroup
+	policy  retry.Policy
+	onPanic func(recovered Panic)
+}
+
+func NewSupervisor(waitgrp *sync.WaitGroup, onPanic func(recovered Panic)) *Supervisor {
+	return &Supervisor{
+		waitgrp: waitgrp,
+		policy:  DefaultPolicy(),
+		onPanic: onPanic,
+	}
+}
+
+// DefaultPolicy returns the backoff of the restarts, which gives up after MAX_RESTARTS restarts in a
+// row. The circuit breaker is disabled, the maximum restarts already stop a failing routine.
+func DefaultPolicy() retry.Policy {
+	policy := retry.DefaultPolicy().WithMaxAttempts(MAX_RESTARTS)
+	policy.BreakerThreshold = 0
+	return policy
+}
+
+// Go runs the function in a goroutine tracked by the waitgroup. The function is restarted after a
+// panic until it panicked more than MAX_RESTARTS times in a row or the context is done. A run longer
+// than the maximum backoff resets the restarts. The returned channel is closed once the routine
+// finished.
+func (supervisor *Supervisor) Go(ctx context.Context, name string, function func(), options ...Option) <-chan struct{} {
+	routine := &routine{
+		name:    name,
+		fields:  make(map[string]string),
+		restart: true,
+	}
+	for _, option := range options {
+		option(routine)
+	}
+	if routine.backoff == nil {
+		routine.backoff = retry.NewBackoff(supervisor.policy)
+	}
+	done := make(chan struct{})
+	supervisor.waitgrp.Add(1)
+	go supervisor.run(ctx, routine, function, done)
+	return done
+}
+
+func (supervisor *Supervisor) run(ctx context.Context, routine *routine, function func(), done chan struct{}) {
+	defer supervisor.waitgrp.Done()
+	defer close(done)
+	for {
+		start := time.Now()
+		value, stack, panicked := call(function)
+		if !panicked {
+			return
+		}
+		if time.Since(start) > supervisor.policy.Max {
+			routine.backoff.Success()
+		}
+		restarts := routine.backoff.Failures()
+		stopped := !routine.restart || routine.backoff.Exhausted() || ctx.Err() != nil
+		supervisor.report(routine, Panic{
+			Name:     routine.name,
+			Fields:   routine.fields,
+			Value:    value,
+			Stack:    stack,
+			Restarts: restarts,
+			Stopped:  stopped,
+		})
+		if stopped {
+			return
+		}
+		delay := routine.backoff.Failure()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		log.Info().Str("routine", routine.name).Fields(fields(routine.fields)).Int("restarts", restarts+1).Msg("restarting routine after panic")
+		if routine.restartFunc != nil {
+			routine.restartFunc()
+			return
+		}
+	}
+}
+
+func (supervisor *Supervisor) report(routine *routine, recovered Panic) {
+	log.Error().
+		Str("routine", recovered.Name).
+		Fields(fields(recovered.Fields)).
+		Interface("panic", recovered.Value).
+		Str("stack", string(recovered.Stack)).
+		Int("restarts", recovered.Restarts).
+		Bool("stopped", recovered.Stopped).
+		Msg("recovered from panic")
+	if routine.recovered != nil {
+		_, _, panicked := call(func() { routine.recovered(recovered.Value) })
+		if panicked {
+			log.Error().Str("routine", recovered.Name).Msg("panic in recovered function")
+		}
+	}
+	if supervisor.onPanic != nil {
+		supervisor.onPanic(recovered)
+	}
+}
+
+func call(function func()) (value any, stack []byte, panicked bool) {
+	defer func() {
+		value = recover()
+		if value != nil {
+			stack = debug.Stack()
+			panicked = true
+		}
+	}()
+	function()
+	return
+}
+
+func (recovered Panic) String() string {
+	return fmt.Sprintf("panic in %s: %v", recovered.Name, recovered.Value)
+}
+
+func fields(values map[string]string) map[string]any {
+	fields := make(map[string]any, len(values))
+	for key, value := range values {
+		fields[key] = value
+	}
+	return fields
+}
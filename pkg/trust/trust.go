@@ -0,0 +1,205 @@
+package trust
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrNoCertificates      = errors.New("no certificates found")
+	ErrFingerprintMismatch = errors.New("certificate does not match the pinned fingerprint")
+)
+
+// Config is how the certificate of the server is verified. Without pinned fingerprints the
+// certificate has to be signed by a system CA or one of the CA file, with pinned fingerprints the
+// certificate has to be one of them instead.
+type Config struct {
+	CAFile             string
+	Fingerprints       []string
+	InsecureSkipVerify bool
+}
+
+// UntrustedError is the error of a server certificate which could not be verified. It holds the
+// fingerprint, so the user can decide to trust the certificate.
+type UntrustedError struct {
+	Host        string
+	Fingerprint string
+	Err         error
+}
+
+func (err *UntrustedError) Error() string {
+	return fmt.Sprintf("certificate of %s (SHA-256 %s) not trusted: %v", err.Host, err.Fingerprint, err.Err)
+}
+
+func (err *UntrustedError) Unwrap() error {
+	return err.Err
+}
+
+// Fingerprint returns the SHA-256 fingerprint of the certificate as hex pairs separated by colons.
+func Fingerprint(certificate *x509.Certificate) string {
+	sum := sha256.Sum256(certificate.Raw)
+	pairs := make([]string, len(sum))
+	for index, b := range sum {
+		pairs[index] = hex.EncodeToString([]byte{b})
+	}
+	return strings.ToUpper(strings.Join(pairs, ":"))
+}
+
+// NormalizeFingerprint returns the fingerprint in the format of Fingerprint, it may be written with
+// or without colons and in any case.
+func NormalizeFingerprint(fingerprint string) string {
+	fingerprint = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(fingerprint), ":", ""))
+	pairs := make([]string, 0, len(fingerprint)/2)
+	for index := 0; index < len(fingerprint); index += 2 {
+		pairs = append(pairs, fingerprint[index:min(index+2, len(fingerprint))])
+	}
+	return strings.Join(pairs, ":")
+}
+
+// Verifier verifies the certificates of all TLS connections to the server. Its transport dials
+// through the verifier, so the configuration can be changed at runtime without replacing it.
+type Verifier struct {
+	config    Config
+	roots     *x509.CertPool
+	transport *http.Transport
+	mutex     sync.RWMutex
+
+	// OnUntrusted is called for every connection with a certificate which is not trusted.
+	OnUntrusted func(err *UntrustedError)
+}
+
+func NewVerifier() *Verifier {
+	verifier := &Verifier{}
+	verifier.transport = http.DefaultTransport.(*http.Transport).Clone()
+	verifier.transport.DialTLSContext = verifier.DialTLSContext
+	// Requests through a proxy would be tunneled without DialTLSContext and not be verified, the
+	// servers are on the local network anyway.
+	verifier.transport.Proxy = nil
+	return verifier
+}
+
+// SetConfig loads the CA file and closes idle connections, so the next requests are verified with
+// the configuration. The configuration is not changed if the CA file can not be loaded.
+func (verifier *Verifier) SetConfig(config Config) error {
+	var roots *x509.CertPool
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return err
+		}
+		roots, err = x509.SystemCertPool()
+		if err != nil {
+			log.Warn().Err(err).Msg("error loading system certificates, only the CA file is trusted")
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("%s: %w", config.CAFile, ErrNoCertificates)
+		}
+	}
+	fingerprints := make([]string, 0, len(config.Fingerprints))
+	for _, fingerprint := range config.Fingerprints {
+		fingerprints = append(fingerprints, NormalizeFingerprint(fingerprint))
+	}
+	config.Fingerprints = fingerprints
+
+	verifier.mutex.Lock()
+	verifier.config = config
+	verifier.roots = roots
+	verifier.mutex.Unlock()
+	verifier.transport.CloseIdleConnections()
+	return nil
+}
+
+func (verifier *Verifier) Config() Config {
+	defer verifier.mutex.RUnlock()
+	verifier.mutex.RLock()
+	return verifier.config
+}
+
+// DialTLSContext connects to the address and verifies the certificate of the host with the
+// current configuration.
+func (verifier *Verifier) DialTLSContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	var dialer net.Dialer
+	connection, err := dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	client := tls.Client(connection, &tls.Config{
+		ServerName: host,
+		// The certificate is verified by verify, which knows the pinned fingerprints and CA file.
+		InsecureSkipVerify: true,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			return verifier.verify(host, state)
+		},
+	})
+	err = client.HandshakeContext(ctx)
+	if err != nil {
+		connection.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// Transport returns the HTTP transport dialing through the verifier, it does not use a proxy.
+func (verifier *Verifier) Transport() *http.Transport {
+	return verifier.transport
+}
+
+func (verifier *Verifier) verify(host string, state tls.ConnectionState) error {
+	verifier.mutex.RLock()
+	config := verifier.config
+	roots := verifier.roots
+	verifier.mutex.RUnlock()
+	if config.InsecureSkipVerify {
+		return nil
+	}
+	if len(state.PeerCertificates) == 0 {
+		return ErrNoCertificates
+	}
+
+	leaf := state.PeerCertificates[0]
+	fingerprint := Fingerprint(leaf)
+	var err error
+	if len(config.Fingerprints) > 0 {
+		if !slices.Contains(config.Fingerprints, fingerprint) {
+			err = ErrFingerprintMismatch
+		}
+	} else {
+		intermediates := x509.NewCertPool()
+		for _, certificate := range state.PeerCertificates[1:] {
+			intermediates.AddCert(certificate)
+		}
+		_, err = leaf.Verify(x509.VerifyOptions{
+			DNSName:       host,
+			Roots:         roots,
+			Intermediates: intermediates,
+		})
+	}
+	if err == nil {
+		return nil
+	}
+
+	untrusted := &UntrustedError{Host: host, Fingerprint: fingerprint, Err: err}
+	log.Warn().Err(untrusted).Msg("rejected server certificate")
+	if verifier.OnUntrusted != nil {
+		verifier.OnUntrusted(untrusted)
+	}
+	return untrusted
+}
@@ -9,7 +9,9 @@ import (
 	"fyne.io/fyne/v2/widget"
 	"github.com/rs/zerolog/log"
 	"github.com/seternate/go-lanty-client/pkg/controller"
+	"github.com/seternate/go-lanty-client/pkg/event"
 	"github.com/seternate/go-lanty-client/pkg/theme"
+	"github.com/seternate/go-lanty/pkg/chat"
 )
 
 type ChatBrowser struct {
@@ -21,7 +23,7 @@ type ChatBrowser struct {
 	messageentry *widget.Entry
 	sendbutton   *widget.Button
 	filebutton   *widget.Button
-	newmessage   chan struct{}
+	newmessage   chan event.Event[chat.Message]
 }
 
 func NewChatBrowser(controller *controller.Controller, window fyne.Window) (chatbrowser *ChatBrowser) {
@@ -30,7 +32,7 @@ func NewChatBrowser(controller *controller.Controller, window fyne.Window) (chat
 		window:       window,
 		messageboard: NewMessageBoard(controller),
 		messageentry: widget.NewMultiLineEntry(),
-		newmessage:   make(chan struct{}, 50),
+		newmessage:   make(chan event.Event[chat.Message], 50),
 		sendbutton:   widget.NewButtonWithIcon("", fynetheme.MailSendIcon(), nil),
 		filebutton:   widget.NewButtonWithIcon("", fynetheme.MailAttachmentIcon(), nil),
 	}
@@ -78,12 +80,10 @@ func (widget *ChatBrowser) fileUploadCallback() {
 }
 
 func (widget *ChatBrowser) run() {
-	widget.controller.WaitGroup().Add(1)
-	go widget.messageUpdater()
+	widget.controller.Go("ChatBrowser.messageUpdater", widget.messageUpdater)
 }
 
 func (widget *ChatBrowser) messageUpdater() {
-	defer widget.controller.WaitGroup().Done()
 	for {
 		select {
 		case <-widget.controller.Context().Done():
@@ -1,63 +1,183 @@
 package widget
 
 import (
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	fynetheme "fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	"github.com/rs/zerolog/log"
 	"github.com/seternate/go-lanty-client/pkg/controller"
+	"github.com/seternate/go-lanty-client/pkg/event"
 	"github.com/seternate/go-lanty-client/pkg/setting"
 	"github.com/seternate/go-lanty-client/pkg/theme"
+	"github.com/seternate/go-lanty-client/pkg/trust"
 )
 
+// Connectionbar shows the connection status and latency, tapping it shows the connection details.
+// It asks the user to trust server certificates which are not trusted and for the event password
+// if the server rejects it.
 type Connectionbar struct {
 	widget.BaseWidget
 
-	controller    *controller.Controller
-	statusupdated chan struct{}
-	statustext    string
+	controller     *controller.Controller
+	window         fyne.Window
+	profile        *ProfileSelect
+	statusupdated  chan event.Event[controller.ConnectionStatus]
+	qualityupdated chan event.Event[controller.ConnectionQuality]
+	untrusted      chan event.Event[trust.UntrustedError]
+	unauthorized   chan event.Event[string]
+	quality        controller.ConnectionQuality
+	statustext     string
 }
 
-func NewConnectionbar(controller *controller.Controller) *Connectionbar {
+func NewConnectionbar(controller *controller.Controller, window fyne.Window) *Connectionbar {
 	connectionbar := &Connectionbar{
-		controller:    controller,
-		statusupdated: make(chan struct{}, 50),
-		statustext:    "UNKNOWN",
+		controller: controller,
+		window:     window,
+		profile:    NewProfileSelect(controller),
+		statustext: "UNKNOWN",
 	}
 	connectionbar.ExtendBaseWidget(connectionbar)
 
-	controller.Connection.Subscribe(connectionbar.statusupdated)
-	controller.WaitGroup().Add(1)
-	go connectionbar.statusUpdater()
+	connectionbar.run()
 
 	return connectionbar
 }
 
+func (widget *Connectionbar) run() {
+	widget.statusupdated = make(chan event.Event[controller.ConnectionStatus], 50)
+	widget.controller.Connection.Subscribe(widget.statusupdated)
+	widget.qualityupdated = make(chan event.Event[controller.ConnectionQuality], 50)
+	widget.controller.Connection.SubscribeQuality(widget.qualityupdated)
+	widget.untrusted = make(chan event.Event[trust.UntrustedError], 50)
+	widget.controller.Connection.SubscribeUntrusted(widget.untrusted)
+	widget.unauthorized = make(chan event.Event[string], 50)
+	widget.controller.Connection.SubscribeUnauthorized(widget.unauthorized)
+	widget.controller.Go("Connectionbar.statusUpdater", widget.statusUpdater)
+}
+
 func (widget *Connectionbar) statusUpdater() {
-	defer widget.controller.WaitGroup().Done()
-	widget.updateStatus()
 	for {
 		select {
 		case <-widget.controller.Context().Done():
 			log.Trace().Msg("exiting connectionbar statusUpdater()")
 			return
-		case <-widget.statusupdated:
+		case event := <-widget.statusupdated:
+			widget.quality.Status = event.Data
+			widget.updateStatus()
+			widget.Refresh()
+		case event := <-widget.qualityupdated:
+			widget.quality = event.Data
 			widget.updateStatus()
 			widget.Refresh()
+		case event := <-widget.untrusted:
+			widget.confirmCertificate(event.Data)
+		case event := <-widget.unauthorized:
+			widget.promptPassword(event.Data)
 		}
 	}
 }
 
+// confirmCertificate asks the user to trust the certificate, which pins it in the active profile.
+func (widget *Connectionbar) confirmCertificate(untrusted trust.UntrustedError) {
+	reason := "is not signed by a trusted CA"
+	if errors.Is(untrusted.Err, trust.ErrFingerprintMismatch) {
+		reason = "does not match the pinned certificates and may have been changed or be an attack"
+	}
+	text := fmt.Sprintf("The certificate of %s %s.\n\nSHA-256 fingerprint:\n%s\n\nOnly trust it if the fingerprint matches the one of the server.",
+		untrusted.Host, reason, untrusted.Fingerprint)
+	dialog.ShowConfirm("Trust server certificate?", text, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		err := widget.controller.Settings.TrustCertificate(untrusted.Fingerprint)
+		if err == nil {
+			widget.controller.Status.Info("Trusted certificate of "+untrusted.Host, 3*time.Second)
+		}
+	}, widget.window)
+}
+
 func (widget *Connectionbar) updateStatus() {
-	status := widget.controller.Connection.Status
-	if status == controller.Connected {
-		widget.statustext = fmt.Sprintf("Connected to server: %s", widget.controller.Settings.Settings().ServerURL)
-	} else if status == controller.Disconnected {
-		widget.statustext = fmt.Sprintf("Error connecting to server: %s", widget.controller.Settings.Settings().ServerURL)
+	serverurl := widget.controller.Connection.ServerURL()
+	switch widget.quality.Status {
+	case controller.Connecting:
+		widget.statustext = fmt.Sprintf("Connecting to server: %s", serverurl)
+	case controller.Connected:
+		widget.statustext = fmt.Sprintf("Connected to server: %s (%s)", serverurl, formatRTT(widget.quality.RTT))
+	case controller.Degraded:
+		widget.statustext = fmt.Sprintf("Degraded connection to server: %s (%s, %.0f%% loss)", serverurl, formatRTT(widget.quality.RTT), widget.quality.Loss*100)
+	case controller.ServerIncompatible:
+		widget.statustext = fmt.Sprintf("Incompatible server: %s", serverurl)
+	case controller.Unauthorized:
+		widget.statustext = fmt.Sprintf("Event password rejected by server: %s", serverurl)
+	default:
+		widget.statustext = fmt.Sprintf("Error connecting to server: %s", serverurl)
+	}
+}
+
+// promptPassword asks for the event password of the server, which is stored in the active profile.
+func (w *Connectionbar) promptPassword(serverurl string) {
+	password := widget.NewPasswordEntry()
+	password.SetText(w.controller.Settings.Password())
+	item := widget.NewFormItem("Password", password)
+	item.HintText = "The server " + serverurl + " needs an event password or rejected it"
+	dialog.ShowForm("Event password", "Log in", "Cancel", []*widget.FormItem{item}, func(confirmed bool) {
+		if confirmed {
+			w.controller.Settings.SetPassword(password.Text)
+		}
+	}, w.window)
+}
+
+// Tapped shows the connection details, the profile select handles its taps itself.
+func (w *Connectionbar) Tapped(*fyne.PointEvent) {
+	quality := w.controller.Connection.Quality()
+	details := widget.NewForm(
+		widget.NewFormItem("Server", widget.NewLabel(w.controller.Connection.ServerURL())),
+		widget.NewFormItem("Status", widget.NewLabel(quality.Status.String())),
+		widget.NewFormItem("Latency", widget.NewLabel(formatRTT(quality.RTT))),
+		widget.NewFormItem("Packet loss", widget.NewLabel(fmt.Sprintf("%.0f%%", quality.Loss*100))),
+	)
+	for _, subsystem := range quality.Subsystems {
+		text := "ok since " + subsystem.Since.Format(time.TimeOnly)
+		if !subsystem.OK {
+			text = fmt.Sprintf("failing since %s: %s", subsystem.Since.Format(time.TimeOnly), subsystem.Err)
+		}
+		label := widget.NewLabel(text)
+		label.Wrapping = fyne.TextWrapWord
+		details.Append(string(subsystem.Subsystem), label)
+	}
+
+	history := w.controller.Connection.History()
+	lines := make([]string, 0, len(history))
+	for i := len(history) - 1; i >= 0; i-- {
+		change := history[i]
+		line := fmt.Sprintf("%s  %s -> %s", change.Time.Format(time.TimeOnly), change.From, change.To)
+		if change.Err != "" {
+			line += ": " + change.Err
+		}
+		lines = append(lines, line)
 	}
+	historytext := widget.NewLabel(strings.Join(lines, "\n"))
+	historytext.Wrapping = fyne.TextWrapWord
+
+	content := container.NewBorder(container.NewVBox(details, widget.NewLabelWithStyle("History", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})), nil, nil, nil, container.NewVScroll(historytext))
+	detailsdialog := dialog.NewCustom("Connection", "Close", content, w.window)
+	detailsdialog.Resize(fyne.NewSize(700, 500))
+	detailsdialog.Show()
+}
+
+func formatRTT(rtt time.Duration) string {
+	if rtt == 0 {
+		return "unknown latency"
+	}
+	return fmt.Sprintf("%d ms", rtt.Milliseconds())
 }
 
 func (widget *Connectionbar) CreateRenderer() fyne.WidgetRenderer {
@@ -87,6 +207,7 @@ func (renderer *connectionbarRenderer) Objects() []fyne.CanvasObject {
 		renderer.line,
 		renderer.status,
 		renderer.version,
+		renderer.widget.profile,
 	}
 	return objects
 }
@@ -97,7 +218,11 @@ func (renderer *connectionbarRenderer) Layout(size fyne.Size) {
 	versiontextsize := fyne.MeasureText(renderer.version.Text, renderer.version.TextSize, renderer.version.TextStyle)
 	statustextsize := fyne.MeasureText(renderer.status.Text, renderer.status.TextSize, renderer.status.TextStyle)
 
+	profilesize := renderer.widget.profile.MinSize()
+
 	renderer.version.Move(fyne.NewPos(theme.InnerPadding(), (size.Height-versiontextsize.Height)/2))
+	renderer.widget.profile.Resize(profilesize)
+	renderer.widget.profile.Move(fyne.NewPos(versiontextsize.Width+2*theme.InnerPadding(), (size.Height-profilesize.Height)/2))
 	renderer.status.Move(fyne.NewPos(size.Width-statustextsize.Width-theme.InnerPadding(), (size.Height-statustextsize.Height)/2))
 }
 
@@ -105,8 +230,10 @@ func (renderer *connectionbarRenderer) MinSize() fyne.Size {
 	versiontextsize := fyne.MeasureText(renderer.version.Text, renderer.version.TextSize, renderer.version.TextStyle)
 	statustextsize := fyne.MeasureText(renderer.status.Text, renderer.status.TextSize, renderer.status.TextStyle)
 
-	minHeight := fyne.Max(versiontextsize.Height, statustextsize.Height) + theme.InnerPadding()
-	minWidth := versiontextsize.Width + statustextsize.Width + 3*theme.InnerPadding()
+	profilesize := renderer.widget.profile.MinSize()
+
+	minHeight := fyne.Max(fyne.Max(versiontextsize.Height, statustextsize.Height)+theme.InnerPadding(), profilesize.Height)
+	minWidth := versiontextsize.Width + profilesize.Width + statustextsize.Width + 4*theme.InnerPadding()
 
 	return fyne.NewSize(minWidth, minHeight)
 }
@@ -116,6 +243,7 @@ func (renderer *connectionbarRenderer) Refresh() {
 	renderer.status.Text = renderer.widget.statustext
 	renderer.status.Refresh()
 	renderer.version.Refresh()
+	renderer.widget.profile.Refresh()
 }
 
 func (renderer *connectionbarRenderer) Destroy() {}
@@ -30,6 +30,10 @@ func (widget *DefaultUsernameBrowser) SetOnSubmit(onSubmit func()) {
 	widget.settingsbrowser.SetOnSubmit(onSubmit)
 }
 
+func (widget *DefaultUsernameBrowser) DiscoverServers() {
+	widget.settingsbrowser.DiscoverServers()
+}
+
 func (widget *DefaultUsernameBrowser) CreateRenderer() fyne.WidgetRenderer {
 	return newDefaulusernamebrowserRenderer(widget)
 }
@@ -7,6 +7,7 @@ import (
 	"fyne.io/fyne/v2/widget"
 	"github.com/rs/zerolog/log"
 	"github.com/seternate/go-lanty-client/pkg/controller"
+	"github.com/seternate/go-lanty-client/pkg/event"
 	"github.com/seternate/go-lanty-client/pkg/theme"
 )
 
@@ -16,43 +17,38 @@ type DownloadBrowser struct {
 	controller    *controller.Controller
 	downloadtiles []*DownloadTile
 
-	newdownload           chan struct{}
-	downloadstatusupdated chan struct{}
+	newdownload           chan event.Event[*controller.Download]
+	downloadstatusupdated chan event.Event[controller.DownloadEvent]
 }
 
 func NewDownloadBrowser(controller *controller.Controller) (downloadbrowser *DownloadBrowser) {
 	downloadbrowser = &DownloadBrowser{
-		controller:            controller,
-		downloadtiles:         make([]*DownloadTile, 0),
-		newdownload:           make(chan struct{}, 50),
-		downloadstatusupdated: make(chan struct{}, 50),
+		controller:    controller,
+		downloadtiles: make([]*DownloadTile, 0),
 	}
 	downloadbrowser.ExtendBaseWidget(downloadbrowser)
 
-	controller.Download.Subscribe(downloadbrowser.newdownload)
 	downloadbrowser.run()
 
 	return downloadbrowser
 }
 
 func (widget *DownloadBrowser) run() {
-	widget.controller.WaitGroup().Add(2)
-	go widget.downloadUpdater()
-	go widget.downloadStatusUpdater()
+	widget.newdownload = make(chan event.Event[*controller.Download], 50)
+	widget.downloadstatusupdated = make(chan event.Event[controller.DownloadEvent], 50)
+	widget.controller.Download.Subscribe(widget.newdownload)
+	widget.controller.Go("DownloadBrowser.downloadUpdater", widget.downloadUpdater)
+	widget.controller.Go("DownloadBrowser.downloadStatusUpdater", widget.downloadStatusUpdater)
 }
 
 func (widget *DownloadBrowser) downloadUpdater() {
-	defer widget.controller.WaitGroup().Done()
 	for {
 		select {
 		case <-widget.controller.Context().Done():
 			log.Trace().Msg("exiting downloadbrowser downloadUpdater()")
 			return
-		case <-widget.newdownload:
-			download := widget.controller.Download.GetLastQueued()
-			if len(widget.downloadtiles) > 0 && widget.downloadtiles[len(widget.downloadtiles)-1].download == download {
-				continue
-			}
+		case event := <-widget.newdownload:
+			download := event.Data
 			widget.downloadtiles = append(widget.downloadtiles, NewDownloadTile(download))
 			download.Subscribe(widget.downloadstatusupdated)
 			widget.Refresh()
@@ -61,14 +57,15 @@ func (widget *DownloadBrowser) downloadUpdater() {
 }
 
 func (widget *DownloadBrowser) downloadStatusUpdater() {
-	defer widget.controller.WaitGroup().Done()
 	for {
 		select {
 		case <-widget.controller.Context().Done():
 			log.Trace().Msg("exiting downloadbrowser downloadStatusUpdater()")
 			return
-		case <-widget.downloadstatusupdated:
-			widget.Refresh()
+		case event := <-widget.downloadstatusupdated:
+			if event.Topic == controller.TopicDownloadStatus {
+				widget.Refresh()
+			}
 		}
 	}
 }
@@ -10,6 +10,8 @@ import (
 	"fyne.io/fyne/v2/widget"
 	"github.com/rs/zerolog/log"
 	"github.com/seternate/go-lanty-client/pkg/controller"
+	"github.com/seternate/go-lanty-client/pkg/event"
+	"github.com/seternate/go-lanty-client/pkg/supervisor"
 	"github.com/seternate/go-lanty-client/pkg/theme"
 )
 
@@ -20,8 +22,7 @@ type DownloadTile struct {
 	download    *controller.Download
 	progressbar *widget.ProgressBar
 
-	downloadstatusupdated chan struct{}
-	progress              chan struct{}
+	downloadstatusupdated chan event.Event[controller.DownloadEvent]
 }
 
 func NewDownloadTile(download *controller.Download) (downloadtile *DownloadTile) {
@@ -29,8 +30,7 @@ func NewDownloadTile(download *controller.Download) (downloadtile *DownloadTile)
 		controller:            download.Controller(),
 		download:              download,
 		progressbar:           widget.NewProgressBar(),
-		downloadstatusupdated: make(chan struct{}, 50),
-		progress:              make(chan struct{}, 50),
+		downloadstatusupdated: make(chan event.Event[controller.DownloadEvent], 50),
 	}
 	downloadtile.ExtendBaseWidget(downloadtile)
 
@@ -46,48 +46,34 @@ func NewDownloadTile(download *controller.Download) (downloadtile *DownloadTile)
 		}
 		return fmt.Sprintf("%.0f%% (%.0f MB/s)", downloadtile.progressbar.Value*100, download.BytesPerSecond()/(1024*1024))
 	}
-	download.Subscribe(downloadtile.downloadstatusupdated)
-	download.SubscribeProgress(downloadtile.progress)
-
 	downloadtile.run()
 
 	return downloadtile
 }
 
 func (widget *DownloadTile) run() {
-	widget.controller.WaitGroup().Add(2)
-	go widget.downloadStatusUpdater()
-	go widget.progressUpdater()
+	widget.controller.Go("DownloadTile.downloadStatusUpdater", widget.downloadStatusUpdater, supervisor.WithField("slug", widget.download.Game().Slug))
 }
 
 func (widget *DownloadTile) downloadStatusUpdater() {
-	defer widget.controller.WaitGroup().Done()
+	widget.download.Subscribe(widget.downloadstatusupdated)
+	defer widget.download.Unsubscribe(widget.downloadstatusupdated)
 	for !widget.download.IsComplete() {
 		select {
 		case <-widget.controller.Context().Done():
 			log.Trace().Msg("exiting downloadtile downloadStatusUpdater()")
 			return
-		case <-widget.downloadstatusupdated:
+		case event := <-widget.downloadstatusupdated:
+			if event.Topic == controller.TopicDownloadProgress {
+				widget.progressbar.SetValue(event.Data.Progress)
+			}
 			widget.Refresh()
 		}
 	}
+	widget.progressbar.SetValue(widget.download.Progress())
 	widget.Refresh()
 }
 
-func (widget *DownloadTile) progressUpdater() {
-	defer widget.controller.WaitGroup().Done()
-	for !widget.download.IsComplete() {
-		select {
-		case <-widget.controller.Context().Done():
-			log.Trace().Msg("exiting downloadtile downloadStatusUpdater()")
-			return
-		case <-widget.progress:
-			widget.progressbar.SetValue(widget.download.Progress())
-			widget.Refresh()
-		}
-	}
-}
-
 func (widget *DownloadTile) CreateRenderer() fyne.WidgetRenderer {
 	return newDownloadTileRenderer(widget)
 }
@@ -127,6 +127,7 @@ func (renderer *formRenderer) MinSize() fyne.Size {
 }
 
 func (renderer *formRenderer) Refresh() {
+	renderer.Layout(renderer.widget.Size())
 	for _, item := range renderer.widget.items {
 		item.Refresh()
 	}
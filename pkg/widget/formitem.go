@@ -11,6 +11,7 @@ import (
 type FormItem struct {
 	widget.BaseWidget
 	text   string
+	err    error
 	Widget fyne.CanvasObject
 }
 
@@ -23,6 +24,12 @@ func NewFormItem(text string, widget fyne.CanvasObject) (formitem *FormItem) {
 	return
 }
 
+// SetError shows the error below the widget, nil hides it.
+func (widget *FormItem) SetError(err error) {
+	widget.err = err
+	widget.Refresh()
+}
+
 func (widget *FormItem) CreateRenderer() fyne.WidgetRenderer {
 	widget.ExtendBaseWidget(widget)
 	return newFormItemRenderer(widget)
@@ -34,6 +41,7 @@ type formItemRenderer struct {
 	textbackground *canvas.Rectangle
 	text           *canvas.Text
 	itembackground *canvas.Rectangle
+	errortext      *canvas.Text
 }
 
 func newFormItemRenderer(widget *FormItem) (renderer *formItemRenderer) {
@@ -43,7 +51,10 @@ func newFormItemRenderer(widget *FormItem) (renderer *formItemRenderer) {
 		textbackground: canvas.NewRectangle(fynetheme.InputBorderColor()),
 		text:           canvas.NewText(widget.text, theme.ForegroundColor()),
 		itembackground: canvas.NewRectangle(fynetheme.InputBackgroundColor()),
+		errortext:      canvas.NewText("", fynetheme.ErrorColor()),
 	}
+	renderer.errortext.TextSize = fynetheme.CaptionTextSize()
+	renderer.errortext.Hide()
 	renderer.text.TextSize = 18
 	renderer.text.TextStyle.Bold = true
 	renderer.textbackground.CornerRadius = fynetheme.SelectionRadiusSize()
@@ -57,6 +68,7 @@ func (renderer *formItemRenderer) Objects() []fyne.CanvasObject {
 		renderer.text,
 		renderer.itembackground,
 		renderer.widget.Widget,
+		renderer.errortext,
 	}
 }
 
@@ -69,7 +81,15 @@ func (renderer *formItemRenderer) Layout(size fyne.Size) {
 	renderer.itembackground.Move(fyne.NewPos(renderer.itemoffset, renderer.textbackground.Size().Height+theme.InnerPadding()))
 	renderer.itembackground.Resize(fyne.NewSize(size.Width-renderer.itemoffset, size.Height-renderer.itembackground.Position().Y))
 	renderer.widget.Widget.Move(fyne.NewPos(renderer.itembackground.Position().X+theme.InnerPadding(), renderer.itembackground.Position().Y+theme.InnerPadding()))
-	renderer.widget.Widget.Resize(renderer.itembackground.Size().SubtractWidthHeight(2*theme.InnerPadding(), 2*theme.InnerPadding()))
+	renderer.widget.Widget.Resize(renderer.itembackground.Size().SubtractWidthHeight(2*theme.InnerPadding(), 2*theme.InnerPadding()+renderer.errorHeight()))
+	renderer.errortext.Move(renderer.widget.Widget.Position().AddXY(0, renderer.widget.Widget.Size().Height+theme.InnerPadding()))
+}
+
+func (renderer *formItemRenderer) errorHeight() float32 {
+	if renderer.errortext.Hidden {
+		return 0
+	}
+	return fyne.MeasureText(renderer.errortext.Text, renderer.errortext.TextSize, renderer.errortext.TextStyle).Height + theme.InnerPadding()
 }
 
 func (renderer *formItemRenderer) MinSize() fyne.Size {
@@ -77,7 +97,7 @@ func (renderer *formItemRenderer) MinSize() fyne.Size {
 	minsizeheader = minsizeheader.AddWidthHeight(2*theme.InnerPadding(), 2*theme.InnerPadding())
 
 	minsizeitem := renderer.widget.Widget.MinSize()
-	minsizeitem = minsizeitem.AddWidthHeight(renderer.itemoffset+2*theme.InnerPadding(), 2*theme.InnerPadding())
+	minsizeitem = minsizeitem.AddWidthHeight(renderer.itemoffset+2*theme.InnerPadding(), 2*theme.InnerPadding()+renderer.errorHeight())
 
 	return fyne.NewSize(fyne.Max(minsizeheader.Width, minsizeitem.Width), minsizeheader.Height+minsizeitem.Height+theme.InnerPadding())
 }
@@ -87,6 +107,15 @@ func (renderer *formItemRenderer) Refresh() {
 	renderer.text.Refresh()
 	renderer.widget.Widget.Refresh()
 	renderer.itembackground.Refresh()
+	if renderer.widget.err != nil {
+		renderer.errortext.Text = renderer.widget.err.Error()
+		renderer.errortext.Show()
+	} else {
+		renderer.errortext.Text = ""
+		renderer.errortext.Hide()
+	}
+	renderer.errortext.Refresh()
+	renderer.Layout(renderer.widget.Size())
 }
 
 func (renderer *formItemRenderer) Destroy() {}
@@ -5,9 +5,11 @@ import (
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	fynetheme "fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	"github.com/rs/zerolog/log"
 	"github.com/seternate/go-lanty-client/pkg/controller"
+	"github.com/seternate/go-lanty-client/pkg/event"
 	"github.com/seternate/go-lanty-client/pkg/layout"
 	"github.com/seternate/go-lanty/pkg/game"
 )
@@ -17,6 +19,7 @@ type GameBrowser struct {
 
 	controller *controller.Controller
 	gametiles  []*GameTile
+	offline    bool
 	//joinserver  *JoinServer
 	//startserver *StartServer
 
@@ -24,26 +27,25 @@ type GameBrowser struct {
 	OnStartServerTapped func(game game.Game)
 	OnCancelTapped      func()
 
-	gamesupdated          chan struct{}
-	gametileContext       context.Context
-	cancelGametileContext context.CancelFunc
+	gamesupdated   chan event.Event[game.Game]
+	gametileCancel map[string]context.CancelFunc
 }
 
 func NewGameBrowser(controller *controller.Controller) (gamebrowser *GameBrowser) {
 	gamebrowser = &GameBrowser{
-		controller:   controller,
-		gametiles:    make([]*GameTile, 0, 50),
-		gamesupdated: make(chan struct{}, 50),
+		controller:     controller,
+		gametiles:      make([]*GameTile, 0, 50),
+		gamesupdated:   make(chan event.Event[game.Game], 50),
+		gametileCancel: make(map[string]context.CancelFunc),
 	}
 	//gamebrowser.joinserver = NewJoinServer(controller, gamebrowser)
 	//gamebrowser.startserver = NewStartServer(controller, gamebrowser)
-	gamebrowser.gametileContext, gamebrowser.cancelGametileContext = context.WithCancel(controller.Context())
 	gamebrowser.ExtendBaseWidget(gamebrowser)
 
+	gamebrowser.offline = controller.Game.IsOffline()
 	gamebrowser.updateGametiles()
 	controller.Game.Subscribe(gamebrowser.gamesupdated)
-	controller.WaitGroup().Add(1)
-	go gamebrowser.gamesUpdater()
+	controller.Go("GameBrowser.gamesUpdater", gamebrowser.gamesUpdater)
 
 	return
 }
@@ -64,59 +66,130 @@ func (widget *GameBrowser) CreateRenderer() fyne.WidgetRenderer {
 }
 
 func (widget *GameBrowser) gamesUpdater() {
-	defer widget.controller.WaitGroup().Done()
 	for {
 		select {
 		case <-widget.controller.Context().Done():
 			log.Trace().Msg("exiting gamebrowser gamesUpdater()")
 			return
-		case <-widget.gamesupdated:
-			widget.updateGametiles()
+		case e := <-widget.gamesupdated:
+			//Games are published one by one, handle all pending events to only rebuild the tiles once
+			events := []event.Event[game.Game]{e}
+			for len(widget.gamesupdated) > 0 {
+				events = append(events, <-widget.gamesupdated)
+			}
+			rebuild := false
+			changed := make([]string, 0)
+			for _, e := range events {
+				switch e.Topic {
+				case controller.TopicGameIcon:
+					widget.updateGametileIcon(e.Data)
+				case controller.TopicGameAdded:
+					rebuild = true
+				case controller.TopicGameUpdated, controller.TopicGameRemoved:
+					rebuild = true
+					changed = append(changed, e.Data.Slug)
+				case controller.TopicGameOffline:
+					widget.offline = widget.controller.Game.IsOffline()
+					rebuild = true
+				}
+			}
+			if rebuild {
+				widget.updateGametiles(changed...)
+			}
 		}
 	}
 }
 
-func (widget *GameBrowser) updateGametiles() {
-	widget.cancelGametileContext()
-	widget.gametileContext, widget.cancelGametileContext = context.WithCancel(widget.controller.Context())
-	gametiles := make([]*GameTile, 0, len(widget.controller.Game.GetGames().Games()))
-	for _, g := range widget.controller.Game.GetGames().Games() {
-		gametile := NewGameTile(widget.gametileContext, widget.controller, g)
-		gametiles = append(gametiles, gametile)
-		gametile.OnJoinServerTapped = func(game game.Game) {
-			if widget.OnJoinServerTapped != nil {
-				widget.OnJoinServerTapped(game)
-			}
+func (widget *GameBrowser) updateGametileIcon(game game.Game) {
+	for _, gametile := range widget.gametiles {
+		if gametile.game.Slug == game.Slug {
+			gametile.SetIcon(widget.controller.Game.GetIcon(game))
 		}
-		gametile.OnStartServerTapped = func(game game.Game) {
-			if widget.OnStartServerTapped != nil {
-				widget.OnStartServerTapped(game)
-			}
+	}
+}
+
+// updateGametiles keeps the tiles of unchanged games and only creates tiles for added or changed games.
+func (widget *GameBrowser) updateGametiles(changed ...string) {
+	gametiles := make(map[string]*GameTile, len(widget.gametiles))
+	for _, gametile := range widget.gametiles {
+		gametiles[gametile.game.Slug] = gametile
+	}
+	for _, slug := range changed {
+		delete(gametiles, slug)
+		widget.cancelGametile(slug)
+	}
+
+	games := widget.controller.Game.GetGames().Games()
+	newGametiles := make([]*GameTile, 0, len(games))
+	for _, g := range games {
+		gametile, found := gametiles[g.Slug]
+		if !found {
+			gametile = widget.newGametile(g)
 		}
-		gametile.OnCancelTapped = func() {
-			if widget.OnCancelTapped != nil {
-				widget.OnCancelTapped()
-			}
+		delete(gametiles, g.Slug)
+		newGametiles = append(newGametiles, gametile)
+	}
+	for slug := range gametiles {
+		widget.cancelGametile(slug)
+	}
+	widget.setGametiles(newGametiles...)
+}
+
+func (widget *GameBrowser) newGametile(g game.Game) *GameTile {
+	ctx, cancel := context.WithCancel(widget.controller.Context())
+	widget.gametileCancel[g.Slug] = cancel
+	gametile := NewGameTile(ctx, widget.controller, g)
+	gametile.OnJoinServerTapped = func(game game.Game) {
+		if widget.OnJoinServerTapped != nil {
+			widget.OnJoinServerTapped(game)
+		}
+	}
+	gametile.OnStartServerTapped = func(game game.Game) {
+		if widget.OnStartServerTapped != nil {
+			widget.OnStartServerTapped(game)
 		}
 	}
-	widget.setGametiles(gametiles...)
+	gametile.OnCancelTapped = func() {
+		if widget.OnCancelTapped != nil {
+			widget.OnCancelTapped()
+		}
+	}
+	return gametile
+}
+
+func (widget *GameBrowser) cancelGametile(slug string) {
+	if cancel, found := widget.gametileCancel[slug]; found {
+		cancel()
+		delete(widget.gametileCancel, slug)
+	}
+}
+
+// newOfflineBadge returns the badge shown above the games while they are loaded from the cache.
+func newOfflineBadge() *fyne.Container {
+	label := widget.NewLabel("Offline - showing the cached games, installed games can still be played")
+	label.Importance = widget.WarningImportance
+	return container.NewHBox(widget.NewIcon(fynetheme.WarningIcon()), label)
 }
 
 type gameBrowserRenderer struct {
 	widget    *GameBrowser
+	offline   *fyne.Container
 	gametiles *fyne.Container
 }
 
 func newGameBrowserRenderer(widget *GameBrowser) *gameBrowserRenderer {
 	renderer := &gameBrowserRenderer{
 		widget:    widget,
+		offline:   newOfflineBadge(),
 		gametiles: container.New(layout.NewGridScalingLayout(3)),
 	}
+	renderer.offline.Hidden = !widget.offline
 	return renderer
 }
 
 func (renderer *gameBrowserRenderer) Objects() []fyne.CanvasObject {
 	objects := []fyne.CanvasObject{
+		renderer.offline,
 		renderer.gametiles,
 	}
 
@@ -124,15 +197,31 @@ func (renderer *gameBrowserRenderer) Objects() []fyne.CanvasObject {
 }
 
 func (renderer *gameBrowserRenderer) Layout(size fyne.Size) {
-	renderer.gametiles.Resize(size)
-	renderer.gametiles.Move(fyne.NewPos(0, 0))
+	top := float32(0)
+	if renderer.offline.Visible() {
+		top = renderer.offline.MinSize().Height
+		renderer.offline.Resize(fyne.NewSize(size.Width, top))
+		renderer.offline.Move(fyne.NewPos(0, 0))
+	}
+	renderer.gametiles.Resize(fyne.NewSize(size.Width, size.Height-top))
+	renderer.gametiles.Move(fyne.NewPos(0, top))
 }
 
 func (renderer *gameBrowserRenderer) MinSize() fyne.Size {
-	return renderer.gametiles.MinSize()
+	minSize := renderer.gametiles.MinSize()
+	if renderer.offline.Visible() {
+		minSize.Height += renderer.offline.MinSize().Height
+		minSize.Width = fyne.Max(minSize.Width, renderer.offline.MinSize().Width)
+	}
+	return minSize
 }
 
 func (renderer *gameBrowserRenderer) Refresh() {
+	if renderer.widget.offline {
+		renderer.offline.Show()
+	} else {
+		renderer.offline.Hide()
+	}
 	renderer.gametiles.RemoveAll()
 	for _, gametile := range renderer.widget.gametiles {
 		renderer.gametiles.Add(gametile)
@@ -13,6 +13,8 @@ import (
 	"fyne.io/fyne/v2/widget"
 	"github.com/rs/zerolog/log"
 	"github.com/seternate/go-lanty-client/pkg/controller"
+	"github.com/seternate/go-lanty-client/pkg/event"
+	"github.com/seternate/go-lanty-client/pkg/supervisor"
 	"github.com/seternate/go-lanty-client/pkg/theme"
 	"github.com/seternate/go-lanty/pkg/filesystem"
 	"github.com/seternate/go-lanty/pkg/game"
@@ -28,9 +30,8 @@ type GameTile struct {
 	buttons     map[string]*widget.Button
 
 	download              *controller.Download
-	newdownload           chan struct{}
-	downloadstatusupdated chan struct{}
-	progress              chan struct{}
+	newdownload           chan event.Event[*controller.Download]
+	downloadstatusupdated chan event.Event[controller.DownloadEvent]
 	context               context.Context
 
 	OnJoinServerTapped  func(game game.Game)
@@ -54,10 +55,7 @@ func NewGameTile(context context.Context, controller *controller.Controller, gam
 			"startserver": widget.NewButtonWithIcon("Start Server", fynetheme.MailForwardIcon(), nil),
 			"cancel":      widget.NewButtonWithIcon("Cancel", fynetheme.CancelIcon(), nil),
 		},
-		newdownload:           make(chan struct{}, 50),
-		downloadstatusupdated: make(chan struct{}, 50),
-		progress:              make(chan struct{}, 50),
-		context:               context,
+		context: context,
 	}
 	gametile.ExtendBaseWidget(gametile)
 
@@ -114,7 +112,6 @@ func NewGameTile(context context.Context, controller *controller.Controller, gam
 	gametile.Refresh()
 
 	gametile.showDefaultControls()
-	controller.Download.Subscribe(gametile.newdownload)
 	gametile.run()
 
 	return gametile
@@ -144,22 +141,28 @@ func (widget *GameTile) showPlayControls() {
 	widget.Refresh()
 }
 
+func (widget *GameTile) SetIcon(icon image.Image) {
+	widget.icon = icon
+	widget.Refresh()
+}
+
 func (widget *GameTile) CreateRenderer() fyne.WidgetRenderer {
 	return newGametileRenderer(widget)
 }
 
 func (widget *GameTile) run() {
-	widget.controller.WaitGroup().Add(4)
-	go widget.downloadUpdater()
-	go widget.downloadStatusUpdater()
-	go widget.progressUpdater()
-	go widget.gameAvailabilityUpdater()
+	widget.newdownload = make(chan event.Event[*controller.Download], 50)
+	widget.downloadstatusupdated = make(chan event.Event[controller.DownloadEvent], 50)
+	slug := supervisor.WithField("slug", widget.game.Slug)
+	widget.controller.Go("GameTile.downloadUpdater", widget.downloadUpdater, slug)
+	widget.controller.Go("GameTile.downloadStatusUpdater", widget.downloadStatusUpdater, slug)
+	widget.controller.Go("GameTile.gameAvailabilityUpdater", widget.gameAvailabilityUpdater, slug)
 }
 
 // TODO event based model should be used
 func (widget *GameTile) gameAvailabilityUpdater() {
-	defer widget.controller.WaitGroup().Done()
 	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
 	for {
 		select {
 		case <-widget.context.Done():
@@ -188,51 +191,47 @@ func (widget *GameTile) updatePlayAndOpenButtonStatus() {
 }
 
 func (widget *GameTile) downloadUpdater() {
-	defer widget.controller.WaitGroup().Done()
+	widget.controller.Download.Subscribe(widget.newdownload)
+	defer widget.controller.Download.Unsubscribe(widget.newdownload)
 	for {
 		select {
 		case <-widget.context.Done():
 			log.Trace().Str("slug", widget.game.Slug).Msg("exiting gametile downloadUpdater()")
+			if widget.download != nil {
+				widget.download.Unsubscribe(widget.downloadstatusupdated)
+			}
 			return
-		case <-widget.newdownload:
-			download, err := widget.controller.Download.GetLatest(widget.game)
-			if err != nil {
+		case event := <-widget.newdownload:
+			download := event.Data
+			if !download.Game().Equal(widget.game) {
 				continue
 			}
 			if widget.download != nil {
 				widget.download.Unsubscribe(widget.downloadstatusupdated)
-				widget.download.UnsubscribeProgress(widget.progress)
 			}
-			download.Subscribe(widget.downloadstatusupdated)
-			download.SubscribeProgress(widget.progress)
 			widget.download = download
+			download.Subscribe(widget.downloadstatusupdated)
 			widget.Refresh()
 		}
 	}
 }
 
 func (widget *GameTile) downloadStatusUpdater() {
-	defer widget.controller.WaitGroup().Done()
 	for {
 		select {
 		case <-widget.context.Done():
 			log.Trace().Str("slug", widget.game.Slug).Msg("exiting gametile downloadStatusUpdater()")
 			return
-		case <-widget.downloadstatusupdated:
-			widget.Refresh()
-		}
-	}
-}
-
-func (widget *GameTile) progressUpdater() {
-	defer widget.controller.WaitGroup().Done()
-	for {
-		select {
-		case <-widget.context.Done():
-			log.Trace().Str("slug", widget.game.Slug).Msg("exiting gametile progressUpdater()")
-			return
-		case <-widget.progress:
-			widget.progressbar.SetValue(widget.download.Progress())
+		case event := <-widget.downloadstatusupdated:
+			if event.Data.Download != widget.download {
+				continue
+			}
+			switch event.Topic {
+			case controller.TopicDownloadProgress:
+				widget.progressbar.SetValue(event.Data.Progress)
+			default:
+				widget.Refresh()
+			}
 		}
 	}
 }
@@ -333,6 +332,7 @@ func (renderer *gametileRenderer) Refresh() {
 	}
 
 	renderer.background.Refresh()
+	renderer.icon.Image = renderer.widget.icon
 	renderer.icon.Refresh()
 	renderer.name.Refresh()
 	renderer.widget.progressbar.Refresh()
@@ -8,6 +8,8 @@ import (
 	"fyne.io/fyne/v2/widget"
 	"github.com/rs/zerolog/log"
 	"github.com/seternate/go-lanty-client/pkg/controller"
+	"github.com/seternate/go-lanty-client/pkg/diagnostics"
+	"github.com/seternate/go-lanty-client/pkg/event"
 	"github.com/seternate/go-lanty-client/pkg/setting"
 	"github.com/seternate/go-lanty-client/pkg/theme"
 	"github.com/seternate/go-lanty/pkg/game"
@@ -31,9 +33,10 @@ type Lanty struct {
 	chatbrowser            *fyne.Container
 	defaultusernamebrowser *ScrollWithState
 
-	resetSettingsBrowser func()
+	resetSettingsBrowser          func()
+	setSettingsDiagnosticExporter func(exporter *diagnostics.Exporter)
 
-	statusupdate chan struct{}
+	statusupdate chan event.Event[controller.Status]
 }
 
 func NewLanty(controller *controller.Controller, window fyne.Window) *Lanty {
@@ -47,7 +50,7 @@ func NewLanty(controller *controller.Controller, window fyne.Window) *Lanty {
 
 	lanty := &Lanty{
 		controller:             controller,
-		connectionbar:          NewConnectionbar(controller),
+		connectionbar:          NewConnectionbar(controller, window),
 		sidebar:                NewSidebar(setting.APPLICATION_NAME),
 		gamebrowser:            NewVScrollWithState(gamebrowser),
 		startserver:            NewVScrollWithState(startserver),
@@ -61,7 +64,9 @@ func NewLanty(controller *controller.Controller, window fyne.Window) *Lanty {
 		resetSettingsBrowser: func() {
 			settingsbrowser.ResetData()
 		},
-		statusupdate: make(chan struct{}, 50),
+		setSettingsDiagnosticExporter: func(exporter *diagnostics.Exporter) {
+			settingsbrowser.SetDiagnosticsExporter(exporter)
+		},
 	}
 	lanty.ExtendBaseWidget(lanty)
 
@@ -122,18 +127,28 @@ func NewLanty(controller *controller.Controller, window fyne.Window) *Lanty {
 	if controller.Settings.Settings().Username == setting.DEFAULT_USERNAME {
 		lanty.defaultusernamebrowser.Show()
 		lanty.hideAll()
+		// New players usually do not know the server URL, so the network is searched right away.
+		defaultusernamebrowser.DiscoverServers()
 	} else {
 		lanty.defaultusernamebrowser.Hide()
 		lanty.showGameBrowser()
 	}
 
-	controller.Status.Subscribe(lanty.statusupdate)
-	controller.WaitGroup().Add(1)
-	go lanty.statusbarUpdater()
+	lanty.run()
 
 	return lanty
 }
 
+func (widget *Lanty) SetDiagnosticsExporter(exporter *diagnostics.Exporter) {
+	widget.setSettingsDiagnosticExporter(exporter)
+}
+
+func (widget *Lanty) run() {
+	widget.statusupdate = make(chan event.Event[controller.Status], 50)
+	widget.controller.Status.Subscribe(widget.statusupdate)
+	widget.controller.Go("Lanty.statusbarUpdater", widget.statusbarUpdater)
+}
+
 func (widget *Lanty) hideAll() {
 	widget.sidebar.Hide()
 	widget.gamebrowser.Hide()
@@ -232,7 +247,6 @@ func (widget *Lanty) showJoinServer() {
 }
 
 func (widget *Lanty) statusbarUpdater() {
-	defer widget.controller.WaitGroup().Done()
 	for {
 		select {
 		case <-widget.controller.Context().Done():
@@ -2,9 +2,7 @@ package widget
 
 import (
 	"container/list"
-	"slices"
 	"strings"
-	"sync"
 	"time"
 
 	"fyne.io/fyne/v2"
@@ -13,27 +11,28 @@ import (
 	"fyne.io/fyne/v2/widget"
 	"github.com/rs/zerolog/log"
 	"github.com/seternate/go-lanty-client/pkg/controller"
+	"github.com/seternate/go-lanty-client/pkg/event"
 	"github.com/seternate/go-lanty-client/pkg/theme"
 	"github.com/seternate/go-lanty/pkg/chat"
-	"github.com/seternate/go-lanty/pkg/util"
 	"golang.design/x/clipboard"
 )
 
+const topicMessageAdded event.Topic = "messageboard.added"
+
 type MessageBoard struct {
 	widget.BaseWidget
 	controller   *controller.Controller
 	messagetiles *list.List
-	newmessage   chan chat.Message
-	subscriber   []chan struct{}
-	mutex        sync.RWMutex
+	newmessage   chan event.Event[chat.Message]
+	events       *event.EventBus[chat.Message]
 }
 
 func NewMessageBoard(controller *controller.Controller) (messageboard *MessageBoard) {
 	messageboard = &MessageBoard{
 		controller:   controller,
 		messagetiles: list.New(),
-		newmessage:   make(chan chat.Message, 50),
-		subscriber:   make([]chan struct{}, 50),
+		newmessage:   make(chan event.Event[chat.Message], 50),
+		events:       event.NewEventBus[chat.Message](),
 	}
 	messageboard.ExtendBaseWidget(messageboard)
 
@@ -43,34 +42,27 @@ func NewMessageBoard(controller *controller.Controller) (messageboard *MessageBo
 	return
 }
 
-func (controller *MessageBoard) Subscribe(subscriber chan struct{}) {
-	defer controller.mutex.Unlock()
-	controller.mutex.Lock()
-	controller.subscriber = append(controller.subscriber, subscriber)
+func (widget *MessageBoard) Subscribe(subscriber chan event.Event[chat.Message]) {
+	widget.events.Subscribe(subscriber, topicMessageAdded)
 }
 
-func (controller *MessageBoard) Unsubscribe(subscriber chan struct{}) {
-	defer controller.mutex.Unlock()
-	controller.mutex.Lock()
-	index := slices.Index(controller.subscriber, subscriber)
-	_ = slices.Delete(controller.subscriber, index, index+1)
+func (widget *MessageBoard) Unsubscribe(subscriber chan event.Event[chat.Message]) {
+	widget.events.Unsubscribe(subscriber)
 }
 
 func (widget *MessageBoard) run() {
-	widget.controller.WaitGroup().Add(1)
-	go widget.messageUpdater()
+	widget.controller.Go("MessageBoard.messageUpdater", widget.messageUpdater)
 }
 
 func (widget *MessageBoard) messageUpdater() {
-	defer widget.controller.WaitGroup().Done()
 	for {
 		select {
 		case <-widget.controller.Context().Done():
 			log.Trace().Msg("exiting messageboard messageUpdater()")
 			return
-		case message := <-widget.newmessage:
-			log.Trace().Interface("message", message).Msg("messageboard received new message")
-			widget.addMessageTile(message)
+		case event := <-widget.newmessage:
+			log.Trace().Interface("message", event.Data).Msg("messageboard received new message")
+			widget.addMessageTile(event.Data)
 		}
 	}
 }
@@ -95,15 +87,7 @@ func (widget *MessageBoard) addMessageTile(message chat.Message) {
 	}
 	widget.messagetiles.PushFront(messagetile)
 	widget.Refresh()
-	widget.notifySubcriber()
-}
-
-func (controller *MessageBoard) notifySubcriber() {
-	defer controller.mutex.RUnlock()
-	controller.mutex.RLock()
-	for _, subscriber := range controller.subscriber {
-		util.ChannelWriteNonBlocking(subscriber, struct{}{})
-	}
+	widget.events.Publish(topicMessageAdded, message)
 }
 
 func (browser *MessageBoard) CreateRenderer() fyne.WidgetRenderer {
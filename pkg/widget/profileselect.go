@@ -0,0 +1,61 @@
+package widget
+
+import (
+	"fyne.io/fyne/v2/widget"
+	"github.com/rs/zerolog/log"
+	"github.com/seternate/go-lanty-client/pkg/controller"
+	"github.com/seternate/go-lanty-client/pkg/event"
+	"github.com/seternate/go-lanty-client/pkg/setting"
+)
+
+// ProfileSelect switches the active server profile.
+type ProfileSelect struct {
+	widget.Select
+
+	controller     *controller.Controller
+	profilechanged chan event.Event[setting.Settings]
+}
+
+func NewProfileSelect(controller *controller.Controller) *ProfileSelect {
+	profileselect := &ProfileSelect{
+		controller: controller,
+	}
+	profileselect.ExtendBaseWidget(profileselect)
+	profileselect.PlaceHolder = "Select profile"
+	profileselect.update(controller.Settings.Settings())
+	profileselect.OnChanged = func(name string) {
+		if name != profileselect.controller.Settings.Settings().ActiveProfile {
+			profileselect.controller.Settings.SwitchProfile(name)
+		}
+	}
+
+	profileselect.run()
+
+	return profileselect
+}
+
+func (widget *ProfileSelect) run() {
+	widget.profilechanged = make(chan event.Event[setting.Settings], 50)
+	widget.controller.Settings.Subscribe(widget.profilechanged, controller.TopicSettingsProfile)
+	widget.controller.Go("ProfileSelect.profileUpdater", widget.profileUpdater)
+}
+
+func (widget *ProfileSelect) profileUpdater() {
+	for {
+		select {
+		case <-widget.controller.Context().Done():
+			widget.controller.Settings.Unsubscribe(widget.profilechanged)
+			log.Trace().Msg("exiting ProfileSelect profileUpdater()")
+			return
+		case event := <-widget.profilechanged:
+			widget.update(event.Data)
+			widget.Refresh()
+		}
+	}
+}
+
+func (widget *ProfileSelect) update(settings setting.Settings) {
+	widget.Options = settings.ProfileNames()
+	// Setting the field directly does not call OnChanged, which would switch the profile again.
+	widget.Selected = settings.ActiveProfile
+}
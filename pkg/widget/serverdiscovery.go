@@ -0,0 +1,89 @@
+package widget
+
+import (
+	"fmt"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/seternate/go-lanty-client/pkg/controller"
+	"github.com/seternate/go-lanty-client/pkg/discovery"
+	"github.com/seternate/go-lanty-client/pkg/supervisor"
+)
+
+// ServerDiscovery lists the Lanty servers found on the local network, selecting one calls OnSelected.
+type ServerDiscovery struct {
+	widget.BaseWidget
+
+	controller *controller.Controller
+	search     *widget.Button
+	text       *widget.Label
+	servers    *fyne.Container
+	searching  bool
+	mutex      sync.Mutex
+
+	OnSelected func(server discovery.Server)
+}
+
+func NewServerDiscovery(controller *controller.Controller) *ServerDiscovery {
+	serverdiscovery := &ServerDiscovery{
+		controller: controller,
+		text:       widget.NewLabel("Search the network for servers"),
+		servers:    container.NewVBox(),
+	}
+	serverdiscovery.ExtendBaseWidget(serverdiscovery)
+	serverdiscovery.search = widget.NewButtonWithIcon("Find servers", theme.SearchIcon(), serverdiscovery.Search)
+	return serverdiscovery
+}
+
+// Search discovers the servers in the background, a running search is not started again.
+func (widget *ServerDiscovery) Search() {
+	widget.mutex.Lock()
+	if widget.searching {
+		widget.mutex.Unlock()
+		return
+	}
+	widget.searching = true
+	widget.mutex.Unlock()
+
+	widget.search.Disable()
+	widget.text.SetText("Searching...")
+	widget.controller.Go("ServerDiscovery.search", func() {
+		servers, err := widget.controller.Settings.Discover(widget.controller.Context())
+		widget.update(servers, err)
+		widget.mutex.Lock()
+		widget.searching = false
+		widget.mutex.Unlock()
+		widget.search.Enable()
+	}, supervisor.WithoutRestart())
+}
+
+func (w *ServerDiscovery) update(servers []discovery.Server, err error) {
+	w.servers.RemoveAll()
+	switch {
+	case err != nil:
+		w.text.SetText("Error searching servers: " + err.Error())
+	case len(servers) == 0:
+		w.text.SetText("No servers found")
+	default:
+		w.text.SetText(fmt.Sprintf("%d server(s) found", len(servers)))
+	}
+	for _, server := range servers {
+		server := server
+		text := fmt.Sprintf("%s - %s (%d ms)", server.Name, server.URL, server.Latency.Milliseconds())
+		w.servers.Add(widget.NewButtonWithIcon(text, theme.ComputerIcon(), func() {
+			if w.OnSelected != nil {
+				w.OnSelected(server)
+			}
+		}))
+	}
+	w.Refresh()
+}
+
+func (w *ServerDiscovery) CreateRenderer() fyne.WidgetRenderer {
+	w.ExtendBaseWidget(w)
+	header := container.NewBorder(nil, nil, w.search, nil, w.text)
+	return widget.NewSimpleRenderer(container.NewVBox(header, w.servers))
+}
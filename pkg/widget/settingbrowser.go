@@ -2,7 +2,8 @@ package widget
 
 import (
 	"errors"
-	"regexp"
+	"fmt"
+	"strings"
 	"time"
 
 	"fyne.io/fyne/v2"
@@ -13,9 +14,15 @@ import (
 	"fyne.io/fyne/v2/widget"
 	"github.com/rs/zerolog/log"
 	"github.com/seternate/go-lanty-client/pkg/controller"
+	"github.com/seternate/go-lanty-client/pkg/diagnostics"
+	"github.com/seternate/go-lanty-client/pkg/discovery"
+	"github.com/seternate/go-lanty-client/pkg/event"
 	"github.com/seternate/go-lanty-client/pkg/setting"
+	"github.com/seternate/go-lanty-client/pkg/supervisor"
 )
 
+const SETTINGS_BUNDLE_FILENAME = "lanty-settings.yaml"
+
 type SettingsBrowser struct {
 	widget.BaseWidget
 
@@ -23,14 +30,32 @@ type SettingsBrowser struct {
 	window     fyne.Window
 	form       *Form
 
-	serverurl         *Entry
-	gamedirectory     *Entry
-	username          *Entry
-	downloaddirectory *Entry
+	profile               *ProfileSelect
+	serverurl             *Entry
+	serverurlitem         *FormItem
+	backupserverurls      *Entry
+	backupserverurlsitem  *FormItem
+	password              *Entry
+	serverdiscovery       *ServerDiscovery
+	gamedirectory         *Entry
+	gamedirectoryitem     *FormItem
+	username              *Entry
+	usernameitem          *FormItem
+	downloaddirectory     *Entry
+	downloaddirectoryitem *FormItem
+	cafile                *Entry
+	cafileitem            *FormItem
+	pinned                *Entry
+	pinneditem            *FormItem
+	insecure              *widget.Check
+	exportsettings        *widget.Button
+	importsettings        *widget.Button
+	diagnostics           *widget.Button
+	exporter              *diagnostics.Exporter
 
 	OnSubmit func()
 
-	settingschanged chan struct{}
+	settingschanged chan event.Event[setting.Settings]
 }
 
 func NewSettingsBrowser(controller *controller.Controller, window fyne.Window) (settingsbrowser *SettingsBrowser) {
@@ -38,89 +63,177 @@ func NewSettingsBrowser(controller *controller.Controller, window fyne.Window) (
 		controller:        controller,
 		window:            window,
 		form:              NewForm(),
+		profile:           NewProfileSelect(controller),
 		serverurl:         NewEntry(),
+		backupserverurls:  NewEntry(),
+		password:          NewEntry(),
+		serverdiscovery:   NewServerDiscovery(controller),
 		gamedirectory:     NewEntry(),
 		username:          NewEntry(),
 		downloaddirectory: NewEntry(),
-		settingschanged:   make(chan struct{}, 50),
+		cafile:            NewEntry(),
+		pinned:            NewEntry(),
+		settingschanged:   make(chan event.Event[setting.Settings], 50),
 	}
 	settingsbrowser.ExtendBaseWidget(settingsbrowser)
 
+	addprofile := widget.NewButtonWithIcon("", theme.ContentAddIcon(), settingsbrowser.addProfileCallback)
+	removeprofile := widget.NewButtonWithIcon("", theme.ContentRemoveIcon(), settingsbrowser.removeProfileCallback)
+	profile := container.NewBorder(nil, nil, nil, container.NewHBox(addprofile, removeprofile), settingsbrowser.profile)
+	settingsbrowser.form.AppendItem(NewFormItem("Profile", profile))
+
 	settingsbrowser.serverurl.SetText(controller.Settings.Settings().ServerURL)
+	settingsbrowser.serverurl.Validator = setting.ValidateServerURL
 	settingsbrowser.serverurl.OnFocusChanged = func(b bool) {
 		if !b {
-			controller.Settings.SetServerURL(settingsbrowser.serverurl.Text)
+			settingsbrowser.setServerURL()
 		}
 	}
 	settingsbrowser.serverurl.OnSubmitted = func(s string) {
-		controller.Settings.SetServerURL(settingsbrowser.serverurl.Text)
+		settingsbrowser.setServerURL()
+	}
+	settingsbrowser.serverurlitem = NewFormItem("Server URL", settingsbrowser.serverurl)
+	settingsbrowser.form.AppendItem(settingsbrowser.serverurlitem)
+
+	settingsbrowser.backupserverurls.SetText(strings.Join(controller.Settings.Settings().BackupServerURLs, ", "))
+	settingsbrowser.backupserverurls.SetPlaceHolder("Comma separated, tried in order if the server is down")
+	settingsbrowser.backupserverurls.Validator = func(s string) error {
+		return setting.ValidateBackupServerURLs(setting.SplitList(s))
+	}
+	settingsbrowser.backupserverurls.OnFocusChanged = func(b bool) {
+		if !b {
+			settingsbrowser.setBackupServerURLs()
+		}
+	}
+	settingsbrowser.backupserverurls.OnSubmitted = func(s string) {
+		settingsbrowser.setBackupServerURLs()
 	}
-	settingsbrowser.form.AppendItem(NewFormItem("Server URL", settingsbrowser.serverurl))
+	settingsbrowser.backupserverurlsitem = NewFormItem("Backup Server URLs", settingsbrowser.backupserverurls)
+	settingsbrowser.form.AppendItem(settingsbrowser.backupserverurlsitem)
+
+	settingsbrowser.password.Password = true
+	settingsbrowser.password.SetText(controller.Settings.Password())
+	settingsbrowser.password.OnFocusChanged = func(b bool) {
+		if !b {
+			settingsbrowser.setPassword()
+		}
+	}
+	settingsbrowser.password.SetPlaceHolder("Password or token of the event, if the server needs one")
+	settingsbrowser.password.OnSubmitted = func(s string) {
+		settingsbrowser.setPassword()
+	}
+	settingsbrowser.form.AppendItem(NewFormItem("Event Password", settingsbrowser.password))
+
+	settingsbrowser.serverdiscovery.OnSelected = func(server discovery.Server) {
+		settingsbrowser.serverurl.SetText(server.URL)
+		settingsbrowser.setServerURL()
+	}
+	settingsbrowser.form.AppendItem(NewFormItem("LAN Servers", settingsbrowser.serverdiscovery))
 
 	settingsbrowser.gamedirectory.SetText(controller.Settings.Settings().GameDirectory)
 	settingsbrowser.gamedirectory.OnFocusChanged = func(b bool) {
 		if !b {
-			controller.Settings.SetGameDirectory(settingsbrowser.gamedirectory.Text)
+			settingsbrowser.setGameDirectory()
 		}
 	}
 	settingsbrowser.gamedirectory.OnSubmitted = func(s string) {
-		controller.Settings.SetGameDirectory(settingsbrowser.gamedirectory.Text)
+		settingsbrowser.setGameDirectory()
 	}
 	gamedirectoryexplorer := widget.NewButtonWithIcon("", theme.FolderOpenIcon(), settingsbrowser.gamedirectoryExplorerCallback)
 	gamedirectory := container.NewBorder(nil, nil, nil, gamedirectoryexplorer, settingsbrowser.gamedirectory)
-	settingsbrowser.form.AppendItem(NewFormItem("Game Directory", gamedirectory))
+	settingsbrowser.gamedirectoryitem = NewFormItem("Game Directory", gamedirectory)
+	settingsbrowser.form.AppendItem(settingsbrowser.gamedirectoryitem)
 
 	settingsbrowser.username.SetText(controller.Settings.Settings().Username)
-	settingsbrowser.username.Validator = func(username string) error {
-		if username == setting.DEFAULT_USERNAME {
-			return errors.New("default username not allowed")
-		}
-		match, err := regexp.MatchString("^(?:[a-zA-Z]|[0-9]|-)+$", username)
-		if !match || err != nil {
-			return errors.New("only alphanumeric characters and \"-\" allowed")
-		}
-		return nil
-	}
+	settingsbrowser.username.Validator = setting.ValidateUsername
 	settingsbrowser.username.OnFocusChanged = func(b bool) {
-		if !b && settingsbrowser.username.Validate() == nil {
-			controller.Settings.SetUsername(settingsbrowser.username.Text)
+		if !b {
+			settingsbrowser.setUsername()
 		}
 	}
 	settingsbrowser.username.OnSubmitted = func(s string) {
-		if settingsbrowser.username.Validate() == nil {
-			controller.Settings.SetUsername(settingsbrowser.username.Text)
-		}
+		settingsbrowser.setUsername()
 	}
-	settingsbrowser.form.AppendItem(NewFormItem("Username", settingsbrowser.username))
+	settingsbrowser.usernameitem = NewFormItem("Username", settingsbrowser.username)
+	settingsbrowser.form.AppendItem(settingsbrowser.usernameitem)
 
 	settingsbrowser.downloaddirectory.SetText(controller.Settings.Settings().DownloadDirectory)
 	settingsbrowser.downloaddirectory.OnFocusChanged = func(b bool) {
 		if !b {
-			controller.Settings.SetDownloadDirectory(settingsbrowser.downloaddirectory.Text)
+			settingsbrowser.setDownloadDirectory()
 		}
 	}
 	settingsbrowser.downloaddirectory.OnSubmitted = func(s string) {
-		controller.Settings.SetDownloadDirectory(settingsbrowser.downloaddirectory.Text)
+		settingsbrowser.setDownloadDirectory()
 	}
 	downloaddirectoryexplorer := widget.NewButtonWithIcon("", theme.FolderOpenIcon(), settingsbrowser.downloaddirectoryExplorerCallback)
 	downloaddirectory := container.NewBorder(nil, nil, nil, downloaddirectoryexplorer, settingsbrowser.downloaddirectory)
-	settingsbrowser.form.AppendItem(NewFormItem("Download Directory", downloaddirectory))
+	settingsbrowser.downloaddirectoryitem = NewFormItem("Download Directory", downloaddirectory)
+	settingsbrowser.form.AppendItem(settingsbrowser.downloaddirectoryitem)
+
+	settingsbrowser.cafile.SetText(controller.Settings.Settings().CAFile)
+	settingsbrowser.cafile.SetPlaceHolder("PEM file of a CA trusted for https:// servers")
+	settingsbrowser.cafile.OnFocusChanged = func(b bool) {
+		if !b {
+			settingsbrowser.setCAFile()
+		}
+	}
+	settingsbrowser.cafile.OnSubmitted = func(s string) {
+		settingsbrowser.setCAFile()
+	}
+	cafileexplorer := widget.NewButtonWithIcon("", theme.FileIcon(), settingsbrowser.cafileExplorerCallback)
+	cafile := container.NewBorder(nil, nil, nil, cafileexplorer, settingsbrowser.cafile)
+	settingsbrowser.cafileitem = NewFormItem("CA File", cafile)
+	settingsbrowser.form.AppendItem(settingsbrowser.cafileitem)
+
+	settingsbrowser.pinned.SetText(strings.Join(controller.Settings.Settings().PinnedCertificates, ", "))
+	settingsbrowser.pinned.SetPlaceHolder("Comma separated SHA-256 fingerprints, trusted instead of CAs")
+	settingsbrowser.pinned.Validator = func(s string) error {
+		return setting.ValidatePinnedCertificates(setting.SplitList(s))
+	}
+	settingsbrowser.pinned.OnFocusChanged = func(b bool) {
+		if !b {
+			settingsbrowser.setPinnedCertificates()
+		}
+	}
+	settingsbrowser.pinned.OnSubmitted = func(s string) {
+		settingsbrowser.setPinnedCertificates()
+	}
+	settingsbrowser.pinneditem = NewFormItem("Pinned Certificates", settingsbrowser.pinned)
+	settingsbrowser.form.AppendItem(settingsbrowser.pinneditem)
+
+	settingsbrowser.insecure = widget.NewCheck("Trust every server certificate (testing only)", func(insecure bool) {
+		if insecure != controller.Settings.Settings().InsecureSkipVerify {
+			controller.Settings.SetInsecureSkipVerify(insecure)
+		}
+	})
+	settingsbrowser.insecure.SetChecked(controller.Settings.Settings().InsecureSkipVerify)
+	settingsbrowser.form.AppendItem(NewFormItem("Skip Verification", settingsbrowser.insecure))
 
 	settingsbrowser.form.HideSubmit()
 	settingsbrowser.form.OnSubmit = func() {
 		if settingsbrowser.OnSubmit != nil {
-			serverurl := settingsbrowser.serverurl.Text
-			gamedir := settingsbrowser.gamedirectory.Text
-			username := settingsbrowser.username.Text
-			downloaddir := settingsbrowser.downloaddirectory.Text
-			controller.Settings.SetServerURL(serverurl)
-			controller.Settings.SetGameDirectory(gamedir)
-			controller.Settings.SetUsername(username)
-			controller.Settings.SetDownloadDirectory(downloaddir)
-			settingsbrowser.OnSubmit()
+			err := errors.Join(
+				settingsbrowser.setServerURL(),
+				settingsbrowser.setBackupServerURLs(),
+				settingsbrowser.setPassword(),
+				settingsbrowser.setGameDirectory(),
+				settingsbrowser.setUsername(),
+				settingsbrowser.setDownloadDirectory(),
+				settingsbrowser.setCAFile(),
+				settingsbrowser.setPinnedCertificates(),
+			)
+			if err == nil {
+				settingsbrowser.OnSubmit()
+			}
 		}
 	}
 
+	settingsbrowser.exportsettings = widget.NewButtonWithIcon("Export settings", theme.UploadIcon(), settingsbrowser.exportSettingsCallback)
+	settingsbrowser.importsettings = widget.NewButtonWithIcon("Import settings", theme.DownloadIcon(), settingsbrowser.importSettingsCallback)
+	settingsbrowser.diagnostics = widget.NewButtonWithIcon("Export diagnostics", theme.DocumentSaveIcon(), settingsbrowser.exportDiagnosticsCallback)
+	settingsbrowser.diagnostics.Hide()
+
 	settingsbrowser.form.SetCancelText("Reset")
 	settingsbrowser.form.OnCancel = func() {
 		settingsbrowser.ResetData()
@@ -143,38 +256,136 @@ func (widget *SettingsBrowser) SetOnSubmit(onSubmit func()) {
 	widget.Refresh()
 }
 
+func (widget *SettingsBrowser) SetDiagnosticsExporter(exporter *diagnostics.Exporter) {
+	widget.exporter = exporter
+	if exporter != nil {
+		widget.diagnostics.Show()
+	} else {
+		widget.diagnostics.Hide()
+	}
+	widget.Refresh()
+}
+
+// DiscoverServers searches the local network for servers, which are listed below the server URL.
+func (widget *SettingsBrowser) DiscoverServers() {
+	widget.serverdiscovery.Search()
+}
+
 func (widget *SettingsBrowser) run() {
-	widget.controller.WaitGroup().Add(1)
-	go widget.settingsUpdater()
+	widget.controller.Go("SettingsBrowser.settingsUpdater", widget.settingsUpdater)
 }
 
 func (widget *SettingsBrowser) settingsUpdater() {
-	defer widget.controller.WaitGroup().Done()
 	for {
 		select {
 		case <-widget.controller.Context().Done():
 			log.Trace().Msg("exiting SettingsBrowser settingsUpdater()")
 			return
-		case <-widget.settingschanged:
-			widget.serverurl.SetText(widget.controller.Settings.Settings().ServerURL)
-			widget.gamedirectory.SetText(widget.controller.Settings.Settings().GameDirectory)
-			widget.username.SetText(widget.controller.Settings.Settings().Username)
-			widget.downloaddirectory.SetText(widget.controller.Settings.Settings().DownloadDirectory)
+		case event := <-widget.settingschanged:
+			switch event.Topic {
+			case controller.TopicSettingsServerURL:
+				widget.serverurl.SetText(event.Data.ServerURL)
+				widget.serverurlitem.SetError(nil)
+			case controller.TopicSettingsBackupServerURLs:
+				widget.backupserverurls.SetText(strings.Join(event.Data.BackupServerURLs, ", "))
+				widget.backupserverurlsitem.SetError(nil)
+			case controller.TopicSettingsPassword:
+				widget.password.SetText(widget.controller.Settings.Password())
+			case controller.TopicSettingsGameDirectory:
+				widget.gamedirectory.SetText(event.Data.GameDirectory)
+				widget.gamedirectoryitem.SetError(nil)
+			case controller.TopicSettingsUsername:
+				widget.username.SetText(event.Data.Username)
+				widget.usernameitem.SetError(nil)
+			case controller.TopicSettingsDownloadDirectory:
+				widget.downloaddirectory.SetText(event.Data.DownloadDirectory)
+				widget.downloaddirectoryitem.SetError(nil)
+			case controller.TopicSettingsTLS:
+				widget.cafile.SetText(event.Data.CAFile)
+				widget.cafileitem.SetError(nil)
+				widget.pinned.SetText(strings.Join(event.Data.PinnedCertificates, ", "))
+				widget.pinneditem.SetError(nil)
+				widget.insecure.SetChecked(event.Data.InsecureSkipVerify)
+			}
 			widget.Refresh()
 		}
 	}
 }
 
+// setServerURL stores the server URL and shows inline if it is invalid or the server is unreachable.
+func (widget *SettingsBrowser) setServerURL() error {
+	serverurl := widget.serverurl.Text
+	err := widget.controller.Settings.SetServerURL(serverurl)
+	widget.showError(widget.serverurlitem, err)
+	if err != nil {
+		return err
+	}
+	widget.controller.Go("SettingsBrowser.checkServerURL", func() {
+		err := widget.controller.Settings.CheckServerURL(serverurl)
+		if widget.serverurl.Text == serverurl {
+			widget.showError(widget.serverurlitem, err)
+		}
+	}, supervisor.WithoutRestart())
+	return nil
+}
+
+func (widget *SettingsBrowser) setBackupServerURLs() error {
+	err := widget.controller.Settings.SetBackupServerURLs(setting.SplitList(widget.backupserverurls.Text))
+	widget.showError(widget.backupserverurlsitem, err)
+	return err
+}
+
+// setPassword stores the event password if it changed, so the components do not reconnect needlessly.
+func (widget *SettingsBrowser) setPassword() error {
+	if widget.password.Text == widget.controller.Settings.Password() {
+		return nil
+	}
+	return widget.controller.Settings.SetPassword(widget.password.Text)
+}
+
+func (widget *SettingsBrowser) setGameDirectory() error {
+	err := widget.controller.Settings.SetGameDirectory(widget.gamedirectory.Text)
+	widget.showError(widget.gamedirectoryitem, err)
+	return err
+}
+
+func (widget *SettingsBrowser) setUsername() error {
+	err := widget.controller.Settings.SetUsername(widget.username.Text)
+	widget.showError(widget.usernameitem, err)
+	return err
+}
+
+func (widget *SettingsBrowser) setDownloadDirectory() error {
+	err := widget.controller.Settings.SetDownloadDirectory(widget.downloaddirectory.Text)
+	widget.showError(widget.downloaddirectoryitem, err)
+	return err
+}
+
+func (widget *SettingsBrowser) setCAFile() error {
+	err := widget.controller.Settings.SetCAFile(widget.cafile.Text)
+	widget.showError(widget.cafileitem, err)
+	return err
+}
+
+func (widget *SettingsBrowser) setPinnedCertificates() error {
+	err := widget.controller.Settings.SetPinnedCertificates(setting.SplitList(widget.pinned.Text))
+	widget.showError(widget.pinneditem, err)
+	return err
+}
+
+// showError shows the error inline below the setting, the browser is refreshed as its size changes.
+func (widget *SettingsBrowser) showError(item *FormItem, err error) {
+	item.SetError(err)
+	widget.Refresh()
+}
+
 func (widget *SettingsBrowser) gamedirectoryExplorerCallback() {
 	folderdialog := dialog.NewFolderOpen(func(uri fyne.ListableURI, err error) {
 		if uri == nil || err != nil {
 			return
 		}
 		widget.gamedirectory.SetText(uri.Path())
-		if widget.gamedirectory.Validate() == nil {
-			widget.controller.Settings.SetGameDirectory(widget.gamedirectory.Text)
-			widget.controller.Settings.Save()
-		}
+		widget.setGameDirectory()
 	}, widget.window)
 
 	dialogStartURI, err := storage.ListerForURI(storage.NewFileURI(widget.controller.Settings.Settings().GameDirectory))
@@ -193,10 +404,7 @@ func (widget *SettingsBrowser) downloaddirectoryExplorerCallback() {
 			return
 		}
 		widget.downloaddirectory.SetText(uri.Path())
-		if widget.downloaddirectory.Validate() == nil {
-			widget.controller.Settings.SetDownloadDirectory(widget.downloaddirectory.Text)
-			widget.controller.Settings.Save()
-		}
+		widget.setDownloadDirectory()
 	}, widget.window)
 
 	dialogStartURI, err := storage.ListerForURI(storage.NewFileURI(widget.controller.Settings.Settings().DownloadDirectory))
@@ -209,14 +417,188 @@ func (widget *SettingsBrowser) downloaddirectoryExplorerCallback() {
 	folderdialog.Show()
 }
 
+func (widget *SettingsBrowser) cafileExplorerCallback() {
+	opendialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if reader == nil || err != nil {
+			return
+		}
+		reader.Close()
+		widget.cafile.SetText(reader.URI().Path())
+		widget.setCAFile()
+	}, widget.window)
+	opendialog.SetFilter(storage.NewExtensionFileFilter([]string{".pem", ".crt", ".cer"}))
+
+	//This will make the fileopen dialog to be "fullscreen" inside the app
+	opendialog.Resize(fyne.NewSize(10000, 10000))
+	opendialog.Show()
+}
+
+// addProfileCallback creates a profile with the values of the active profile and switches to it.
+func (w *SettingsBrowser) addProfileCallback() {
+	name := NewEntry()
+	name.Validator = func(name string) error {
+		if name == "" {
+			return errors.New("name must not be empty")
+		}
+		return nil
+	}
+	items := []*widget.FormItem{widget.NewFormItem("Name", name)}
+	dialog.ShowForm("New profile", "Create", "Cancel", items, func(create bool) {
+		if !create {
+			return
+		}
+		profile := w.controller.Settings.Settings().Profile
+		profile.Name = name.Text
+		if w.controller.Settings.AddProfile(profile) != nil {
+			return
+		}
+		w.controller.Settings.SwitchProfile(profile.Name)
+	}, w.window)
+}
+
+func (widget *SettingsBrowser) removeProfileCallback() {
+	name := widget.controller.Settings.Settings().ActiveProfile
+	dialog.ShowConfirm("Remove profile", fmt.Sprintf("Remove the profile \"%s\"?", name), func(remove bool) {
+		if remove {
+			widget.controller.Settings.RemoveProfile(name)
+		}
+	}, widget.window)
+}
+
+func (widget *SettingsBrowser) exportSettingsCallback() {
+	savedialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if writer == nil || err != nil {
+			return
+		}
+		defer writer.Close()
+		err = widget.controller.Settings.ExportBundle(writer)
+		if err != nil {
+			log.Error().Err(err).Str("path", writer.URI().Path()).Msg("error exporting settings")
+			widget.controller.Status.Error("Error exporting settings", 3*time.Second)
+			return
+		}
+		widget.controller.Status.Info(fmt.Sprintf("Settings exported to \"%s\"", writer.URI().Path()), 3*time.Second)
+	}, widget.window)
+	savedialog.SetFileName(SETTINGS_BUNDLE_FILENAME)
+	savedialog.SetFilter(storage.NewExtensionFileFilter([]string{".yaml"}))
+
+	//This will make the filesave dialog to be "fullscreen" inside the app
+	savedialog.Resize(fyne.NewSize(10000, 10000))
+	savedialog.Show()
+}
+
+func (widget *SettingsBrowser) importSettingsCallback() {
+	opendialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if reader == nil || err != nil {
+			return
+		}
+		defer reader.Close()
+		bundle, err := setting.ReadBundle(reader)
+		if err != nil {
+			log.Error().Err(err).Str("path", reader.URI().Path()).Msg("error reading settings bundle")
+			widget.controller.Status.Error("Error importing settings: "+err.Error(), 5*time.Second)
+			return
+		}
+		widget.showImportPreview(bundle)
+	}, widget.window)
+	opendialog.SetFilter(storage.NewExtensionFileFilter([]string{".yaml"}))
+
+	//This will make the fileopen dialog to be "fullscreen" inside the app
+	opendialog.Resize(fyne.NewSize(10000, 10000))
+	opendialog.Show()
+}
+
+// showImportPreview shows the changes of the import for the selected mode, the settings are only
+// changed when the import is confirmed.
+func (w *SettingsBrowser) showImportPreview(bundle *setting.Bundle) {
+	var imported setting.Settings
+	changes := widget.NewLabel("")
+	changes.Wrapping = fyne.TextWrapWord
+	update := func(mode setting.ImportMode) {
+		var diff []setting.Change
+		imported, diff = w.controller.Settings.PreviewImport(bundle, mode)
+		lines := make([]string, 0, len(diff))
+		for _, change := range diff {
+			lines = append(lines, change.String())
+		}
+		if len(lines) == 0 {
+			lines = append(lines, "No changes")
+		}
+		changes.SetText(strings.Join(lines, "\n"))
+	}
+	modes := map[string]setting.ImportMode{
+		"Merge":   setting.ImportMerge,
+		"Replace": setting.ImportReplace,
+	}
+	mode := widget.NewRadioGroup([]string{"Merge", "Replace"}, func(selected string) {
+		update(modes[selected])
+	})
+	mode.Horizontal = true
+	mode.Required = true
+	mode.SetSelected("Merge")
+
+	header := widget.NewLabel(fmt.Sprintf("Exported %s by %s", bundle.Exported.Format(time.DateTime), bundle.ClientVersion))
+	content := container.NewBorder(container.NewVBox(header, mode), nil, nil, nil, container.NewVScroll(changes))
+	preview := dialog.NewCustomConfirm("Import settings", "Apply", "Cancel", content, func(apply bool) {
+		if !apply {
+			return
+		}
+		err := w.controller.Settings.ApplyImport(imported)
+		if err != nil {
+			log.Error().Err(err).Msg("error applying imported settings")
+		}
+		w.ResetData()
+	}, w.window)
+	preview.Resize(fyne.NewSize(700, 500))
+	preview.Show()
+}
+
+func (widget *SettingsBrowser) exportDiagnosticsCallback() {
+	savedialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if writer == nil || err != nil {
+			return
+		}
+		defer writer.Close()
+		err = widget.exporter.Write(writer)
+		if err != nil {
+			log.Error().Err(err).Str("path", writer.URI().Path()).Msg("error exporting diagnostics")
+			widget.controller.Status.Error("Error exporting diagnostics", 3*time.Second)
+			return
+		}
+		widget.controller.Status.Info(fmt.Sprintf("Diagnostics exported to \"%s\"", writer.URI().Path()), 3*time.Second)
+	}, widget.window)
+	savedialog.SetFileName(widget.exporter.Filename())
+
+	dialogStartURI, err := storage.ListerForURI(storage.NewFileURI(widget.controller.Settings.Settings().DownloadDirectory))
+	if err == nil {
+		savedialog.SetLocation(dialogStartURI)
+	}
+
+	//This will make the filesave dialog to be "fullscreen" inside the app
+	savedialog.Resize(fyne.NewSize(10000, 10000))
+	savedialog.Show()
+}
+
 func (widget *SettingsBrowser) ResetData() {
 	widget.serverurl.SetText(widget.controller.Settings.Settings().ServerURL)
+	widget.backupserverurls.SetText(strings.Join(widget.controller.Settings.Settings().BackupServerURLs, ", "))
+	widget.password.SetText(widget.controller.Settings.Password())
 	widget.gamedirectory.SetText(widget.controller.Settings.Settings().GameDirectory)
 	widget.username.SetText(widget.controller.Settings.Settings().Username)
 	widget.downloaddirectory.SetText(widget.controller.Settings.Settings().DownloadDirectory)
+	widget.serverurlitem.SetError(nil)
+	widget.backupserverurlsitem.SetError(nil)
+	widget.gamedirectoryitem.SetError(nil)
+	widget.usernameitem.SetError(nil)
+	widget.downloaddirectoryitem.SetError(nil)
+	widget.cafile.SetText(widget.controller.Settings.Settings().CAFile)
+	widget.cafileitem.SetError(nil)
+	widget.pinned.SetText(strings.Join(widget.controller.Settings.Settings().PinnedCertificates, ", "))
+	widget.pinneditem.SetError(nil)
+	widget.insecure.SetChecked(widget.controller.Settings.Settings().InsecureSkipVerify)
 	widget.Refresh()
 }
 
 func (w *SettingsBrowser) CreateRenderer() fyne.WidgetRenderer {
-	return widget.NewSimpleRenderer(w.form)
+	return widget.NewSimpleRenderer(container.NewVBox(w.form, container.NewHBox(w.exportsettings, w.importsettings, w.diagnostics)))
 }
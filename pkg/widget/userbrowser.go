@@ -6,6 +6,7 @@ import (
 	"fyne.io/fyne/v2/widget"
 	"github.com/rs/zerolog/log"
 	"github.com/seternate/go-lanty-client/pkg/controller"
+	"github.com/seternate/go-lanty-client/pkg/event"
 	"github.com/seternate/go-lanty-client/pkg/theme"
 	"github.com/seternate/go-lanty/pkg/user"
 )
@@ -16,7 +17,7 @@ type UserBrowser struct {
 	controller *controller.Controller
 	usertiles  []*UserTile
 
-	usersupdated chan struct{}
+	usersupdated chan event.Event[user.User]
 
 	onUserTapped       func(user.User)
 	onUserDoubleTapped func(user.User)
@@ -26,13 +27,12 @@ func NewUserBrowser(controller *controller.Controller) (userbrowser *UserBrowser
 	userbrowser = &UserBrowser{
 		controller:   controller,
 		usertiles:    make([]*UserTile, 0, 50),
-		usersupdated: make(chan struct{}, 50),
+		usersupdated: make(chan event.Event[user.User], 50),
 	}
 	userbrowser.ExtendBaseWidget(userbrowser)
 	userbrowser.updateUsertiles()
 	controller.User.Subscribe(userbrowser.usersupdated)
-	controller.WaitGroup().Add(1)
-	go userbrowser.usersUpdater()
+	controller.Go("UserBrowser.usersUpdater", userbrowser.usersUpdater)
 
 	return
 }
@@ -56,24 +56,34 @@ func (widget *UserBrowser) CreateRenderer() fyne.WidgetRenderer {
 }
 
 func (widget *UserBrowser) usersUpdater() {
-	defer widget.controller.WaitGroup().Done()
 	for {
 		select {
 		case <-widget.controller.Context().Done():
 			log.Trace().Msg("exiting userbrowser usersUpdater()")
 			return
 		case <-widget.usersupdated:
+			//Users are published one by one, drop all pending events as the tiles are built from all users
+			for len(widget.usersupdated) > 0 {
+				<-widget.usersupdated
+			}
 			widget.updateUsertiles()
 		}
 	}
 }
 
 func (widget *UserBrowser) updateUsertiles() {
+	oldUsertiles := make(map[string]*UserTile, len(widget.usertiles))
+	for _, usertile := range widget.usertiles {
+		oldUsertiles[usertile.user.IP] = usertile
+	}
 	usertiles := make([]*UserTile, 0, len(widget.controller.User.GetUsers()))
 	for _, user := range widget.controller.User.GetUsers() {
-		usertile := NewUserTile(widget, user)
-		usertile.OnTapped = widget.onUserTapped
-		usertile.OnDoubleTapped = widget.onUserDoubleTapped
+		usertile, found := oldUsertiles[user.IP]
+		if !found || usertile.user != user {
+			usertile = NewUserTile(widget, user)
+			usertile.OnTapped = widget.onUserTapped
+			usertile.OnDoubleTapped = widget.onUserDoubleTapped
+		}
 		usertiles = append(usertiles, usertile)
 	}
 	widget.setUsertiles(usertiles...)